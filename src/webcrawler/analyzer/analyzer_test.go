@@ -0,0 +1,48 @@
+package analyzer
+
+import (
+	"net/http"
+	"testing"
+	base "webcrawler/base"
+)
+
+func TestAnalyzeWithMeta(t *testing.T) {
+	httpReq, _ := http.NewRequest("GET", "http://example.com/a", nil)
+	httpResp := &http.Response{Request: httpReq, StatusCode: 200}
+	meta := base.Meta{base.META_KEY_SEED_URL: "http://example.com", "category": "news"}
+	resp := base.NewResponseWithMeta(httpResp, 1, meta)
+	var received base.Meta
+	parser := func(httpResp *http.Response, respDepth uint32, respMeta base.Meta) ([]base.Data, []error) {
+		received = respMeta
+		childHttpReq, _ := http.NewRequest("GET", "http://example.com/b", nil)
+		child := base.NewRequest(childHttpReq, 0)
+		child.Meta()["category"] = "sports"
+		return []base.Data{child}, nil
+	}
+	dataList, errs := NewAnalyzer().Analyze([]ParseResponse{parser}, *resp)
+	if len(errs) != 0 {
+		t.Fatalf("Analyze error: %v\n", errs)
+	}
+	if received["category"] != "news" {
+		t.Errorf("The parser should receive the response meta, but %v!\n", received)
+	}
+	if len(dataList) != 1 {
+		t.Fatalf("The length of data list should be 1, but %d!\n", len(dataList))
+	}
+	req := dataList[0].(*base.Request)
+	if req.Depth() != 2 {
+		t.Errorf("The depth of child request should be 2, but %d!\n", req.Depth())
+	}
+	childMeta := req.Meta()
+	if childMeta[base.META_KEY_SEED_URL] != "http://example.com" ||
+		childMeta[base.META_KEY_PARENT_URL] != "http://example.com/a" ||
+		childMeta["category"] != "sports" {
+		t.Errorf("Unexpected child meta %v!\n", childMeta)
+	}
+	if referer := req.HttpReq().Header.Get("Referer"); referer != "http://example.com/a" {
+		t.Errorf("The referer should be the parent url, but %q!\n", referer)
+	}
+	if meta[base.META_KEY_PARENT_URL] != nil {
+		t.Errorf("The response meta should not be modified!\n")
+	}
+}
@@ -0,0 +1,333 @@
+package analyzer
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// 字符集解码函数的类型。它会把以相应字符集编码的内容转换为以UTF-8编码的内容。
+type CharsetDecoder func(content []byte) ([]byte, error)
+
+// 字符集的来源。
+type CharsetSource string
+
+// 字符集的来源的常量。
+const (
+	CHARSET_FROM_BOM    CharsetSource = "bom"    // 字节顺序标记。
+	CHARSET_FROM_HEADER CharsetSource = "header" // Content-Type响应头。
+	CHARSET_FROM_META   CharsetSource = "meta"   // HTML的meta标签或XML声明。
+	CHARSET_FROM_GUESS  CharsetSource = "guess"  // 根据内容猜测。
+)
+
+// 字符集注册表。
+type charsetRegistry struct {
+	names    map[string]string         // 字符集名称或别名与标准名称的映射。
+	decoders map[string]CharsetDecoder // 标准名称与解码函数的映射。
+	rwmutex  sync.RWMutex              // 读写锁。
+}
+
+// 默认的字符集注册表。
+// 中文等多字节字符集的解码函数由webcrawler/analyzer/cjk包在初始化时注册。
+var charsets = &charsetRegistry{
+	names:    make(map[string]string),
+	decoders: make(map[string]CharsetDecoder),
+}
+
+func init() {
+	RegisterCharset("utf-8", decodeUtf8, "utf8", "unicode-1-1-utf-8")
+	RegisterCharset("utf-16le", decodeUtf16(false), "utf-16")
+	RegisterCharset("utf-16be", decodeUtf16(true))
+	RegisterCharset("windows-1252", decodeWindows1252,
+		"cp1252", "x-cp1252", "iso-8859-1", "iso8859-1", "latin1", "l1", "us-ascii", "ascii")
+}
+
+// 注册字符集的解码函数。参数name代表字符集的标准名称，参数aliases代表其别名。
+// 名称不区分大小写。已注册的同名字符集会被覆盖。
+func RegisterCharset(name string, decoder CharsetDecoder, aliases ...string) error {
+	canonical := normalizeCharset(name)
+	if canonical == "" {
+		return errors.New("Empty charset name!")
+	}
+	if decoder == nil {
+		return errors.New(fmt.Sprintf("The decoder of charset '%s' is invalid!", name))
+	}
+	charsets.rwmutex.Lock()
+	defer charsets.rwmutex.Unlock()
+	charsets.decoders[canonical] = decoder
+	charsets.names[canonical] = canonical
+	for _, alias := range aliases {
+		if alias = normalizeCharset(alias); alias != "" {
+			charsets.names[alias] = canonical
+		}
+	}
+	return nil
+}
+
+// 根据名称或别名查找字符集。结果值分别代表标准名称、解码函数以及是否找到。
+func LookupCharset(name string) (string, CharsetDecoder, bool) {
+	charsets.rwmutex.RLock()
+	defer charsets.rwmutex.RUnlock()
+	canonical, ok := charsets.names[normalizeCharset(name)]
+	if !ok {
+		return "", nil, false
+	}
+	return canonical, charsets.decoders[canonical], true
+}
+
+// 规范化字符集名称。
+func normalizeCharset(name string) string {
+	return strings.ToLower(strings.Trim(strings.TrimSpace(name), "\"'"))
+}
+
+// 用于查找meta标签和XML声明中的字符集的正则表达式。
+var (
+	metaCharsetRegexp = regexp.MustCompile(
+		`(?i)<meta[^>]+charset\s*=\s*["']?\s*([a-z0-9_.:\-]+)`)
+	xmlEncodingRegexp = regexp.MustCompile(
+		`(?i)^\s*<\?xml[^>]+encoding\s*=\s*["']([a-z0-9_.:\-]+)["']`)
+)
+
+// 查找meta标签和XML声明时检查的内容长度。
+const charsetPrescanLength = 1024
+
+// 检测内容的字符集。参数contentType代表Content-Type响应头的值。
+// 检测顺序依次为：字节顺序标记、Content-Type响应头、meta标签或XML声明，以及根据内容猜测。
+// 结果值分别代表字符集名称和其来源。
+func DetectCharset(contentType string, content []byte) (string, CharsetSource) {
+	switch {
+	case bytes.HasPrefix(content, []byte{0xEF, 0xBB, 0xBF}):
+		return "utf-8", CHARSET_FROM_BOM
+	case bytes.HasPrefix(content, []byte{0xFF, 0xFE}):
+		return "utf-16le", CHARSET_FROM_BOM
+	case bytes.HasPrefix(content, []byte{0xFE, 0xFF}):
+		return "utf-16be", CHARSET_FROM_BOM
+	}
+	if _, params, err := mime.ParseMediaType(contentType); err == nil {
+		if charset := normalizeCharset(params["charset"]); charset != "" {
+			return charset, CHARSET_FROM_HEADER
+		}
+	}
+	prefix := content
+	if len(prefix) > charsetPrescanLength {
+		prefix = prefix[:charsetPrescanLength]
+	}
+	for _, re := range []*regexp.Regexp{xmlEncodingRegexp, metaCharsetRegexp} {
+		if match := re.FindSubmatch(prefix); match != nil {
+			charset := normalizeCharset(string(match[1]))
+			// 能被读到的声明一定是以兼容ASCII的字符集编码的。
+			if strings.HasPrefix(charset, "utf-16") {
+				charset = "utf-8"
+			}
+			return charset, CHARSET_FROM_META
+		}
+	}
+	return guessCharset(content), CHARSET_FROM_GUESS
+}
+
+// 猜测字符集时，双字节字符的最少数量，以及双字节字符在所有非ASCII字节序列中所占的最低百分比。
+// 西欧文字中的非ASCII字节大多零散地出现，偶尔才会与后面的字母凑成看似合法的双字节字符。
+const (
+	charsetGuessMinPairs   = 2
+	charsetGuessMinPercent = 90
+)
+
+// 根据内容猜测字符集。
+// 合法的UTF-8内容会被视为UTF-8。否则，若内容中的非ASCII字节几乎都能组成双字节字符，
+// 则会根据双字节字符的分布在GBK和Big5之间做出选择，且所选字符集必须能无误地解码内容。
+// 其他情况下，内容会被视为Windows-1252。
+func guessCharset(content []byte) string {
+	if utf8.Valid(content) {
+		return "utf-8"
+	}
+	var pairs, strays, gbOnly, lowTrails int
+	for i := 0; i < len(content); i++ {
+		lead := content[i]
+		if lead < 0x80 {
+			continue
+		}
+		if lead == 0x80 || lead == 0xFF || i+1 == len(content) {
+			strays++
+			continue
+		}
+		trail := content[i+1]
+		if trail < 0x40 || trail == 0x7F || trail == 0xFF {
+			strays++
+			continue
+		}
+		pairs++
+		switch {
+		case lead <= 0xA0:
+			// Big5的标准区段中没有以此范围内的字节开头的字符。
+			gbOnly++
+		case trail <= 0x7E:
+			// GB2312中的字符的两个字节都不小于0xA1，而Big5中有相当比例的字符的第二个字节小于0x7F。
+			lowTrails++
+		}
+		i++
+	}
+	if pairs < charsetGuessMinPairs || pairs*100 < (pairs+strays)*charsetGuessMinPercent {
+		return "windows-1252"
+	}
+	candidates := []string{"gbk", "big5"}
+	if lowTrails*5 > pairs && gbOnly*10 < pairs {
+		candidates = []string{"big5", "gbk"}
+	}
+	for _, charset := range candidates {
+		if decodesCleanly(charset, content) {
+			return charset
+		}
+	}
+	return "windows-1252"
+}
+
+// 判断内容能否被给定的字符集无误地解码，即解码时没有出现非法的字节序列。
+// 若该字符集的解码函数未被注册，则无从判断，此时结果值总为true。
+func decodesCleanly(charset string, content []byte) bool {
+	_, decoder, ok := LookupCharset(charset)
+	if !ok {
+		return true
+	}
+	decoded, err := decoder(content)
+	return err == nil && !bytes.ContainsRune(decoded, utf8.RuneError)
+}
+
+// 判断媒体类型是否代表文本内容。空的媒体类型也会被视为文本。
+func isTextMediaType(mediaType string) bool {
+	if mediaType == "" || strings.HasPrefix(mediaType, "text/") {
+		return true
+	}
+	for _, keyword := range []string{"html", "xml", "json", "javascript"} {
+		if strings.Contains(mediaType, keyword) {
+			return true
+		}
+	}
+	return false
+}
+
+// 把HTTP响应的响应体转换为以UTF-8编码的内容。非文本的响应体会被忽略。
+// 转换后，Content-Type响应头中的字符集会被设为utf-8（没有媒体类型时会使用text/html），
+// 所以对同一个响应重复调用该函数不会再次转换。
+// 结果值分别代表检测到的字符集（非文本或没有响应体时为空）和转换时发生的错误。
+// 若发生错误，那么响应体会保持原样。
+func DecodeToUtf8(httpResp *http.Response) (string, error) {
+	if httpResp == nil {
+		return "", errors.New("The http response is invalid!")
+	}
+	if httpResp.Body == nil {
+		return "", nil
+	}
+	contentType := httpResp.Header.Get("Content-Type")
+	mediaType, params, _ := mime.ParseMediaType(contentType)
+	if !isTextMediaType(mediaType) {
+		return "", nil
+	}
+	content, err := ioutil.ReadAll(httpResp.Body)
+	httpResp.Body.Close()
+	httpResp.Body = ioutil.NopCloser(bytes.NewReader(content))
+	if err != nil {
+		return "", err
+	}
+	charset, source := DetectCharset(contentType, content)
+	canonical, decoder, ok := LookupCharset(charset)
+	if !ok {
+		errMsg := fmt.Sprintf("Unsupported charset '%s' (source=%s)!", charset, source)
+		return charset, errors.New(errMsg)
+	}
+	if canonical == "utf-8" && utf8.Valid(content) && !bytes.HasPrefix(content, []byte{0xEF, 0xBB, 0xBF}) {
+		markUtf8(httpResp, mediaType, params)
+		return canonical, nil
+	}
+	decoded, err := decoder(content)
+	if err != nil {
+		return canonical, err
+	}
+	httpResp.Body = ioutil.NopCloser(bytes.NewReader(decoded))
+	httpResp.ContentLength = int64(len(decoded))
+	markUtf8(httpResp, mediaType, params)
+	return canonical, nil
+}
+
+// 把Content-Type响应头中的字符集设为utf-8，以标明响应体已是以UTF-8编码的内容。
+// 参数mediaType为空时会使用text/html。
+func markUtf8(httpResp *http.Response, mediaType string, params map[string]string) {
+	if httpResp.Header == nil {
+		httpResp.Header = make(http.Header)
+	}
+	httpResp.Header.Del("Content-Length")
+	if mediaType == "" {
+		mediaType = "text/html"
+	}
+	if params == nil {
+		params = make(map[string]string)
+	}
+	params["charset"] = "utf-8"
+	httpResp.Header.Set("Content-Type", mime.FormatMediaType(mediaType, params))
+}
+
+// UTF-8的解码函数。字节顺序标记会被去掉，非法的字节会被替换为U+FFFD。
+func decodeUtf8(content []byte) ([]byte, error) {
+	content = bytes.TrimPrefix(content, []byte{0xEF, 0xBB, 0xBF})
+	if utf8.Valid(content) {
+		return content, nil
+	}
+	return bytes.ToValidUTF8(content, []byte(string(utf8.RuneError))), nil
+}
+
+// 生成UTF-16的解码函数。参数bigEndian代表默认的字节序。字节顺序标记会覆盖默认的字节序。
+func decodeUtf16(bigEndian bool) CharsetDecoder {
+	return func(content []byte) ([]byte, error) {
+		bigEndian := bigEndian
+		switch {
+		case bytes.HasPrefix(content, []byte{0xFF, 0xFE}):
+			bigEndian = false
+			content = content[2:]
+		case bytes.HasPrefix(content, []byte{0xFE, 0xFF}):
+			bigEndian = true
+			content = content[2:]
+		}
+		if len(content)%2 != 0 {
+			return nil, errors.New(fmt.Sprintf("Odd length %d of UTF-16 content!", len(content)))
+		}
+		units := make([]uint16, len(content)/2)
+		for i := range units {
+			if bigEndian {
+				units[i] = uint16(content[2*i])<<8 | uint16(content[2*i+1])
+			} else {
+				units[i] = uint16(content[2*i+1])<<8 | uint16(content[2*i])
+			}
+		}
+		return []byte(string(utf16.Decode(units))), nil
+	}
+}
+
+// Windows-1252中0x80至0x9F的字节对应的字符。未定义的字节会被映射为同值的控制字符。
+var windows1252Table = [32]rune{
+	'€', 0x81, '‚', 'ƒ', '„', '…', '†', '‡', 'ˆ', '‰', 'Š', '‹', 'Œ', 0x8D, 'Ž', 0x8F,
+	0x90, '‘', '’', '“', '”', '•', '–', '—', '˜', '™', 'š', '›', 'œ', 0x9D, 'ž', 'Ÿ',
+}
+
+// Windows-1252的解码函数。ISO-8859-1和US-ASCII的内容也会使用它解码。
+func decodeWindows1252(content []byte) ([]byte, error) {
+	var buffer bytes.Buffer
+	buffer.Grow(len(content))
+	for _, b := range content {
+		switch {
+		case b < 0x80:
+			buffer.WriteByte(b)
+		case b < 0xA0:
+			buffer.WriteRune(windows1252Table[b-0x80])
+		default:
+			buffer.WriteRune(rune(b))
+		}
+	}
+	return buffer.Bytes(), nil
+}
@@ -0,0 +1,102 @@
+package analyzer
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"testing"
+)
+
+func TestDetectCharset(t *testing.T) {
+	cases := []struct {
+		contentType string
+		content     []byte
+		charset     string
+		source      CharsetSource
+	}{
+		{"text/html; charset=GBK", []byte{0xEF, 0xBB, 0xBF, 'a'}, "utf-8", CHARSET_FROM_BOM},
+		{"text/html; charset=GBK", []byte("<html>"), "gbk", CHARSET_FROM_HEADER},
+		{"text/html", []byte(`<head><meta charset="gb2312"></head>`), "gb2312", CHARSET_FROM_META},
+		{"text/html", []byte(`<meta http-equiv="Content-Type" content="text/html; charset=big5">`),
+			"big5", CHARSET_FROM_META},
+		{"", []byte(`<?xml version="1.0" encoding="GB18030"?><rss/>`), "gb18030", CHARSET_FROM_META},
+		{"text/html", []byte("中文"), "utf-8", CHARSET_FROM_GUESS},
+		// “中文网页”的GBK编码。
+		{"text/html", []byte{0xD6, 0xD0, 0xCE, 0xC4, 0xCD, 0xF8, 0xD2, 0xB3}, "gbk", CHARSET_FROM_GUESS},
+		// “這是我們的”的Big5編碼。
+		{"text/html", []byte{0xB3, 0x6F, 0xAC, 0x4F, 0xA7, 0xDA, 0xAD, 0xCC, 0xAA, 0xBA},
+			"big5", CHARSET_FROM_GUESS},
+		{"text/html", []byte{'c', 'a', 'f', 0xE9}, "windows-1252", CHARSET_FROM_GUESS},
+		// 零散的Latin-1字符偶尔会与后面的字母凑成看似合法的双字节字符。
+		{"text/html", []byte("d\xe9j\xe0"), "windows-1252", CHARSET_FROM_GUESS},
+		{"text/html", []byte("d\xe9j\xe0 vu, caf\xe9 cr\xe8me br\xfbl\xe9e"), "windows-1252", CHARSET_FROM_GUESS},
+	}
+	for _, c := range cases {
+		charset, source := DetectCharset(c.contentType, c.content)
+		if charset != c.charset || source != c.source {
+			t.Errorf("Unexpected charset %s (source=%s) for %q, expected %s (source=%s)!\n",
+				charset, source, c.content, c.charset, c.source)
+		}
+	}
+}
+
+func TestDecodeToUtf8(t *testing.T) {
+	// 用一个只认识“中文”的解码函数代替GBK的解码函数。
+	RegisterCharset("x-test-gbk", func(content []byte) ([]byte, error) {
+		return bytes.Replace(content, []byte{0xD6, 0xD0, 0xCE, 0xC4}, []byte("中文"), -1), nil
+	}, "x-test-gb2312")
+	newResp := func(contentType string, content []byte) *http.Response {
+		header := make(http.Header)
+		header.Set("Content-Type", contentType)
+		return &http.Response{Header: header, Body: ioutil.NopCloser(bytes.NewReader(content))}
+	}
+	httpResp := newResp("text/html; charset=X-Test-GB2312", []byte{'<', 'p', '>', 0xD6, 0xD0, 0xCE, 0xC4})
+	charset, err := DecodeToUtf8(httpResp)
+	if err != nil || charset != "x-test-gbk" {
+		t.Fatalf("Unexpected charset %s: %v\n", charset, err)
+	}
+	if body, _ := ioutil.ReadAll(httpResp.Body); string(body) != "<p>中文" {
+		t.Errorf("Unexpected body %q!\n", body)
+	}
+	if contentType := httpResp.Header.Get("Content-Type"); contentType != "text/html; charset=utf-8" {
+		t.Errorf("Unexpected content type %s!\n", contentType)
+	}
+	httpResp = newResp("text/plain", []byte{0xFF, 0xFE, 'h', 0, 'i', 0})
+	if charset, err := DecodeToUtf8(httpResp); err != nil || charset != "utf-16le" {
+		t.Fatalf("Unexpected charset %s: %v\n", charset, err)
+	}
+	if body, _ := ioutil.ReadAll(httpResp.Body); string(body) != "hi" {
+		t.Errorf("Unexpected body %q!\n", body)
+	}
+	// 没有Content-Type响应头时，字符集来自meta标签。重复转换不应改变结果。
+	page := []byte(`<meta charset="iso-8859-1"><p>caf` + "\xe9")
+	httpResp = &http.Response{Body: ioutil.NopCloser(bytes.NewReader(page))}
+	for i := 0; i < 2; i++ {
+		if _, err := DecodeToUtf8(httpResp); err != nil {
+			t.Fatalf("Decode error: %s\n", err)
+		}
+	}
+	if body, _ := ioutil.ReadAll(httpResp.Body); string(body) != `<meta charset="iso-8859-1"><p>café` {
+		t.Errorf("Unexpected body %q after decoding twice!\n", body)
+	}
+	if contentType := httpResp.Header.Get("Content-Type"); contentType != "text/html; charset=utf-8" {
+		t.Errorf("Unexpected content type %s!\n", contentType)
+	}
+	// 非文本的响应体应该保持原样。
+	image := []byte{0x89, 'P', 'N', 'G', 0xD6, 0xD0}
+	httpResp = newResp("image/png", image)
+	if charset, err := DecodeToUtf8(httpResp); err != nil || charset != "" {
+		t.Fatalf("Unexpected charset %s: %v\n", charset, err)
+	}
+	if body, _ := ioutil.ReadAll(httpResp.Body); !bytes.Equal(body, image) {
+		t.Errorf("The body of image should not be changed, but %q!\n", body)
+	}
+	// 不被支持的字符集会导致错误，但响应体应该保持原样。
+	httpResp = newResp("text/html; charset=x-unknown", []byte("abc"))
+	if _, err := DecodeToUtf8(httpResp); err == nil {
+		t.Errorf("An error is expected for unsupported charset!\n")
+	}
+	if body, _ := ioutil.ReadAll(httpResp.Body); string(body) != "abc" {
+		t.Errorf("Unexpected body %q!\n", body)
+	}
+}
@@ -0,0 +1,27 @@
+// 该包为分析器注册中文字符集（GBK、GB2312、GB18030以及Big5）的解码函数。
+// 使用时只需匿名导入即可：
+//
+//	import _ "webcrawler/analyzer/cjk"
+package cjk
+
+import (
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/encoding/traditionalchinese"
+	"webcrawler/analyzer"
+)
+
+func init() {
+	// GB18030兼容GBK和GB2312，所以它们共用同一个解码函数。
+	analyzer.RegisterCharset("gb18030", newDecoder(simplifiedchinese.GB18030),
+		"gbk", "gb2312", "cp936", "x-gbk", "csgb2312", "euc-cn", "windows-936")
+	analyzer.RegisterCharset("big5", newDecoder(traditionalchinese.Big5),
+		"big5-hkscs", "x-big5", "cn-big5", "csbig5", "cp950")
+}
+
+// 生成基于给定编码的解码函数。
+func newDecoder(enc encoding.Encoding) analyzer.CharsetDecoder {
+	return func(content []byte) ([]byte, error) {
+		return enc.NewDecoder().Bytes(content)
+	}
+}
@@ -0,0 +1,185 @@
+package analyzer
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	base "webcrawler/base"
+)
+
+// 创建用于解析JSON响应的函数。
+// 参数itemsPath代表条目所在位置的JSON路径。若它匹配到的唯一值是数组，那么数组中的每个元素都会成为一个条目。
+// 参数fields代表条目的字段名称与相对于条目的JSON路径之间的映射。
+// 若它为空，那么条目会包含对象的所有字段。可能匹配多个值的路径会使对应的字段值成为切片。
+// 参数paginations代表翻页方式的列表。每种翻页方式最多会产生一个针对下一页的请求。
+// 注意，每一页都会使请求的深度加1。表示失败的响应会被跳过，因为网页下载器已经报告了相应的状态错误。
+func NewJsonParser(
+	itemsPath string,
+	fields map[string]string,
+	paginations ...Pagination) (ParseResponse, error) {
+	items, err := CompileJsonPath(itemsPath)
+	if err != nil {
+		return nil, err
+	}
+	fieldPaths := make(map[string]*JsonPath, len(fields))
+	for name, expr := range fields {
+		if name == "" {
+			return nil, errors.New("Empty field name!")
+		}
+		path, err := CompileJsonPath(expr)
+		if err != nil {
+			return nil, err
+		}
+		fieldPaths[name] = path
+	}
+	for i, pagination := range paginations {
+		if pagination == nil {
+			return nil, errors.New(fmt.Sprintf("The pagination [%d] is invalid!", i))
+		}
+	}
+	parser := &jsonParser{items: items, fields: fieldPaths, paginations: paginations}
+	return parser.parse, nil
+}
+
+// JSON响应解析器。
+type jsonParser struct {
+	items       *JsonPath            // 条目所在位置的JSON路径。
+	fields      map[string]*JsonPath // 字段名称与JSON路径的映射。
+	paginations []Pagination         // 翻页方式的列表。
+}
+
+func (parser *jsonParser) parse(
+	httpResp *http.Response, respDepth uint32, respMeta base.Meta) ([]base.Data, []error) {
+	if base.IsFailureStatus(httpResp.StatusCode) {
+		return nil, nil
+	}
+	if httpResp.StatusCode < 200 || httpResp.StatusCode >= 300 {
+		return nil, []error{base.NewHttpStatusError(httpResp.StatusCode, responseUrl(httpResp))}
+	}
+	doc, err := decodeJsonBody(httpResp)
+	if err != nil {
+		return nil, []error{err}
+	}
+	dataList := make([]base.Data, 0)
+	errs := make([]error, 0)
+	itemCount := 0
+	for _, node := range parser.itemNodes(doc) {
+		item := parser.genItem(node)
+		if item == nil {
+			continue
+		}
+		dataList = append(dataList, &item)
+		itemCount++
+	}
+	for _, pagination := range parser.paginations {
+		httpReq, err := pagination.Next(httpResp, doc, itemCount)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if httpReq != nil {
+			dataList = append(dataList, base.NewRequest(httpReq, respDepth))
+		}
+	}
+	return dataList, errs
+}
+
+// 获得条目对应的值的列表。
+func (parser *jsonParser) itemNodes(doc interface{}) []interface{} {
+	nodes := parser.items.Find(doc)
+	if !parser.items.Multi() && len(nodes) == 1 {
+		if array, ok := nodes[0].([]interface{}); ok {
+			return array
+		}
+	}
+	return nodes
+}
+
+// 根据值生成条目。若无法生成则返回nil。
+// 条目中的json.Number类型的值会被转换为普通的数值，详见normalizeJsonValue函数。
+func (parser *jsonParser) genItem(node interface{}) base.Item {
+	if len(parser.fields) == 0 {
+		object, ok := node.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		item := make(base.Item, len(object))
+		for k, v := range object {
+			item[k] = normalizeJsonValue(v)
+		}
+		return item
+	}
+	item := make(base.Item, len(parser.fields))
+	for name, path := range parser.fields {
+		values := path.Find(node)
+		switch {
+		case path.Multi():
+			item[name] = normalizeJsonValue(values)
+		case len(values) > 0:
+			item[name] = normalizeJsonValue(values[0])
+		}
+	}
+	if len(item) == 0 {
+		return nil
+	}
+	return item
+}
+
+// 把JSON值中的json.Number类型的值转换为普通的数值，以便条目模式校验以及条目解码。
+// 整数会被转换为int64类型的值，超出其范围的非负整数会被转换为uint64类型的值，其他数值会被转换为float64类型的值。
+// 无法转换的数值会以字符串的形式保留。对象和数组中的值会被递归地转换，原值不会被修改。
+func normalizeJsonValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return n
+		}
+		if n, err := strconv.ParseUint(v.String(), 10, 64); err == nil {
+			return n
+		}
+		if f, err := v.Float64(); err == nil {
+			return f
+		}
+		return v.String()
+	case map[string]interface{}:
+		object := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			object[k] = normalizeJsonValue(e)
+		}
+		return object
+	case []interface{}:
+		array := make([]interface{}, len(v))
+		for i, e := range v {
+			array[i] = normalizeJsonValue(e)
+		}
+		return array
+	}
+	return value
+}
+
+// 读取并解码HTTP响应中的JSON文档。
+// 响应体会被读取，但在返回前会把一个内容相同的响应体放回原处，以供其他解析函数使用。
+// 文档中的数值会被解码为json.Number类型的值，以免较大的ID或游标失去精度。
+// 生成条目时，它们才会被转换为普通的数值。
+func decodeJsonBody(httpResp *http.Response) (interface{}, error) {
+	if httpResp.Body == nil {
+		return nil, errors.New("The http response body is invalid!")
+	}
+	body, err := ioutil.ReadAll(httpResp.Body)
+	httpResp.Body.Close()
+	httpResp.Body = ioutil.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	var doc interface{}
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, errors.New(fmt.Sprintf("Invalid JSON document: %s", err))
+	}
+	return doc, nil
+}
@@ -0,0 +1,207 @@
+package analyzer
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	base "webcrawler/base"
+	ipl "webcrawler/itempipeline"
+)
+
+func TestJsonPath(t *testing.T) {
+	var doc interface{}
+	json.Unmarshal([]byte(`{"data": {"items": [{"id": 1, "tags": ["a", "b"]}, {"id": 2, "tags": ["c"]}]},
+		"meta": {"next cursor": "x1", "id": 9}}`), &doc)
+	cases := []struct {
+		expr   string
+		expect string
+	}{
+		{"$.data.items[0].id", "[1]"},
+		{"$.data.items[-1].id", "[2]"},
+		{"$.data.items[*].id", "[1,2]"},
+		{"$..id", "[1,2,9]"},
+		{"$.data.items[*].tags[*]", `["a","b","c"]`},
+		{"$['meta']['next cursor']", `["x1"]`},
+		{"$.meta.*", `[9,"x1"]`},
+		{"$.missing.id", "null"},
+	}
+	for _, c := range cases {
+		path, err := CompileJsonPath(c.expr)
+		if err != nil {
+			t.Fatalf("Compile error: %s (expr=%s)\n", err, c.expr)
+		}
+		result, _ := json.Marshal(path.Find(doc))
+		if string(result) != c.expect {
+			t.Errorf("Unexpected result %s for '%s', expected %s!\n", result, c.expr, c.expect)
+		}
+	}
+	for _, expr := range []string{"$.", "$[1", "$[x]", "$..", "$a"} {
+		if _, err := CompileJsonPath(expr); err == nil {
+			t.Errorf("An error is expected for '%s'!\n", expr)
+		}
+	}
+}
+
+func newJsonResponse(httpReq *http.Request, body string, header http.Header) *http.Response {
+	if header == nil {
+		header = make(http.Header)
+	}
+	return &http.Response{
+		StatusCode: 200,
+		Header:     header,
+		Body:       ioutil.NopCloser(strings.NewReader(body)),
+		Request:    httpReq,
+	}
+}
+
+// 解析响应并分别返回条目和请求。
+func parseJson(t *testing.T, parser ParseResponse, httpResp *http.Response) ([]base.Item, []*base.Request) {
+	dataList, errs := parser(httpResp, 0, nil)
+	if len(errs) != 0 {
+		t.Fatalf("Parse error: %v\n", errs)
+	}
+	var items []base.Item
+	var reqs []*base.Request
+	for _, data := range dataList {
+		switch d := data.(type) {
+		case *base.Item:
+			items = append(items, *d)
+		case *base.Request:
+			reqs = append(reqs, d)
+		}
+	}
+	return items, reqs
+}
+
+func TestJsonParserCursor(t *testing.T) {
+	pagination, _ := NewCursorPagination("$.paging.cursor", "after")
+	parser, err := NewJsonParser("$.results",
+		map[string]string{"id": "@.id", "tags": "@.tags[*]"}, pagination)
+	if err != nil {
+		t.Fatalf("Parser error: %s\n", err)
+	}
+	httpReq, _ := http.NewRequest("GET", "http://api.example.com/v1/list?q=go", nil)
+	httpReq.Header.Set("Authorization", "Bearer t")
+	httpReq.Header.Set("If-None-Match", `"v1"`)
+	body := `{"results": [{"id": 12345678901234567, "tags": ["x"]}, {"id": 2}],
+		"paging": {"cursor": "c2"}}`
+	httpResp := newJsonResponse(httpReq, body, nil)
+	items, reqs := parseJson(t, parser, httpResp)
+	if len(items) != 2 || items[0]["id"] != int64(12345678901234567) {
+		t.Fatalf("Unexpected items %v!\n", items)
+	}
+	if tags := items[1]["tags"].([]interface{}); len(tags) != 0 {
+		t.Errorf("Unexpected tags %v!\n", tags)
+	}
+	if len(reqs) != 1 {
+		t.Fatalf("The number of requests should be 1, but %d!\n", len(reqs))
+	}
+	next := reqs[0].HttpReq()
+	if next.URL.String() != "http://api.example.com/v1/list?after=c2&q=go" {
+		t.Errorf("Unexpected next url %s!\n", next.URL)
+	}
+	if next.Header.Get("Authorization") != "Bearer t" || next.Header.Get("If-None-Match") != "" {
+		t.Errorf("Unexpected next headers %v!\n", next.Header)
+	}
+	// 响应体应该可以被再次读取。
+	if rest, _ := ioutil.ReadAll(httpResp.Body); string(rest) != body {
+		t.Errorf("The response body should be restored!\n")
+	}
+	// 游标为空时代表已没有下一页。
+	httpResp = newJsonResponse(next, `{"results": [], "paging": {"cursor": null}}`, nil)
+	if _, reqs := parseJson(t, parser, httpResp); len(reqs) != 0 {
+		t.Errorf("There should be no next page, but %d!\n", len(reqs))
+	}
+}
+
+func TestJsonParserOffset(t *testing.T) {
+	pagination, _ := NewOffsetPagination("offset", "limit", 2, "$.total")
+	parser, _ := NewJsonParser("$.items[*]", nil, pagination)
+	httpReq, _ := http.NewRequest("POST", "http://api.example.com/search",
+		bytes.NewReader([]byte(`{"query": "go", "offset": 0}`)))
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpResp := newJsonResponse(httpReq, `{"total": 5, "items": [{"n": 1}, {"n": 2}]}`, nil)
+	items, reqs := parseJson(t, parser, httpResp)
+	if len(items) != 2 || items[1]["n"] != int64(2) {
+		t.Fatalf("Unexpected items %v!\n", items)
+	}
+	if len(reqs) != 1 {
+		t.Fatalf("The number of requests should be 1, but %d!\n", len(reqs))
+	}
+	next := reqs[0]
+	if next.HttpReq().Method != "POST" ||
+		string(next.Body()) != `{"limit":2,"offset":2,"query":"go"}` {
+		t.Errorf("Unexpected next request %s %s!\n", next.HttpReq().Method, next.Body())
+	}
+	// 下一页的偏移量不小于条目总数时代表已没有下一页。
+	httpResp = newJsonResponse(next.HttpReq(), `{"total": 4, "items": [{"n": 3}, {"n": 4}]}`, nil)
+	if _, reqs := parseJson(t, parser, httpResp); len(reqs) != 0 {
+		t.Errorf("There should be no next page, but %d!\n", len(reqs))
+	}
+}
+
+func TestJsonParserLinkHeader(t *testing.T) {
+	nextUrl, _ := NewNextUrlPagination("$.next")
+	parser, _ := NewJsonParser("$", map[string]string{"name": "$.name"},
+		NewLinkHeaderPagination(), nextUrl)
+	httpReq, _ := http.NewRequest("GET", "http://api.example.com/repos?page=1", nil)
+	header := make(http.Header)
+	header.Add("Link", `<http://api.example.com/repos?page=1>; rel="first", `+
+		`</repos?page=2>; rel="next last"`)
+	httpResp := newJsonResponse(httpReq, `{"name": "crawler", "next": "?page=3"}`, header)
+	items, reqs := parseJson(t, parser, httpResp)
+	if len(items) != 1 || items[0]["name"] != "crawler" {
+		t.Fatalf("Unexpected items %v!\n", items)
+	}
+	if len(reqs) != 2 {
+		t.Fatalf("The number of requests should be 2, but %d!\n", len(reqs))
+	}
+	if url := reqs[0].HttpReq().URL.String(); url != "http://api.example.com/repos?page=2" {
+		t.Errorf("Unexpected link url %s!\n", url)
+	}
+	if url := reqs[1].HttpReq().URL.String(); url != "http://api.example.com/repos?page=3" {
+		t.Errorf("Unexpected next url %s!\n", url)
+	}
+	httpResp = newJsonResponse(httpReq, `{"name": `, nil)
+	if _, errs := parser(httpResp, 0, nil); len(errs) != 1 {
+		t.Errorf("An error is expected for invalid JSON, but %v!\n", errs)
+	}
+}
+
+func TestJsonParserItemRoundTrip(t *testing.T) {
+	parser, _ := NewJsonParser("$.items", nil)
+	httpReq, _ := http.NewRequest("GET", "http://api.example.com/items", nil)
+	httpResp := newJsonResponse(httpReq,
+		`{"items": [{"id": 42, "price": 9.5, "name": "go", "ids": [1, 2]}]}`, nil)
+	items, _ := parseJson(t, parser, httpResp)
+	if len(items) != 1 {
+		t.Fatalf("Unexpected items %v!\n", items)
+	}
+	schema, err := ipl.NewItemSchema(
+		ipl.NewFieldSpec("id", ipl.FIELD_KIND_INT, true),
+		ipl.NewFieldSpec("price", ipl.FIELD_KIND_FLOAT, true),
+		ipl.NewFieldSpec("name", ipl.FIELD_KIND_STRING, true),
+		ipl.NewFieldSpec("ids", ipl.FIELD_KIND_SLICE, true))
+	if err != nil {
+		t.Fatalf("Schema error: %s\n", err)
+	}
+	if errs := schema.Validate(items[0]); len(errs) != 0 {
+		t.Fatalf("Validate error: %v\n", errs)
+	}
+	var product struct {
+		Id    int     `item:"id"`
+		Price float64 `item:"price"`
+		Name  string  `item:"name"`
+		Ids   []int   `item:"ids"`
+	}
+	if err := items[0].Decode(&product); err != nil {
+		t.Fatalf("Decode error: %s\n", err)
+	}
+	if product.Id != 42 || product.Price != 9.5 || product.Name != "go" ||
+		len(product.Ids) != 2 || product.Ids[1] != 2 {
+		t.Errorf("Unexpected result %+v!\n", product)
+	}
+}
@@ -0,0 +1,190 @@
+package analyzer
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// JSON路径片段的类型。
+type jsonPathSegmentKind uint8
+
+const (
+	jsonPathChild     jsonPathSegmentKind = iota // 子元素，如“.name”或“['name']”。
+	jsonPathIndex                                // 数组元素，如“[0]”或“[-1]”。
+	jsonPathWildcard                             // 所有子元素，如“.*”或“[*]”。
+	jsonPathRecursive                            // 递归查找的子元素，如“..name”或“..*”。
+)
+
+// JSON路径的片段。
+type jsonPathSegment struct {
+	kind  jsonPathSegmentKind // 类型。
+	name  string              // 名称。为“*”时代表所有子元素（仅用于递归查找）。
+	index int                 // 数组的索引。负数代表从末尾开始计数。
+}
+
+// JSON路径。它支持JSONPath的一个常用的子集：
+// 根元素“$”（或当前元素“@”）、子元素“.name”和“['name']”、数组元素“[n]”、
+// 通配符“.*”和“[*]”，以及递归查找“..name”和“..*”。
+type JsonPath struct {
+	expr     string            // 表达式。
+	segments []jsonPathSegment // 片段的列表。
+	multi    bool              // 是否可能匹配多个值。
+}
+
+// 编译JSON路径表达式。
+func CompileJsonPath(expr string) (*JsonPath, error) {
+	path := &JsonPath{expr: expr}
+	rest := strings.TrimSpace(expr)
+	if strings.HasPrefix(rest, "$") || strings.HasPrefix(rest, "@") {
+		rest = rest[1:]
+	}
+	invalid := func(reason string) error {
+		return errors.New(fmt.Sprintf("Invalid JSON path '%s': %s", expr, reason))
+	}
+	for rest != "" {
+		var seg jsonPathSegment
+		switch {
+		case strings.HasPrefix(rest, ".."):
+			rest = rest[2:]
+			name := leadingName(rest)
+			if name == "" {
+				return nil, invalid("missing name after '..'")
+			}
+			seg = jsonPathSegment{kind: jsonPathRecursive, name: name}
+			rest = rest[len(name):]
+		case rest[0] == '.':
+			rest = rest[1:]
+			name := leadingName(rest)
+			if name == "" {
+				return nil, invalid("missing name after '.'")
+			}
+			if name == "*" {
+				seg = jsonPathSegment{kind: jsonPathWildcard}
+			} else {
+				seg = jsonPathSegment{kind: jsonPathChild, name: name}
+			}
+			rest = rest[len(name):]
+		case rest[0] == '[':
+			end := strings.Index(rest, "]")
+			if end < 0 {
+				return nil, invalid("unclosed '['")
+			}
+			inner := strings.TrimSpace(rest[1:end])
+			rest = rest[end+1:]
+			switch {
+			case inner == "*":
+				seg = jsonPathSegment{kind: jsonPathWildcard}
+			case len(inner) >= 2 && (inner[0] == '\'' || inner[0] == '"') && inner[len(inner)-1] == inner[0]:
+				seg = jsonPathSegment{kind: jsonPathChild, name: inner[1 : len(inner)-1]}
+			default:
+				index, err := strconv.Atoi(inner)
+				if err != nil {
+					return nil, invalid(fmt.Sprintf("illegal index '%s'", inner))
+				}
+				seg = jsonPathSegment{kind: jsonPathIndex, index: index}
+			}
+		default:
+			return nil, invalid(fmt.Sprintf("unexpected character '%c'", rest[0]))
+		}
+		if seg.kind == jsonPathWildcard || seg.kind == jsonPathRecursive {
+			path.multi = true
+		}
+		path.segments = append(path.segments, seg)
+	}
+	return path, nil
+}
+
+// 获得字符串开头的名称。名称在“.”或“[”之前结束。
+func leadingName(s string) string {
+	end := strings.IndexAny(s, ".[")
+	if end < 0 {
+		return s
+	}
+	return s[:end]
+}
+
+// 获得表达式。
+func (path *JsonPath) String() string {
+	return path.expr
+}
+
+// 判断该路径是否可能匹配多个值，即是否包含通配符或递归查找。
+func (path *JsonPath) Multi() bool {
+	return path.multi
+}
+
+// 在经过json.Unmarshal解码的文档中查找所有匹配的值。
+func (path *JsonPath) Find(doc interface{}) []interface{} {
+	current := []interface{}{doc}
+	for _, seg := range path.segments {
+		var next []interface{}
+		for _, node := range current {
+			next = appendMatches(next, node, seg)
+		}
+		current = next
+		if len(current) == 0 {
+			break
+		}
+	}
+	return current
+}
+
+// 在给定的值中查找与片段匹配的值，并追加到结果中。
+func appendMatches(result []interface{}, node interface{}, seg jsonPathSegment) []interface{} {
+	switch seg.kind {
+	case jsonPathChild:
+		if m, ok := node.(map[string]interface{}); ok {
+			if v, ok := m[seg.name]; ok {
+				result = append(result, v)
+			}
+		}
+	case jsonPathIndex:
+		if a, ok := node.([]interface{}); ok {
+			index := seg.index
+			if index < 0 {
+				index += len(a)
+			}
+			if index >= 0 && index < len(a) {
+				result = append(result, a[index])
+			}
+		}
+	case jsonPathWildcard:
+		result = appendChildren(result, node)
+	case jsonPathRecursive:
+		if seg.name == "*" {
+			result = appendChildren(result, node)
+		} else {
+			result = appendMatches(result, node, jsonPathSegment{kind: jsonPathChild, name: seg.name})
+		}
+		for _, child := range appendChildren(nil, node) {
+			result = appendMatches(result, child, seg)
+		}
+	}
+	return result
+}
+
+// 追加给定的值的所有子元素。对象的子元素会按照键的顺序排列。
+func appendChildren(result []interface{}, node interface{}) []interface{} {
+	switch v := node.(type) {
+	case map[string]interface{}:
+		for _, key := range sortedKeys(v) {
+			result = append(result, v[key])
+		}
+	case []interface{}:
+		result = append(result, v...)
+	}
+	return result
+}
+
+// 获得对象的所有键，并按照字典顺序排列。
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
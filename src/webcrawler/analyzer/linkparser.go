@@ -0,0 +1,134 @@
+package analyzer
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"strings"
+	base "webcrawler/base"
+)
+
+// 用于查找链接的正则表达式。
+var (
+	linkTagRegexp   = regexp.MustCompile(`(?is)<(a|area|base)\s[^>]*>`)
+	hrefRegexp      = regexp.MustCompile(`(?is)\shref\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s"'>]+))`)
+	altRegexp       = regexp.MustCompile(`(?is)\salt\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s"'>]+))`)
+	commentRegexp   = regexp.MustCompile(`(?s)<!--.*?-->`)
+	anchorEndRegexp = regexp.MustCompile(`(?i)</a\s*>|<a\s`)
+	tagRegexp       = regexp.MustCompile(`(?s)<[^>]*>`)
+)
+
+// 响应解析函数。它会从HTML网页的“A”和“AREA”标签中提取链接并生成请求。
+// 相对链接会基于“BASE”标签或请求的URL进行解析。JavaScript代码、邮件地址以及页内锚点都会被忽略。
+// 链接的锚文本（对于“AREA”标签则是其alt属性）会被存放在请求的元数据中。
+// 该函数只依赖于标准库，并不会解析完整的DOM，所以它适用于只需要链接的场景。
+// 表示失败的响应会被跳过，因为网页下载器已经报告了相应的状态错误。
+func ParseLinks(httpResp *http.Response, respDepth uint32, respMeta base.Meta) ([]base.Data, []error) {
+	if base.IsFailureStatus(httpResp.StatusCode) {
+		return nil, nil
+	}
+	if httpResp.StatusCode != 200 {
+		return nil, []error{base.NewHttpStatusError(httpResp.StatusCode, responseUrl(httpResp))}
+	}
+	if httpResp.Request == nil || httpResp.Request.URL == nil || httpResp.Body == nil {
+		return nil, []error{errors.New("The http response is invalid!")}
+	}
+	body, err := ioutil.ReadAll(httpResp.Body)
+	httpResp.Body.Close()
+	httpResp.Body = ioutil.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return nil, []error{err}
+	}
+	baseUrl := httpResp.Request.URL
+	dataList := make([]base.Data, 0)
+	errs := make([]error, 0)
+	seen := make(map[string]bool)
+	content := commentRegexp.ReplaceAll(body, nil)
+	for _, loc := range linkTagRegexp.FindAllSubmatchIndex(content, -1) {
+		tag, tagName := content[loc[0]:loc[1]], string(content[loc[2]:loc[3]])
+		href := extractAttr(hrefRegexp, tag)
+		if href == "" {
+			continue
+		}
+		if strings.EqualFold(tagName, "base") {
+			if baseHref, err := baseUrl.Parse(href); err == nil {
+				baseUrl = baseHref
+			}
+			continue
+		}
+		lowerHref := strings.ToLower(href)
+		if strings.HasPrefix(href, "#") ||
+			strings.HasPrefix(lowerHref, "javascript:") ||
+			strings.HasPrefix(lowerHref, "mailto:") {
+			continue
+		}
+		aUrl, err := baseUrl.Parse(href)
+		if err != nil {
+			errs = append(errs, errors.New(fmt.Sprintf("Invalid link '%s': %s", href, err)))
+			continue
+		}
+		aUrl.Fragment = ""
+		aUrl.RawFragment = ""
+		if scheme := strings.ToLower(aUrl.Scheme); scheme != "http" && scheme != "https" {
+			continue
+		}
+		link := aUrl.String()
+		if seen[link] {
+			continue
+		}
+		seen[link] = true
+		httpReq, err := http.NewRequest("GET", link, nil)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		req := base.NewRequest(httpReq, respDepth)
+		var anchor string
+		if strings.EqualFold(tagName, "area") {
+			anchor = extractAttr(altRegexp, tag)
+		} else {
+			anchor = extractAnchorText(content[loc[1]:])
+		}
+		if anchor != "" {
+			req.Meta()[base.META_KEY_ANCHOR_TEXT] = anchor
+		}
+		dataList = append(dataList, req)
+	}
+	return dataList, errs
+}
+
+// 提取“A”标签的锚文本。参数rest代表开始标签之后的内容。
+// 锚文本止于结束标签或下一个“A”标签，其中的标签会被去除，空白会被合并，HTML实体会被解码。
+func extractAnchorText(rest []byte) string {
+	if loc := anchorEndRegexp.FindIndex(rest); loc != nil {
+		rest = rest[:loc[0]]
+	}
+	text := html.UnescapeString(string(tagRegexp.ReplaceAll(rest, []byte(" "))))
+	return strings.Join(strings.Fields(text), " ")
+}
+
+// 提取标签中的属性的值。参数attrRegexp代表匹配该属性的正则表达式。HTML实体会被解码。
+func extractAttr(attrRegexp *regexp.Regexp, tag []byte) string {
+	match := attrRegexp.FindSubmatch(tag)
+	if match == nil {
+		return ""
+	}
+	for _, value := range match[1:] {
+		if len(value) > 0 {
+			return strings.TrimSpace(html.UnescapeString(string(value)))
+		}
+	}
+	return ""
+}
+
+// 获得HTTP响应所对应的请求的URL。该函数仅用于生成错误信息。
+func responseUrl(httpResp *http.Response) string {
+	if httpResp.Request == nil || httpResp.Request.URL == nil {
+		return ""
+	}
+	return httpResp.Request.URL.String()
+}
@@ -0,0 +1,65 @@
+package analyzer
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	base "webcrawler/base"
+)
+
+func TestParseLinks(t *testing.T) {
+	page := `<html><head><base href="http://example.com/docs/"></head><body>
+		<a href="intro.html">Intro</a>
+		<A class="x" HREF='/about?a=1&amp;b=2#team'><b>About</b>
+			us &amp; team</A>
+		<area shape="rect" href=http://other.com/map alt="Map">
+		<a href="intro.html#top">Intro again</a>
+		<!-- <a href="hidden.html">Hidden</a> -->
+		<a href="#top">Top</a><a href="javascript:void(0)">JS</a>
+		<a href="mailto:a@example.com">Mail</a><a href="ftp://example.com/f">FTP</a><a name="x">X</a>
+		</body></html>`
+	httpReq, _ := http.NewRequest("GET", "http://example.com/index.html", nil)
+	httpResp := &http.Response{
+		StatusCode: 200,
+		Request:    httpReq,
+		Body:       ioutil.NopCloser(strings.NewReader(page)),
+	}
+	dataList, errs := ParseLinks(httpResp, 1, nil)
+	if len(errs) != 0 {
+		t.Fatalf("Parse error: %v\n", errs)
+	}
+	expected := []string{
+		"http://example.com/docs/intro.html",
+		"http://example.com/about?a=1&b=2",
+		"http://other.com/map",
+	}
+	expectedAnchors := []string{"Intro", "About us & team", "Map"}
+	if len(dataList) != len(expected) {
+		t.Fatalf("The number of links should be %d, but %d!\n", len(expected), len(dataList))
+	}
+	for i, data := range dataList {
+		req := data.(*base.Request)
+		if url := req.HttpReq().URL.String(); url != expected[i] {
+			t.Errorf("Unexpected link [%d] %s, expected %s!\n", i, url, expected[i])
+		}
+		if req.Depth() != 1 {
+			t.Errorf("Unexpected depth %d!\n", req.Depth())
+		}
+		if anchor := req.Meta()[base.META_KEY_ANCHOR_TEXT]; anchor != expectedAnchors[i] {
+			t.Errorf("Unexpected anchor text [%d] %v, expected %s!\n", i, anchor, expectedAnchors[i])
+		}
+	}
+	if body, _ := ioutil.ReadAll(httpResp.Body); string(body) != page {
+		t.Errorf("The response body should be restored!\n")
+	}
+	// 状态错误已由网页下载器报告，这里不应再次报告。
+	httpResp = &http.Response{
+		StatusCode: 404,
+		Request:    httpReq,
+		Body:       ioutil.NopCloser(strings.NewReader(page)),
+	}
+	if dataList, errs := ParseLinks(httpResp, 1, nil); len(dataList) != 0 || len(errs) != 0 {
+		t.Errorf("The failed response should be skipped, but %v %v!\n", dataList, errs)
+	}
+}
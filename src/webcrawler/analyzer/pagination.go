@@ -0,0 +1,393 @@
+package analyzer
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// 翻页方式的接口类型。
+type Pagination interface {
+	// 根据HTTP响应、解码后的JSON文档以及从中解析出的条目的数量生成针对下一页的HTTP请求。
+	// 若已没有下一页，则结果值为nil。
+	Next(httpResp *http.Response, doc interface{}, itemCount int) (*http.Request, error)
+}
+
+// 创建基于游标的翻页方式。
+// 参数cursorPath代表游标在响应中的JSON路径。游标不存在、为null或为空时代表已没有下一页。
+// 参数param代表承载游标的请求参数的名称。
+func NewCursorPagination(cursorPath string, param string) (Pagination, error) {
+	path, err := CompileJsonPath(cursorPath)
+	if err != nil {
+		return nil, err
+	}
+	if param == "" {
+		return nil, errors.New("Empty cursor parameter!")
+	}
+	return &cursorPagination{path: path, param: param}, nil
+}
+
+// 基于游标的翻页方式。
+type cursorPagination struct {
+	path  *JsonPath // 游标的JSON路径。
+	param string    // 请求参数的名称。
+}
+
+func (pagination *cursorPagination) Next(
+	httpResp *http.Response, doc interface{}, itemCount int) (*http.Request, error) {
+	values := pagination.path.Find(doc)
+	if len(values) == 0 {
+		return nil, nil
+	}
+	cursor := jsonScalar(values[0])
+	if cursor == "" || cursor == "false" {
+		return nil, nil
+	}
+	if current, _ := readParam(httpResp.Request, pagination.param); current == cursor {
+		return nil, nil
+	}
+	return withParams(httpResp.Request, map[string]interface{}{pagination.param: values[0]})
+}
+
+// 创建基于偏移量的翻页方式。
+// 参数offsetParam和limitParam代表承载偏移量和每页条目数量的请求参数的名称。参数limitParam可以为空。
+// 参数pageSize代表每页条目数量。若它为0，那么会使用当前请求中的相应参数的值。
+// 参数totalPath代表条目总数在响应中的JSON路径。它可以为空。
+// 当前页的条目数量为0、小于每页条目数量，或下一页的偏移量不小于条目总数时，代表已没有下一页。
+func NewOffsetPagination(
+	offsetParam string,
+	limitParam string,
+	pageSize uint32,
+	totalPath string) (Pagination, error) {
+	if offsetParam == "" {
+		return nil, errors.New("Empty offset parameter!")
+	}
+	if pageSize == 0 && limitParam == "" {
+		return nil, errors.New("Either the page size or the limit parameter is required!")
+	}
+	pagination := &offsetPagination{
+		offsetParam: offsetParam,
+		limitParam:  limitParam,
+		pageSize:    pageSize,
+	}
+	if totalPath != "" {
+		path, err := CompileJsonPath(totalPath)
+		if err != nil {
+			return nil, err
+		}
+		pagination.total = path
+	}
+	return pagination, nil
+}
+
+// 基于偏移量的翻页方式。
+type offsetPagination struct {
+	offsetParam string    // 承载偏移量的请求参数的名称。
+	limitParam  string    // 承载每页条目数量的请求参数的名称。
+	pageSize    uint32    // 每页条目数量。
+	total       *JsonPath // 条目总数的JSON路径。
+}
+
+func (pagination *offsetPagination) Next(
+	httpResp *http.Response, doc interface{}, itemCount int) (*http.Request, error) {
+	if itemCount == 0 {
+		return nil, nil
+	}
+	var offset, limit uint64
+	if value, _ := readParam(httpResp.Request, pagination.offsetParam); value != "" {
+		n, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("Illegal offset '%s'!", value))
+		}
+		offset = n
+	}
+	limit = uint64(pagination.pageSize)
+	if limit == 0 {
+		value, _ := readParam(httpResp.Request, pagination.limitParam)
+		n, err := strconv.ParseUint(value, 10, 64)
+		if err != nil || n == 0 {
+			return nil, errors.New(fmt.Sprintf("Illegal limit '%s'!", value))
+		}
+		limit = n
+	}
+	if uint64(itemCount) < limit {
+		return nil, nil
+	}
+	next := offset + limit
+	if pagination.total != nil {
+		if values := pagination.total.Find(doc); len(values) > 0 {
+			total, err := strconv.ParseUint(jsonScalar(values[0]), 10, 64)
+			if err == nil && next >= total {
+				return nil, nil
+			}
+		}
+	}
+	params := map[string]interface{}{pagination.offsetParam: next}
+	if pagination.limitParam != "" {
+		params[pagination.limitParam] = limit
+	}
+	return withParams(httpResp.Request, params)
+}
+
+// 创建基于Link响应头的翻页方式（参见RFC 8288）。关系类型为“next”的链接代表下一页。
+func NewLinkHeaderPagination() Pagination {
+	return &linkHeaderPagination{}
+}
+
+// 基于Link响应头的翻页方式。
+type linkHeaderPagination struct{}
+
+func (pagination *linkHeaderPagination) Next(
+	httpResp *http.Response, doc interface{}, itemCount int) (*http.Request, error) {
+	for _, header := range httpResp.Header["Link"] {
+		for _, link := range splitLinks(header) {
+			target, rels := parseLink(link)
+			for _, rel := range strings.Fields(rels) {
+				if strings.EqualFold(rel, "next") {
+					return withUrl(httpResp.Request, target)
+				}
+			}
+		}
+	}
+	return nil, nil
+}
+
+// 把Link响应头的值拆分为多个链接。
+func splitLinks(header string) []string {
+	var links []string
+	var inUrl, inQuote bool
+	start := 0
+	for i, c := range header {
+		switch {
+		case c == '<' && !inQuote:
+			inUrl = true
+		case c == '>' && !inQuote:
+			inUrl = false
+		case c == '"' && !inUrl:
+			inQuote = !inQuote
+		case c == ',' && !inUrl && !inQuote:
+			links = append(links, header[start:i])
+			start = i + 1
+		}
+	}
+	return append(links, header[start:])
+}
+
+// 解析链接，并返回其目标和关系类型。
+func parseLink(link string) (target string, rels string) {
+	link = strings.TrimSpace(link)
+	if !strings.HasPrefix(link, "<") {
+		return "", ""
+	}
+	end := strings.Index(link, ">")
+	if end < 0 {
+		return "", ""
+	}
+	target = link[1:end]
+	for _, param := range strings.Split(link[end+1:], ";") {
+		kv := strings.SplitN(strings.TrimSpace(param), "=", 2)
+		if len(kv) == 2 && strings.EqualFold(strings.TrimSpace(kv[0]), "rel") {
+			rels = strings.Trim(strings.TrimSpace(kv[1]), "\"")
+		}
+	}
+	return target, rels
+}
+
+// 创建基于下一页URL的翻页方式。
+// 参数nextPath代表下一页的URL在响应中的JSON路径。相对URL会基于当前请求的URL进行解析。
+func NewNextUrlPagination(nextPath string) (Pagination, error) {
+	path, err := CompileJsonPath(nextPath)
+	if err != nil {
+		return nil, err
+	}
+	return &nextUrlPagination{path: path}, nil
+}
+
+// 基于下一页URL的翻页方式。
+type nextUrlPagination struct {
+	path *JsonPath // 下一页的URL的JSON路径。
+}
+
+func (pagination *nextUrlPagination) Next(
+	httpResp *http.Response, doc interface{}, itemCount int) (*http.Request, error) {
+	values := pagination.path.Find(doc)
+	if len(values) == 0 {
+		return nil, nil
+	}
+	target, ok := values[0].(string)
+	if !ok || strings.TrimSpace(target) == "" {
+		return nil, nil
+	}
+	return withUrl(httpResp.Request, target)
+}
+
+// 获得JSON标量值或整数的字符串形式。null以及非标量值会被视为空字符串。
+func jsonScalar(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	case uint64:
+		return strconv.FormatUint(v, 10)
+	}
+	return ""
+}
+
+// 在翻页时不被复制的请求头。
+var skippedPageHeaders = []string{
+	"Cookie", "Content-Length", "Referer",
+	"If-None-Match", "If-Modified-Since", "If-Match", "If-Unmodified-Since",
+}
+
+// 根据当前请求创建针对给定URL的请求。HTTP方法、请求头和请求体都会被复制。
+func withUrl(parent *http.Request, target string) (*http.Request, error) {
+	if parent == nil || parent.URL == nil {
+		return nil, errors.New("The http request is invalid!")
+	}
+	targetUrl, err := parent.URL.Parse(strings.TrimSpace(target))
+	if err != nil {
+		return nil, err
+	}
+	body, err := requestBody(parent)
+	if err != nil {
+		return nil, err
+	}
+	return newPageRequest(parent, targetUrl, body)
+}
+
+// 根据当前请求创建设置了给定参数的请求。
+// 若当前请求的请求体是表单或JSON对象，那么参数会被设置在请求体中，否则会被设置在URL的查询字符串中。
+// 参数值会保持其在JSON对象中的类型，在其他位置则会被转换为字符串。
+func withParams(parent *http.Request, params map[string]interface{}) (*http.Request, error) {
+	if parent == nil || parent.URL == nil {
+		return nil, errors.New("The http request is invalid!")
+	}
+	body, err := requestBody(parent)
+	if err != nil {
+		return nil, err
+	}
+	targetUrl := *parent.URL
+	switch bodyType(parent, body) {
+	case "application/x-www-form-urlencoded":
+		form, err := url.ParseQuery(string(body))
+		if err != nil {
+			return nil, err
+		}
+		for k, v := range params {
+			form.Set(k, jsonScalar(v))
+		}
+		body = []byte(form.Encode())
+	case "application/json":
+		var object map[string]interface{}
+		decoder := json.NewDecoder(bytes.NewReader(body))
+		decoder.UseNumber()
+		if err := decoder.Decode(&object); err != nil {
+			return nil, err
+		}
+		for k, v := range params {
+			object[k] = v
+		}
+		if body, err = json.Marshal(object); err != nil {
+			return nil, err
+		}
+	default:
+		query := targetUrl.Query()
+		for k, v := range params {
+			query.Set(k, jsonScalar(v))
+		}
+		targetUrl.RawQuery = query.Encode()
+	}
+	return newPageRequest(parent, &targetUrl, body)
+}
+
+// 读取当前请求中的给定参数的值。其查找位置与withParams函数的设置位置一致。
+func readParam(parent *http.Request, name string) (string, error) {
+	if parent == nil || parent.URL == nil || name == "" {
+		return "", nil
+	}
+	body, err := requestBody(parent)
+	if err != nil {
+		return "", err
+	}
+	switch bodyType(parent, body) {
+	case "application/x-www-form-urlencoded":
+		form, err := url.ParseQuery(string(body))
+		if err != nil {
+			return "", err
+		}
+		return form.Get(name), nil
+	case "application/json":
+		var object map[string]interface{}
+		decoder := json.NewDecoder(bytes.NewReader(body))
+		decoder.UseNumber()
+		if err := decoder.Decode(&object); err != nil {
+			return "", err
+		}
+		return jsonScalar(object[name]), nil
+	}
+	return parent.URL.Query().Get(name), nil
+}
+
+// 获得请求体的媒体类型。若请求体为空或其类型不被支持，则结果值为空字符串。
+func bodyType(req *http.Request, body []byte) string {
+	if len(body) == 0 {
+		return ""
+	}
+	mediaType, _, _ := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	switch mediaType {
+	case "application/x-www-form-urlencoded":
+		return mediaType
+	case "application/json":
+		if trimmed := bytes.TrimSpace(body); len(trimmed) > 0 && trimmed[0] == '{' {
+			return mediaType
+		}
+	}
+	return ""
+}
+
+// 获得请求的请求体的副本。
+func requestBody(req *http.Request) ([]byte, error) {
+	if req.GetBody == nil {
+		return nil, nil
+	}
+	reader, err := req.GetBody()
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return ioutil.ReadAll(reader)
+}
+
+// 创建针对下一页的请求。
+func newPageRequest(parent *http.Request, targetUrl *url.URL, body []byte) (*http.Request, error) {
+	var httpReq *http.Request
+	var err error
+	if len(body) > 0 {
+		httpReq, err = http.NewRequest(parent.Method, targetUrl.String(), bytes.NewReader(body))
+	} else {
+		httpReq, err = http.NewRequest(parent.Method, targetUrl.String(), nil)
+	}
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header = parent.Header.Clone()
+	if httpReq.Header == nil {
+		httpReq.Header = make(http.Header)
+	}
+	for _, name := range skippedPageHeaders {
+		httpReq.Header.Del(name)
+	}
+	return httpReq, nil
+}
@@ -1,9 +1,11 @@
-package analyzer
-
-import (
-	"net/http"
-	base "webcrawler/base"
-)
-
-// 被用于解析HTTP响应的函数类型。
-type ParseResponse func(httpResp *http.Response, respDepth uint32) ([]base.Data, []error)
+package analyzer
+
+import (
+	"net/http"
+	base "webcrawler/base"
+)
+
+// 被用于解析HTTP响应的函数类型。
+// 参数respMeta代表响应的元数据，它来自于相应的请求。
+// 从响应中解析出的请求会自动继承这些元数据，除非请求中已存在同名的元素。
+type ParseResponse func(httpResp *http.Response, respDepth uint32, respMeta base.Meta) ([]base.Data, []error)
@@ -0,0 +1,225 @@
+package analyzer
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"unicode"
+)
+
+// 网页去重器的接口类型。
+type PageDeduplicator interface {
+	// 检查HTTP响应所承载的网页是否与已检查过的某个网页近似重复。
+	// 没有任何文本的网页无法比较，它总不会被视为近似重复，也不会被加入索引。
+	// 该方法会读取响应体，但在返回前会把一个内容相同的响应体放回原处。
+	Check(httpResp *http.Response) (nearDup bool, err error)
+	// 获得已检查和被判定为近似重复的网页的计数值。
+	// 作为结果值的切片总会有两个元素值，分别代表前述的两个计数。
+	Count() []uint64
+	// 获取摘要信息。
+	Summary() string
+}
+
+// 创建基于SimHash的网页去重器。
+// 参数threshold代表汉明距离的阈值。指纹之间的汉明距离不大于此值的网页会被视为近似重复。
+func NewSimHashDeduplicator(threshold uint8) PageDeduplicator {
+	if threshold > 63 {
+		panic(errors.New(fmt.Sprintf("Invalid hamming distance threshold %d!\n", threshold)))
+	}
+	blockNumber := int(threshold) + 1
+	index := make([]map[uint64][]uint64, blockNumber)
+	for i := range index {
+		index[i] = make(map[uint64][]uint64)
+	}
+	return &simHashDeduplicator{threshold: threshold, index: index}
+}
+
+// 基于SimHash的网页去重器的实现类型。
+// 指纹会被平均分成threshold+1块。根据抽屉原理，近似重复的两个指纹至少会有一块完全相同，
+// 所以只需对块值相同的指纹计算汉明距离即可。
+type simHashDeduplicator struct {
+	threshold uint8                 // 汉明距离的阈值。
+	index     []map[uint64][]uint64 // 指纹的分块索引。
+	mutex     sync.Mutex            // 针对分块索引操作的互斥锁。
+	checked   uint64                // 已检查的网页的数量。
+	nearDup   uint64                // 被判定为近似重复的网页的数量。
+}
+
+func (dedup *simHashDeduplicator) Check(httpResp *http.Response) (bool, error) {
+	if httpResp == nil || httpResp.Body == nil {
+		return false, errors.New("The http response is invalid!")
+	}
+	body, err := ioutil.ReadAll(httpResp.Body)
+	httpResp.Body.Close()
+	httpResp.Body = ioutil.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	atomic.AddUint64(&dedup.checked, 1)
+	tokens := tokenize(ExtractText(body))
+	if len(tokens) == 0 {
+		return false, nil
+	}
+	if dedup.findOrAdd(simHash(tokens)) {
+		atomic.AddUint64(&dedup.nearDup, 1)
+		return true, nil
+	}
+	return false, nil
+}
+
+// 查找与给定指纹近似重复的指纹。若未找到，则把给定指纹加入索引。
+func (dedup *simHashDeduplicator) findOrAdd(fingerprint uint64) bool {
+	dedup.mutex.Lock()
+	defer dedup.mutex.Unlock()
+	keys := make([]uint64, len(dedup.index))
+	for i := range dedup.index {
+		keys[i] = dedup.blockKey(fingerprint, i)
+		for _, fp := range dedup.index[i][keys[i]] {
+			if HammingDistance(fp, fingerprint) <= dedup.threshold {
+				return true
+			}
+		}
+	}
+	for i := range dedup.index {
+		dedup.index[i][keys[i]] = append(dedup.index[i][keys[i]], fingerprint)
+	}
+	return false
+}
+
+// 获取指纹中第i块的值。
+func (dedup *simHashDeduplicator) blockKey(fingerprint uint64, i int) uint64 {
+	blockNumber := len(dedup.index)
+	width := 64 / blockNumber
+	start := i * width
+	if i == blockNumber-1 {
+		width = 64 - start
+	}
+	if width == 64 {
+		return fingerprint
+	}
+	return (fingerprint >> uint(start)) & (1<<uint(width) - 1)
+}
+
+func (dedup *simHashDeduplicator) Count() []uint64 {
+	counts := make([]uint64, 2)
+	counts[0] = atomic.LoadUint64(&dedup.checked)
+	counts[1] = atomic.LoadUint64(&dedup.nearDup)
+	return counts
+}
+
+var simHashSummaryTemplate = "threshold: %d, checked: %d, nearDuplicate: %d"
+
+func (dedup *simHashDeduplicator) Summary() string {
+	counts := dedup.Count()
+	return fmt.Sprintf(simHashSummaryTemplate,
+		dedup.threshold, counts[0], counts[1])
+}
+
+// 计算文本的SimHash指纹。
+// 文本中的单词（或单个汉字）以及相邻两者组成的词组都会作为特征参与计算，权重为其出现次数。
+// 没有任何单词的文本的指纹为0。
+func SimHash(text string) uint64 {
+	return simHash(tokenize(text))
+}
+
+// 根据单词列表计算SimHash指纹。
+func simHash(tokens []string) uint64 {
+	weights := make(map[string]int)
+	for i, token := range tokens {
+		weights[token]++
+		if i > 0 {
+			weights[tokens[i-1]+" "+token]++
+		}
+	}
+	var vector [64]int
+	for feature, weight := range weights {
+		h := fnv.New64a()
+		h.Write([]byte(feature))
+		sum := h.Sum64()
+		for i := 0; i < 64; i++ {
+			if sum&(1<<uint(i)) != 0 {
+				vector[i] += weight
+			} else {
+				vector[i] -= weight
+			}
+		}
+	}
+	var fingerprint uint64
+	for i := 0; i < 64; i++ {
+		if vector[i] > 0 {
+			fingerprint |= 1 << uint(i)
+		}
+	}
+	return fingerprint
+}
+
+// 计算两个指纹之间的汉明距离。
+func HammingDistance(a, b uint64) uint8 {
+	var distance uint8
+	for x := a ^ b; x != 0; x &= x - 1 {
+		distance++
+	}
+	return distance
+}
+
+// 把文本切分成单词。汉字等表意文字会被逐个切分。
+func tokenize(text string) []string {
+	tokens := make([]string, 0)
+	var word []rune
+	flush := func() {
+		if len(word) > 0 {
+			tokens = append(tokens, string(word))
+			word = word[:0]
+		}
+	}
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case unicode.Is(unicode.Han, r):
+			flush()
+			tokens = append(tokens, string(r))
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			word = append(word, r)
+		default:
+			flush()
+		}
+	}
+	flush()
+	return tokens
+}
+
+// 从HTML文档中提取文本。标签、注释以及script和style元素中的内容都会被忽略。
+func ExtractText(body []byte) string {
+	var buffer bytes.Buffer
+	lower := bytes.ToLower(body)
+	for i := 0; i < len(body); {
+		if body[i] != '<' {
+			buffer.WriteByte(body[i])
+			i++
+			continue
+		}
+		var skipTo []byte
+		switch {
+		case bytes.HasPrefix(lower[i:], []byte("<!--")):
+			skipTo = []byte("-->")
+		case bytes.HasPrefix(lower[i:], []byte("<script")):
+			skipTo = []byte("</script>")
+		case bytes.HasPrefix(lower[i:], []byte("<style")):
+			skipTo = []byte("</style>")
+		default:
+			skipTo = []byte(">")
+		}
+		end := bytes.Index(lower[i:], skipTo)
+		if end < 0 {
+			break
+		}
+		i += end + len(skipTo)
+		buffer.WriteByte(' ')
+	}
+	return buffer.String()
+}
@@ -0,0 +1,108 @@
+package analyzer
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+var pageTemplate = `<html><head><title>Page</title>
+<style>body { color: red; }</style>
+<script>var tracker = "%s";</script></head>
+<body><h1>Go Concurrency Programming</h1>
+<p>Goroutines are lightweight threads managed by the Go runtime.
+Channels are the pipes that connect concurrent goroutines.
+You can send values into channels from one goroutine and receive
+those values into another goroutine.</p>
+<p>The select statement lets a goroutine wait on multiple
+communication operations.</p>
+<!-- %s --></body></html>`
+
+func newPageResponse(noise string) *http.Response {
+	page := strings.Replace(pageTemplate, "%s", noise, -1)
+	return &http.Response{Body: ioutil.NopCloser(strings.NewReader(page))}
+}
+
+func TestExtractText(t *testing.T) {
+	body := []byte(`<p>Hello<!-- hidden --></p><script>alert(1)</script><b>world</b>`)
+	tokens := tokenize(ExtractText(body))
+	if len(tokens) != 2 || tokens[0] != "hello" || tokens[1] != "world" {
+		t.Errorf("Unexpected tokens %v!\n", tokens)
+	}
+}
+
+func TestHammingDistance(t *testing.T) {
+	if d := HammingDistance(0, 0); d != 0 {
+		t.Errorf("The distance should be 0, but %d!\n", d)
+	}
+	if d := HammingDistance(0xF0, 0x0F); d != 8 {
+		t.Errorf("The distance should be 8, but %d!\n", d)
+	}
+	if d := HammingDistance(0, ^uint64(0)); d != 64 {
+		t.Errorf("The distance should be 64, but %d!\n", d)
+	}
+}
+
+func TestSimHashDeduplicator(t *testing.T) {
+	dedup := NewSimHashDeduplicator(3)
+	nearDup, err := dedup.Check(newPageResponse("a"))
+	if err != nil {
+		t.Fatalf("Check error: %s\n", err)
+	}
+	if nearDup {
+		t.Errorf("The first page should not be a near-duplicate!\n")
+	}
+	// 只有脚本和注释中的内容不同。
+	resp := newPageResponse("b")
+	nearDup, err = dedup.Check(resp)
+	if err != nil {
+		t.Fatalf("Check error: %s\n", err)
+	}
+	if !nearDup {
+		t.Errorf("The second page should be a near-duplicate!\n")
+	}
+	body, _ := ioutil.ReadAll(resp.Body)
+	if len(body) == 0 {
+		t.Errorf("The response body should be restored!\n")
+	}
+	other := &http.Response{Body: ioutil.NopCloser(strings.NewReader(
+		"<p>A completely different page about web crawlers, " +
+			"schedulers, downloaders, analyzers and item pipelines.</p>"))}
+	nearDup, err = dedup.Check(other)
+	if err != nil {
+		t.Fatalf("Check error: %s\n", err)
+	}
+	if nearDup {
+		t.Errorf("The third page should not be a near-duplicate!\n")
+	}
+	counts := dedup.Count()
+	if counts[0] != 3 || counts[1] != 1 {
+		t.Errorf("Unexpected counts %v!\n", counts)
+	}
+}
+
+func TestSimHashDeduplicatorEmptyText(t *testing.T) {
+	dedup := NewSimHashDeduplicator(3)
+	// 两个内容不同但都没有可见文本的网页。
+	for i, page := range []string{
+		`<html><head><script>var a = 1;</script></head><body></body></html>`,
+		`<html><body><img src="/logo.png"><!-- banner --></body></html>`,
+	} {
+		resp := &http.Response{Body: ioutil.NopCloser(strings.NewReader(page))}
+		nearDup, err := dedup.Check(resp)
+		if err != nil {
+			t.Fatalf("Check error: %s\n", err)
+		}
+		if nearDup {
+			t.Errorf("The page [%d] without text should not be a near-duplicate!\n", i)
+		}
+	}
+	if nearDup, _ := dedup.Check(newPageResponse("a")); nearDup {
+		t.Errorf("The page with text should not be a near-duplicate of empty pages!\n")
+	}
+	counts := dedup.Count()
+	if counts[0] != 3 || counts[1] != 0 {
+		t.Errorf("Unexpected counts %v!\n", counts)
+	}
+}
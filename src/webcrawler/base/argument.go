@@ -1,141 +1,708 @@
-package base
-
-import (
-	"errors"
-	"fmt"
-)
-
-// 参数容器的接口。
-type Args interface {
-	// 自检参数的有效性，并在必要时返回可以说明问题的错误值。
-	// 若结果值为nil，则说明未发现问题，否则就意味着自检未通过。
-	Check() error
-	// 获得参数容器的字符串表现形式。
-	String() string
-}
-
-// 通道参数的容器的描述模板。
-var channelArgsTemplate string = "{ reqChanLen: %d, respChanLen: %d," +
-	" itemChanLen: %d, errorChanLen: %d }"
-
-// 通道参数的容器。
-type ChannelArgs struct {
-	reqChanLen   uint   // 请求通道的长度。
-	respChanLen  uint   // 响应通道的长度。
-	itemChanLen  uint   // 条目通道的长度。
-	errorChanLen uint   // 错误通道的长度。
-	description  string // 描述。
-}
-
-// 创建通道参数的容器。
-func NewChannelArgs(
-	reqChanLen uint,
-	respChanLen uint,
-	itemChanLen uint,
-	errorChanLen uint) ChannelArgs {
-	return ChannelArgs{
-		reqChanLen:   reqChanLen,
-		respChanLen:  respChanLen,
-		itemChanLen:  itemChanLen,
-		errorChanLen: errorChanLen,
-	}
-}
-
-func (args *ChannelArgs) Check() error {
-	if args.reqChanLen == 0 {
-		return errors.New("The request channel max length (capacity) can not be 0!\n")
-	}
-	if args.respChanLen == 0 {
-		return errors.New("The response channel max length (capacity) can not be 0!\n")
-	}
-	if args.itemChanLen == 0 {
-		return errors.New("The item channel max length (capacity) can not be 0!\n")
-	}
-	if args.errorChanLen == 0 {
-		return errors.New("The error channel max length (capacity) can not be 0!\n")
-	}
-	return nil
-}
-
-func (args *ChannelArgs) String() string {
-	if args.description == "" {
-		args.description =
-			fmt.Sprintf(channelArgsTemplate,
-				args.reqChanLen,
-				args.respChanLen,
-				args.itemChanLen,
-				args.errorChanLen)
-	}
-	return args.description
-}
-
-// 获得请求通道的长度。
-func (args *ChannelArgs) ReqChanLen() uint {
-	return args.reqChanLen
-}
-
-// 获得响应通道的长度。
-func (args *ChannelArgs) RespChanLen() uint {
-	return args.respChanLen
-}
-
-// 获得条目通道的长度。
-func (args *ChannelArgs) ItemChanLen() uint {
-	return args.itemChanLen
-}
-
-// 获得错误通道的长度。
-func (args *ChannelArgs) ErrorChanLen() uint {
-	return args.errorChanLen
-}
-
-// 池基本参数容器的描述模板。
-var poolBaseArgsTemplate string = "{ pageDownloaderPoolSize: %d," +
-	" analyzerPoolSize: %d }"
-
-// 池基本参数的容器。
-type PoolBaseArgs struct {
-	pageDownloaderPoolSize uint32 // 网页下载器池的尺寸。
-	analyzerPoolSize       uint32 // 分析器池的尺寸。
-	description            string // 描述。
-}
-
-// 创建池基本参数的容器。
-func NewPoolBaseArgs(
-	pageDownloaderPoolSize uint32,
-	analyzerPoolSize uint32) PoolBaseArgs {
-	return PoolBaseArgs{
-		pageDownloaderPoolSize: pageDownloaderPoolSize,
-		analyzerPoolSize:       analyzerPoolSize,
-	}
-}
-
-func (args *PoolBaseArgs) Check() error {
-	if args.pageDownloaderPoolSize == 0 {
-		return errors.New("The page downloader pool size can not be 0!\n")
-	}
-	if args.analyzerPoolSize == 0 {
-		return errors.New("The analyzer pool size can not be 0!\n")
-	}
-	return nil
-}
-
-func (args *PoolBaseArgs) String() string {
-	if args.description == "" {
-		args.description =
-			fmt.Sprintf(poolBaseArgsTemplate,
-				args.pageDownloaderPoolSize,
-				args.analyzerPoolSize)
-	}
-	return args.description
-}
-
-// 获得网页下载器池的尺寸。
-func (args *PoolBaseArgs) PageDownloaderPoolSize() uint32 {
-	return args.pageDownloaderPoolSize
-}
-
-// 获得分析器池的尺寸。
-func (args *PoolBaseArgs) AnalyzerPoolSize() uint32 {
-	return args.analyzerPoolSize
-}
+package base
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// 参数容器的接口。
+type Args interface {
+	// 自检参数的有效性，并在必要时返回可以说明问题的错误值。
+	// 若结果值为nil，则说明未发现问题，否则就意味着自检未通过。
+	Check() error
+	// 获得参数容器的字符串表现形式。
+	String() string
+}
+
+// 溢出策略。它决定了在错误缓冲区已满时如何处理新的错误。
+type OverflowPolicy uint8
+
+// 溢出策略常量。
+const (
+	OVERFLOW_POLICY_DROP_OLDEST OverflowPolicy = 0 // 丢弃最早的错误。
+	OVERFLOW_POLICY_DROP_NEWEST OverflowPolicy = 1 // 丢弃最新的错误。
+	OVERFLOW_POLICY_BLOCK       OverflowPolicy = 2 // 阻塞发送方，直到缓冲区有空余。
+)
+
+// 表示溢出策略与其名称之间的映射关系的字典。
+var overflowPolicyNameMap = map[OverflowPolicy]string{
+	OVERFLOW_POLICY_DROP_OLDEST: "drop-oldest",
+	OVERFLOW_POLICY_DROP_NEWEST: "drop-newest",
+	OVERFLOW_POLICY_BLOCK:       "block",
+}
+
+// 获得溢出策略的名称。若该策略不被支持，则第二个结果值为false。
+func OverflowPolicyName(policy OverflowPolicy) (string, bool) {
+	name, ok := overflowPolicyNameMap[policy]
+	return name, ok
+}
+
+// 通道参数的容器的描述模板。
+var channelArgsTemplate string = "{ reqChanLen: %d, respChanLen: %d," +
+	" itemChanLen: %d, errorChanLen: %d, errorOverflowPolicy: %s }"
+
+// 通道参数的容器。
+type ChannelArgs struct {
+	reqChanLen          uint           // 请求通道的长度。
+	respChanLen         uint           // 响应通道的长度。
+	itemChanLen         uint           // 条目通道的长度。
+	errorChanLen        uint           // 错误通道的长度。也是错误缓冲区的容量。
+	errorOverflowPolicy OverflowPolicy // 错误缓冲区的溢出策略。
+	description         string         // 描述。
+}
+
+// 创建通道参数的容器。
+func NewChannelArgs(
+	reqChanLen uint,
+	respChanLen uint,
+	itemChanLen uint,
+	errorChanLen uint) ChannelArgs {
+	return ChannelArgs{
+		reqChanLen:   reqChanLen,
+		respChanLen:  respChanLen,
+		itemChanLen:  itemChanLen,
+		errorChanLen: errorChanLen,
+	}
+}
+
+// 创建带有错误缓冲区溢出策略的通道参数的容器。
+func NewChannelArgsWithPolicy(
+	reqChanLen uint,
+	respChanLen uint,
+	itemChanLen uint,
+	errorChanLen uint,
+	errorOverflowPolicy OverflowPolicy) ChannelArgs {
+	args := NewChannelArgs(reqChanLen, respChanLen, itemChanLen, errorChanLen)
+	args.errorOverflowPolicy = errorOverflowPolicy
+	return args
+}
+
+func (args *ChannelArgs) Check() error {
+	if args.reqChanLen == 0 {
+		return errors.New("The request channel max length (capacity) can not be 0!\n")
+	}
+	if args.respChanLen == 0 {
+		return errors.New("The response channel max length (capacity) can not be 0!\n")
+	}
+	if args.itemChanLen == 0 {
+		return errors.New("The item channel max length (capacity) can not be 0!\n")
+	}
+	if args.errorChanLen == 0 {
+		return errors.New("The error channel max length (capacity) can not be 0!\n")
+	}
+	if _, ok := OverflowPolicyName(args.errorOverflowPolicy); !ok {
+		return errors.New(fmt.Sprintf("Unsupported error overflow policy %d!\n", args.errorOverflowPolicy))
+	}
+	return nil
+}
+
+func (args *ChannelArgs) String() string {
+	if args.description == "" {
+		args.description =
+			fmt.Sprintf(channelArgsTemplate,
+				args.reqChanLen,
+				args.respChanLen,
+				args.itemChanLen,
+				args.errorChanLen,
+				overflowPolicyNameMap[args.errorOverflowPolicy])
+	}
+	return args.description
+}
+
+// 获得请求通道的长度。
+func (args *ChannelArgs) ReqChanLen() uint {
+	return args.reqChanLen
+}
+
+// 获得响应通道的长度。
+func (args *ChannelArgs) RespChanLen() uint {
+	return args.respChanLen
+}
+
+// 获得条目通道的长度。
+func (args *ChannelArgs) ItemChanLen() uint {
+	return args.itemChanLen
+}
+
+// 获得错误通道的长度。
+func (args *ChannelArgs) ErrorChanLen() uint {
+	return args.errorChanLen
+}
+
+// 获得错误缓冲区的溢出策略。
+func (args *ChannelArgs) ErrorOverflowPolicy() OverflowPolicy {
+	return args.errorOverflowPolicy
+}
+
+// 池基本参数容器的描述模板。
+var poolBaseArgsTemplate string = "{ pageDownloaderPoolSize: %d," +
+	" analyzerPoolSize: %d }"
+
+// 池基本参数的容器。
+type PoolBaseArgs struct {
+	pageDownloaderPoolSize uint32 // 网页下载器池的尺寸。
+	analyzerPoolSize       uint32 // 分析器池的尺寸。
+	description            string // 描述。
+}
+
+// 创建池基本参数的容器。
+func NewPoolBaseArgs(
+	pageDownloaderPoolSize uint32,
+	analyzerPoolSize uint32) PoolBaseArgs {
+	return PoolBaseArgs{
+		pageDownloaderPoolSize: pageDownloaderPoolSize,
+		analyzerPoolSize:       analyzerPoolSize,
+	}
+}
+
+func (args *PoolBaseArgs) Check() error {
+	if args.pageDownloaderPoolSize == 0 {
+		return errors.New("The page downloader pool size can not be 0!\n")
+	}
+	if args.analyzerPoolSize == 0 {
+		return errors.New("The analyzer pool size can not be 0!\n")
+	}
+	return nil
+}
+
+func (args *PoolBaseArgs) String() string {
+	if args.description == "" {
+		args.description =
+			fmt.Sprintf(poolBaseArgsTemplate,
+				args.pageDownloaderPoolSize,
+				args.analyzerPoolSize)
+	}
+	return args.description
+}
+
+// 获得网页下载器池的尺寸。
+func (args *PoolBaseArgs) PageDownloaderPoolSize() uint32 {
+	return args.pageDownloaderPoolSize
+}
+
+// 获得分析器池的尺寸。
+func (args *PoolBaseArgs) AnalyzerPoolSize() uint32 {
+	return args.analyzerPoolSize
+}
+
+// 自动伸缩参数容器的描述模板。
+var autoScaleArgsTemplate string = "{ minPoolSize: %d, maxPoolSize: %d," +
+	" step: %d, interval: %s, queueHighWatermark: %d," +
+	" queueLowWatermark: %d, latencyThreshold: %s }"
+
+// 网页下载器池的自动伸缩参数的容器。
+// 每经过一个检查间隔，调度器都会根据待下载的请求的数量以及平均下载耗时调整池的尺寸：
+// 当请求数量高于高水位且平均耗时未超过阈值时扩容；
+// 当请求数量低于低水位或平均耗时超过阈值时缩容。
+type AutoScaleArgs struct {
+	minPoolSize        uint32        // 池的最小尺寸。
+	maxPoolSize        uint32        // 池的最大尺寸。
+	step               uint32        // 每次调整的幅度。
+	interval           time.Duration // 检查间隔。
+	queueHighWatermark uint32        // 待下载的请求的数量的高水位。
+	queueLowWatermark  uint32        // 待下载的请求的数量的低水位。
+	latencyThreshold   time.Duration // 平均下载耗时的阈值。
+	description        string        // 描述。
+}
+
+// 创建自动伸缩参数的容器。
+func NewAutoScaleArgs(
+	minPoolSize uint32,
+	maxPoolSize uint32,
+	step uint32,
+	interval time.Duration,
+	queueHighWatermark uint32,
+	queueLowWatermark uint32,
+	latencyThreshold time.Duration) AutoScaleArgs {
+	return AutoScaleArgs{
+		minPoolSize:        minPoolSize,
+		maxPoolSize:        maxPoolSize,
+		step:               step,
+		interval:           interval,
+		queueHighWatermark: queueHighWatermark,
+		queueLowWatermark:  queueLowWatermark,
+		latencyThreshold:   latencyThreshold,
+	}
+}
+
+func (args *AutoScaleArgs) Check() error {
+	if args.minPoolSize == 0 {
+		return errors.New("The min pool size can not be 0!\n")
+	}
+	if args.maxPoolSize < args.minPoolSize {
+		return errors.New("The max pool size can not be less than the min pool size!\n")
+	}
+	if args.step == 0 {
+		return errors.New("The scaling step can not be 0!\n")
+	}
+	if args.interval <= 0 {
+		return errors.New("The checking interval must be positive!\n")
+	}
+	if args.queueHighWatermark <= args.queueLowWatermark {
+		return errors.New("The queue high watermark must be greater than the low watermark!\n")
+	}
+	if args.latencyThreshold <= 0 {
+		return errors.New("The latency threshold must be positive!\n")
+	}
+	return nil
+}
+
+func (args *AutoScaleArgs) String() string {
+	if args.description == "" {
+		args.description =
+			fmt.Sprintf(autoScaleArgsTemplate,
+				args.minPoolSize,
+				args.maxPoolSize,
+				args.step,
+				args.interval,
+				args.queueHighWatermark,
+				args.queueLowWatermark,
+				args.latencyThreshold)
+	}
+	return args.description
+}
+
+// 获得池的最小尺寸。
+func (args *AutoScaleArgs) MinPoolSize() uint32 {
+	return args.minPoolSize
+}
+
+// 获得池的最大尺寸。
+func (args *AutoScaleArgs) MaxPoolSize() uint32 {
+	return args.maxPoolSize
+}
+
+// 获得每次调整的幅度。
+func (args *AutoScaleArgs) Step() uint32 {
+	return args.step
+}
+
+// 获得检查间隔。
+func (args *AutoScaleArgs) Interval() time.Duration {
+	return args.interval
+}
+
+// 获得待下载的请求的数量的高水位。
+func (args *AutoScaleArgs) QueueHighWatermark() uint32 {
+	return args.queueHighWatermark
+}
+
+// 获得待下载的请求的数量的低水位。
+func (args *AutoScaleArgs) QueueLowWatermark() uint32 {
+	return args.queueLowWatermark
+}
+
+// 获得平均下载耗时的阈值。
+func (args *AutoScaleArgs) LatencyThreshold() time.Duration {
+	return args.latencyThreshold
+}
+
+// Cookie罐的模式。
+type CookieJarMode uint8
+
+// Cookie罐的模式常量。
+const (
+	COOKIE_JAR_MODE_SHARED     CookieJarMode = 0 // 所有的域名共用一个Cookie罐。
+	COOKIE_JAR_MODE_PER_DOMAIN CookieJarMode = 1 // 每个域名（即请求的主机名）各自使用一个Cookie罐。
+)
+
+// 表示Cookie罐的模式与其名称之间的映射关系的字典。
+var cookieJarModeNameMap = map[CookieJarMode]string{
+	COOKIE_JAR_MODE_SHARED:     "shared",
+	COOKIE_JAR_MODE_PER_DOMAIN: "per-domain",
+}
+
+// 获得Cookie罐的模式的名称。若该模式不被支持，则第二个结果值为false。
+func CookieJarModeName(mode CookieJarMode) (string, bool) {
+	name, ok := cookieJarModeNameMap[mode]
+	return name, ok
+}
+
+// 会话参数容器的描述模板。
+var sessionArgsTemplate string = "{ jarMode: %s, cookieFile: %s," +
+	" loginUrl: %s, loginFields: [%s], successCookie: %s }"
+
+// 会话参数的容器。
+// 若登录URL不为空，则调度器会在放入首次请求之前以表单的形式向其提交登录字段。
+type SessionArgs struct {
+	jarMode       CookieJarMode     // Cookie罐的模式。
+	cookieFile    string            // Cookie的持久化文件的路径。为空时不会进行持久化。
+	loginUrl      string            // 登录URL。为空时不会登录。
+	loginFields   map[string]string // 登录字段，如用户名和密码。
+	successCookie string            // 登录成功后应存在的Cookie的名称。为空时不会检查。
+	description   string            // 描述。
+}
+
+// 创建会话参数的容器。
+func NewSessionArgs(
+	jarMode CookieJarMode,
+	cookieFile string,
+	loginUrl string,
+	loginFields map[string]string,
+	successCookie string) SessionArgs {
+	fields := make(map[string]string, len(loginFields))
+	for k, v := range loginFields {
+		fields[k] = v
+	}
+	return SessionArgs{
+		jarMode:       jarMode,
+		cookieFile:    cookieFile,
+		loginUrl:      loginUrl,
+		loginFields:   fields,
+		successCookie: successCookie,
+	}
+}
+
+func (args *SessionArgs) Check() error {
+	if _, ok := CookieJarModeName(args.jarMode); !ok {
+		return errors.New(fmt.Sprintf("Unsupported cookie jar mode %d!\n", args.jarMode))
+	}
+	if args.loginUrl == "" {
+		if len(args.loginFields) > 0 || args.successCookie != "" {
+			return errors.New("The login url can not be empty when login fields are given!\n")
+		}
+		return nil
+	}
+	loginUrl, err := url.Parse(args.loginUrl)
+	if err != nil {
+		return errors.New(fmt.Sprintf("The login url is invalid: %s\n", err))
+	}
+	scheme := strings.ToLower(loginUrl.Scheme)
+	if (scheme != "http" && scheme != "https") || loginUrl.Host == "" {
+		return errors.New(fmt.Sprintf("The login url '%s' is not an absolute HTTP url!\n", args.loginUrl))
+	}
+	return nil
+}
+
+func (args *SessionArgs) String() string {
+	if args.description == "" {
+		modeName, _ := CookieJarModeName(args.jarMode)
+		// 登录字段的值可能包含密码，所以只显示字段名。
+		names := make([]string, 0, len(args.loginFields))
+		for name := range args.loginFields {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		args.description =
+			fmt.Sprintf(sessionArgsTemplate,
+				modeName,
+				args.cookieFile,
+				args.loginUrl,
+				strings.Join(names, ", "),
+				args.successCookie)
+	}
+	return args.description
+}
+
+// 获得Cookie罐的模式。
+func (args *SessionArgs) JarMode() CookieJarMode {
+	return args.jarMode
+}
+
+// 获得Cookie的持久化文件的路径。
+func (args *SessionArgs) CookieFile() string {
+	return args.cookieFile
+}
+
+// 获得登录URL。
+func (args *SessionArgs) LoginUrl() string {
+	return args.loginUrl
+}
+
+// 获得登录字段。结果值是一个副本。
+func (args *SessionArgs) LoginFields() map[string]string {
+	fields := make(map[string]string, len(args.loginFields))
+	for k, v := range args.loginFields {
+		fields[k] = v
+	}
+	return fields
+}
+
+// 获得登录成功后应存在的Cookie的名称。
+func (args *SessionArgs) SuccessCookie() string {
+	return args.successCookie
+}
+
+// 代理选择策略。
+type ProxyPolicy uint8
+
+// 代理选择策略常量。
+const (
+	PROXY_POLICY_ROUND_ROBIN     ProxyPolicy = 0 // 依次轮流使用各个代理。
+	PROXY_POLICY_STICKY_PER_HOST ProxyPolicy = 1 // 同一个主机总使用同一个代理。
+)
+
+// 表示代理选择策略与其名称之间的映射关系的字典。
+var proxyPolicyNameMap = map[ProxyPolicy]string{
+	PROXY_POLICY_ROUND_ROBIN:     "round-robin",
+	PROXY_POLICY_STICKY_PER_HOST: "sticky-per-host",
+}
+
+// 获得代理选择策略的名称。若该策略不被支持，则第二个结果值为false。
+func ProxyPolicyName(policy ProxyPolicy) (string, bool) {
+	name, ok := proxyPolicyNameMap[policy]
+	return name, ok
+}
+
+// 请求装饰参数容器的描述模板。
+var decoratorArgsTemplate string = "{ defaultHeaders: %d, userAgents: %d," +
+	" domainHeaders: [%s], proxies: %d, proxyPolicy: %s }"
+
+// 请求装饰参数的容器。在下载之前，每个请求都会按照这些参数被装饰。
+type DecoratorArgs struct {
+	defaultHeaders map[string]string            // 默认的请求头。只会被添加到还没有相应请求头的请求。
+	userAgents     []string                     // 轮流使用的User-Agent的列表。
+	domainHeaders  map[string]map[string]string // 针对域名的请求头。它们也适用于子域名，且会覆盖其他的请求头。
+	proxies        []string                     // 代理URL的列表。
+	proxyPolicy    ProxyPolicy                  // 代理选择策略。
+	description    string                       // 描述。
+}
+
+// 创建请求装饰参数的容器。
+func NewDecoratorArgs(
+	defaultHeaders map[string]string,
+	userAgents []string,
+	domainHeaders map[string]map[string]string,
+	proxies []string,
+	proxyPolicy ProxyPolicy) DecoratorArgs {
+	args := DecoratorArgs{
+		defaultHeaders: copyHeaders(defaultHeaders),
+		userAgents:     append([]string(nil), userAgents...),
+		domainHeaders:  make(map[string]map[string]string, len(domainHeaders)),
+		proxies:        append([]string(nil), proxies...),
+		proxyPolicy:    proxyPolicy,
+	}
+	for domain, headers := range domainHeaders {
+		args.domainHeaders[strings.ToLower(domain)] = copyHeaders(headers)
+	}
+	return args
+}
+
+// 复制请求头的字典。
+func copyHeaders(headers map[string]string) map[string]string {
+	result := make(map[string]string, len(headers))
+	for k, v := range headers {
+		result[k] = v
+	}
+	return result
+}
+
+func (args *DecoratorArgs) Check() error {
+	for name := range args.defaultHeaders {
+		if name == "" {
+			return errors.New("The default header name can not be empty!\n")
+		}
+	}
+	for i, userAgent := range args.userAgents {
+		if userAgent == "" {
+			return errors.New(fmt.Sprintf("The %dth user agent is empty!\n", i))
+		}
+	}
+	for domain, headers := range args.domainHeaders {
+		if domain == "" {
+			return errors.New("The domain of domain headers can not be empty!\n")
+		}
+		for name := range headers {
+			if name == "" {
+				return errors.New(fmt.Sprintf("The header name for domain '%s' can not be empty!\n", domain))
+			}
+		}
+	}
+	for _, proxy := range args.proxies {
+		proxyUrl, err := url.Parse(proxy)
+		if err != nil {
+			return errors.New(fmt.Sprintf("The proxy url '%s' is invalid: %s\n", proxy, err))
+		}
+		switch strings.ToLower(proxyUrl.Scheme) {
+		case "http", "https", "socks5":
+		default:
+			return errors.New(fmt.Sprintf("Unsupported proxy scheme '%s'! (proxy=%s)\n", proxyUrl.Scheme, proxy))
+		}
+		if proxyUrl.Host == "" {
+			return errors.New(fmt.Sprintf("The host of proxy url '%s' is empty!\n", proxy))
+		}
+	}
+	if _, ok := ProxyPolicyName(args.proxyPolicy); !ok {
+		return errors.New(fmt.Sprintf("Unsupported proxy policy %d!\n", args.proxyPolicy))
+	}
+	return nil
+}
+
+func (args *DecoratorArgs) String() string {
+	if args.description == "" {
+		domains := make([]string, 0, len(args.domainHeaders))
+		for domain := range args.domainHeaders {
+			domains = append(domains, domain)
+		}
+		sort.Strings(domains)
+		policyName, _ := ProxyPolicyName(args.proxyPolicy)
+		args.description =
+			fmt.Sprintf(decoratorArgsTemplate,
+				len(args.defaultHeaders),
+				len(args.userAgents),
+				strings.Join(domains, ", "),
+				len(args.proxies),
+				policyName)
+	}
+	return args.description
+}
+
+// 获得默认的请求头。结果值是一个副本。
+func (args *DecoratorArgs) DefaultHeaders() map[string]string {
+	return copyHeaders(args.defaultHeaders)
+}
+
+// 获得User-Agent的列表。结果值是一个副本。
+func (args *DecoratorArgs) UserAgents() []string {
+	return append([]string(nil), args.userAgents...)
+}
+
+// 获得针对域名的请求头。结果值是一个副本。
+func (args *DecoratorArgs) DomainHeaders() map[string]map[string]string {
+	result := make(map[string]map[string]string, len(args.domainHeaders))
+	for domain, headers := range args.domainHeaders {
+		result[domain] = copyHeaders(headers)
+	}
+	return result
+}
+
+// 获得代理URL的列表。结果值是一个副本。
+func (args *DecoratorArgs) Proxies() []string {
+	return append([]string(nil), args.proxies...)
+}
+
+// 获得代理选择策略。
+func (args *DecoratorArgs) ProxyPolicy() ProxyPolicy {
+	return args.proxyPolicy
+}
+
+// 域名配额。其中的各个值为0时表示不限制。
+type DomainQuota struct {
+	MaxPages uint64 // 最大网页数。
+	MaxBytes uint64 // 最大下载字节数。
+}
+
+// 判断域名配额是否有所限制。
+func (quota DomainQuota) Limited() bool {
+	return quota.MaxPages > 0 || quota.MaxBytes > 0
+}
+
+func (quota DomainQuota) String() string {
+	return fmt.Sprintf("{ maxPages: %d, maxBytes: %d }", quota.MaxPages, quota.MaxBytes)
+}
+
+// 爬取预算参数容器的描述模板。
+var budgetArgsTemplate string = "{ maxPages: %d, maxBytes: %d, maxDuration: %s," +
+	" maxItems: %d, domainQuota: %s, domainQuotas: [%s] }"
+
+// 爬取预算参数的容器。其中的各个值为0时表示不限制。
+// 网页数按被放入请求缓存的请求计数，字节数按下载到的响应体的长度计数。
+// 默认的域名配额适用于每一个主机，而针对特定域名的配额则由该域名及其子域名共用，并会覆盖默认的域名配额。
+type BudgetArgs struct {
+	maxPages     uint64                 // 全局的最大网页数。
+	maxBytes     uint64                 // 全局的最大下载字节数。
+	maxDuration  time.Duration          // 最长爬取时间。
+	maxItems     uint64                 // 全局的最大条目数。
+	domainQuota  DomainQuota            // 默认的域名配额。
+	domainQuotas map[string]DomainQuota // 针对特定域名的配额。
+	description  string                 // 描述。
+}
+
+// 创建爬取预算参数的容器。
+func NewBudgetArgs(
+	maxPages uint64,
+	maxBytes uint64,
+	maxDuration time.Duration,
+	maxItems uint64,
+	domainQuota DomainQuota,
+	domainQuotas map[string]DomainQuota) BudgetArgs {
+	args := BudgetArgs{
+		maxPages:     maxPages,
+		maxBytes:     maxBytes,
+		maxDuration:  maxDuration,
+		maxItems:     maxItems,
+		domainQuota:  domainQuota,
+		domainQuotas: make(map[string]DomainQuota, len(domainQuotas)),
+	}
+	for domain, quota := range domainQuotas {
+		args.domainQuotas[strings.ToLower(domain)] = quota
+	}
+	return args
+}
+
+func (args *BudgetArgs) Check() error {
+	if args.maxDuration < 0 {
+		return errors.New("The max crawl duration can not be negative!\n")
+	}
+	limited := args.maxPages > 0 || args.maxBytes > 0 ||
+		args.maxDuration > 0 || args.maxItems > 0 || args.domainQuota.Limited()
+	for domain, quota := range args.domainQuotas {
+		if domain == "" {
+			return errors.New("The domain of domain quota can not be empty!\n")
+		}
+		if quota.Limited() {
+			limited = true
+		}
+	}
+	if !limited {
+		return errors.New("The budget args limit nothing!\n")
+	}
+	return nil
+}
+
+func (args *BudgetArgs) String() string {
+	if args.description == "" {
+		domains := make([]string, 0, len(args.domainQuotas))
+		for domain, quota := range args.domainQuotas {
+			domains = append(domains, fmt.Sprintf("%s: %s", domain, quota))
+		}
+		sort.Strings(domains)
+		args.description =
+			fmt.Sprintf(budgetArgsTemplate,
+				args.maxPages,
+				args.maxBytes,
+				args.maxDuration,
+				args.maxItems,
+				args.domainQuota,
+				strings.Join(domains, ", "))
+	}
+	return args.description
+}
+
+// 获得全局的最大网页数。
+func (args *BudgetArgs) MaxPages() uint64 {
+	return args.maxPages
+}
+
+// 获得全局的最大下载字节数。
+func (args *BudgetArgs) MaxBytes() uint64 {
+	return args.maxBytes
+}
+
+// 获得最长爬取时间。
+func (args *BudgetArgs) MaxDuration() time.Duration {
+	return args.maxDuration
+}
+
+// 获得全局的最大条目数。
+func (args *BudgetArgs) MaxItems() uint64 {
+	return args.maxItems
+}
+
+// 获得默认的域名配额。
+func (args *BudgetArgs) DomainQuota() DomainQuota {
+	return args.domainQuota
+}
+
+// 获得针对特定域名的配额。结果值是一个副本。
+func (args *BudgetArgs) DomainQuotas() map[string]DomainQuota {
+	result := make(map[string]DomainQuota, len(args.domainQuotas))
+	for domain, quota := range args.domainQuotas {
+		result[domain] = quota
+	}
+	return result
+}
@@ -1,70 +1,211 @@
-package base
-
-import (
-	"net/http"
-)
-
-// 数据的接口。
-type Data interface {
-	Valid() bool // 数据是否有效。
-}
-
-// 请求。
-type Request struct {
-	httpReq *http.Request // HTTP请求的指针值。
-	depth   uint32        // 请求的深度。
-}
-
-// 创建新的请求。
-func NewRequest(httpReq *http.Request, depth uint32) *Request {
-	return &Request{httpReq: httpReq, depth: depth}
-}
-
-// 获取HTTP请求。
-func (req *Request) HttpReq() *http.Request {
-	return req.httpReq
-}
-
-// 获取深度值。
-func (req *Request) Depth() uint32 {
-	return req.depth
-}
-
-// 数据是否有效。
-func (req *Request) Valid() bool {
-	return req.httpReq != nil && req.httpReq.URL != nil
-}
-
-// 响应。
-type Response struct {
-	httpResp *http.Response
-	depth    uint32
-}
-
-// 创建新的响应。
-func NewResponse(httpResp *http.Response, depth uint32) *Response {
-	return &Response{httpResp: httpResp, depth: depth}
-}
-
-// 获取HTTP响应。
-func (resp *Response) HttpResp() *http.Response {
-	return resp.httpResp
-}
-
-// 获取深度值。
-func (resp *Response) Depth() uint32 {
-	return resp.depth
-}
-
-// 数据是否有效。
-func (resp *Response) Valid() bool {
-	return resp.httpResp != nil && resp.httpResp.Body != nil
-}
-
-// 条目。
-type Item map[string]interface{}
-
-// 数据是否有效。
-func (item Item) Valid() bool {
-	return item != nil
-}
+package base
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"net/http"
+)
+
+// 数据的接口。
+type Data interface {
+	Valid() bool // 数据是否有效。
+}
+
+// 元数据的保留键。
+const (
+	META_KEY_SEED_URL    = "seed_url"    // 最初的请求（即种子）的URL。
+	META_KEY_PARENT_URL  = "parent_url"  // 父网页（即包含了当前请求的链接的网页）的URL。
+	META_KEY_ANCHOR_TEXT = "anchor_text" // 父网页中指向当前请求的链接的锚文本。它不会被子请求继承。
+)
+
+// 元数据。它会由请求传递给响应，再由响应传递给从中解析出的子请求。
+type Meta map[string]interface{}
+
+// 获得元数据的副本。
+func (meta Meta) Copy() Meta {
+	result := make(Meta, len(meta))
+	for k, v := range meta {
+		result[k] = v
+	}
+	return result
+}
+
+// 请求。
+type Request struct {
+	httpReq *http.Request // HTTP请求的指针值。
+	depth   uint32        // 请求的深度。
+	meta    Meta          // 元数据。
+	body    []byte        // 请求体。它使得请求可以被重复发送。
+}
+
+// 创建新的请求。若HTTP请求带有请求体，则请求体会被完整读取并保存，以便重复发送。
+func NewRequest(httpReq *http.Request, depth uint32) *Request {
+	return NewRequestWithMeta(httpReq, depth, nil)
+}
+
+// 创建新的带有元数据的请求。若HTTP请求带有请求体，则请求体会被完整读取并保存，以便重复发送。
+func NewRequestWithMeta(httpReq *http.Request, depth uint32, meta Meta) *Request {
+	if meta == nil {
+		meta = make(Meta)
+	}
+	req := &Request{httpReq: httpReq, depth: depth, meta: meta}
+	if httpReq != nil {
+		req.setBody(readRequestBody(httpReq))
+	}
+	return req
+}
+
+// 创建新的带有请求体的请求。HTTP请求原有的请求体会被替换。
+func NewRequestWithBody(httpReq *http.Request, depth uint32, body []byte, meta Meta) *Request {
+	if meta == nil {
+		meta = make(Meta)
+	}
+	req := &Request{httpReq: httpReq, depth: depth, meta: meta}
+	if httpReq != nil {
+		req.setBody(body)
+	}
+	return req
+}
+
+// 读取HTTP请求的请求体。读取之后，HTTP请求的请求体仍然可用。
+func readRequestBody(httpReq *http.Request) []byte {
+	var reader io.ReadCloser
+	if httpReq.GetBody != nil {
+		reader, _ = httpReq.GetBody()
+	} else if httpReq.Body != nil && httpReq.Body != http.NoBody {
+		reader = httpReq.Body
+	}
+	if reader == nil {
+		return nil
+	}
+	body, _ := ioutil.ReadAll(reader)
+	reader.Close()
+	return body
+}
+
+// 设置请求体，并使HTTP请求的请求体可以被重复读取。
+func (req *Request) setBody(body []byte) {
+	req.body = body
+	if body == nil {
+		return
+	}
+	req.httpReq.ContentLength = int64(len(body))
+	req.httpReq.GetBody = func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewReader(body)), nil
+	}
+	req.httpReq.Body, _ = req.httpReq.GetBody()
+}
+
+// 获取请求体。若请求没有请求体，则结果值为nil。
+func (req *Request) Body() []byte {
+	return req.body
+}
+
+// 重置HTTP请求的请求体，使其可以被再次发送。
+func (req *Request) ResetBody() {
+	if req.httpReq != nil && req.body != nil {
+		req.httpReq.Body, _ = req.httpReq.GetBody()
+	}
+}
+
+// 获取去重键。它由HTTP方法、URL以及请求体的散列值组成。
+func (req *Request) DedupeKey() string {
+	if req.httpReq == nil || req.httpReq.URL == nil {
+		return ""
+	}
+	return DedupeKey(req.httpReq.Method, req.httpReq.URL.String(), req.body)
+}
+
+// 根据HTTP方法、URL以及请求体生成去重键。
+// 对于没有请求体的GET请求，去重键就是URL本身。
+func DedupeKey(method string, url string, body []byte) string {
+	if method == "" {
+		method = "GET"
+	}
+	if method == "GET" && len(body) == 0 {
+		return url
+	}
+	key := method + " " + url
+	if len(body) > 0 {
+		sum := sha1.Sum(body)
+		key += " " + hex.EncodeToString(sum[:])
+	}
+	return key
+}
+
+// 获取HTTP请求。
+func (req *Request) HttpReq() *http.Request {
+	return req.httpReq
+}
+
+// 获取深度值。
+func (req *Request) Depth() uint32 {
+	return req.depth
+}
+
+// 获取元数据。
+func (req *Request) Meta() Meta {
+	if req.meta == nil {
+		req.meta = make(Meta)
+	}
+	return req.meta
+}
+
+// 数据是否有效。
+func (req *Request) Valid() bool {
+	return req.httpReq != nil && req.httpReq.URL != nil
+}
+
+// 响应。
+type Response struct {
+	httpResp *http.Response
+	depth    uint32
+	meta     Meta
+}
+
+// 创建新的响应。
+func NewResponse(httpResp *http.Response, depth uint32) *Response {
+	return &Response{httpResp: httpResp, depth: depth, meta: make(Meta)}
+}
+
+// 创建新的带有元数据的响应。
+func NewResponseWithMeta(httpResp *http.Response, depth uint32, meta Meta) *Response {
+	if meta == nil {
+		meta = make(Meta)
+	}
+	return &Response{httpResp: httpResp, depth: depth, meta: meta}
+}
+
+// 获取HTTP响应。
+func (resp *Response) HttpResp() *http.Response {
+	return resp.httpResp
+}
+
+// 获取深度值。
+func (resp *Response) Depth() uint32 {
+	return resp.depth
+}
+
+// 获取元数据。
+func (resp *Response) Meta() Meta {
+	if resp.meta == nil {
+		resp.meta = make(Meta)
+	}
+	return resp.meta
+}
+
+// 数据是否有效。
+func (resp *Response) Valid() bool {
+	return resp.httpResp != nil && resp.httpResp.Body != nil
+}
+
+// 条目。
+type Item map[string]interface{}
+
+// 数据是否有效。
+func (item Item) Valid() bool {
+	return item != nil
+}
@@ -0,0 +1,40 @@
+package base
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestRequestBody(t *testing.T) {
+	newPost := func(body string) *Request {
+		httpReq, _ := http.NewRequest("POST", "http://example.com/search", strings.NewReader(body))
+		return NewRequest(httpReq, 0)
+	}
+	req1, req2 := newPost("q=go"), newPost("q=rust")
+	if req1.DedupeKey() == req2.DedupeKey() {
+		t.Fatalf("POST requests with different bodies should have different dedupe keys!")
+	}
+	if req1.DedupeKey() != newPost("q=go").DedupeKey() {
+		t.Fatalf("POST requests with the same body should have the same dedupe key!")
+	}
+	getReq, _ := http.NewRequest("GET", "http://example.com/search", nil)
+	if key := NewRequest(getReq, 0).DedupeKey(); key != "http://example.com/search" {
+		t.Fatalf("Unexpected dedupe key %q for GET request.", key)
+	}
+
+	// 请求体应能被重复读取。
+	for i := 0; i < 2; i++ {
+		req1.ResetBody()
+		body, _ := ioutil.ReadAll(req1.HttpReq().Body)
+		if string(body) != "q=go" {
+			t.Fatalf("Unexpected body %q at the %dth reading.", body, i)
+		}
+	}
+	// 派生的请求应保留请求体。
+	derived := NewRequestWithMeta(req1.HttpReq(), 1, nil)
+	if string(derived.Body()) != "q=go" || derived.DedupeKey() != req1.DedupeKey() {
+		t.Fatalf("The body is lost in the derived request!")
+	}
+}
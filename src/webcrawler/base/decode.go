@@ -0,0 +1,174 @@
+package base
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+)
+
+// 条目解码时使用的结构体标签的键。
+// 例如，带有标签`item:"a.text"`的字段会从条目中键为"a.text"的元素获得值。
+// 标签值为"-"的字段会被忽略。未带有标签的字段会以其名称作为键。
+const ITEM_TAG_KEY = "item"
+
+// 把条目解码到参数v所指向的结构体中。
+// 只有可导出的字段才会被赋值。条目中的元素值需要能被赋值或转换为相应字段的类型。
+// 数值之间的转换不能丢失信息，例如带有小数部分的浮点数不能被赋给整数字段，超出字段取值范围的数值也不行。
+func (item Item) Decode(v interface{}) error {
+	if item == nil {
+		return errors.New("The item is invalid!")
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return errors.New(fmt.Sprintf("The decoding target must be a non-nil struct pointer! (type=%T)", v))
+	}
+	rv = rv.Elem()
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		key := field.Name
+		if tag := field.Tag.Get(ITEM_TAG_KEY); tag != "" {
+			if tag == "-" {
+				continue
+			}
+			key = strings.TrimSpace(tag)
+		}
+		value, ok := item[key]
+		if !ok || value == nil {
+			continue
+		}
+		if err := assignValue(rv.Field(i), reflect.ValueOf(value)); err != nil {
+			return errors.New(fmt.Sprintf("Can not decode the item element '%s' to field '%s': %s",
+				key, field.Name, err))
+		}
+	}
+	return nil
+}
+
+// 把值赋给目标字段。必要时会进行类型转换。
+func assignValue(target reflect.Value, value reflect.Value) error {
+	if value.Type().AssignableTo(target.Type()) {
+		target.Set(value)
+		return nil
+	}
+	if target.Kind() == reflect.Ptr && value.Type().AssignableTo(target.Type().Elem()) {
+		ptr := reflect.New(target.Type().Elem())
+		ptr.Elem().Set(value)
+		target.Set(ptr)
+		return nil
+	}
+	if isNumberKind(value.Kind()) && isNumberKind(target.Kind()) {
+		return assignNumber(target, value)
+	}
+	if value.Kind() == reflect.Slice && target.Kind() == reflect.Slice {
+		slice := reflect.MakeSlice(target.Type(), value.Len(), value.Len())
+		for i := 0; i < value.Len(); i++ {
+			elem := value.Index(i)
+			if elem.Kind() == reflect.Interface {
+				elem = elem.Elem()
+			}
+			if !elem.IsValid() {
+				continue
+			}
+			if err := assignValue(slice.Index(i), elem); err != nil {
+				return err
+			}
+		}
+		target.Set(slice)
+		return nil
+	}
+	return errors.New(fmt.Sprintf("the type %s is NOT compatible with %s", value.Type(), target.Type()))
+}
+
+// 把数值赋给数值类型的目标字段。不能无损转换的数值会导致错误。
+func assignNumber(target reflect.Value, value reflect.Value) error {
+	var f float64
+	switch {
+	case isIntKind(value.Kind()):
+		f = float64(value.Int())
+	case isUintKind(value.Kind()):
+		f = float64(value.Uint())
+	default:
+		f = value.Float()
+	}
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		if !isFloatKind(target.Kind()) {
+			return errors.New(fmt.Sprintf("the value %v is NOT a finite number", f))
+		}
+		target.Set(value.Convert(target.Type()))
+		return nil
+	}
+	if !isFloatKind(target.Kind()) && isFloatKind(value.Kind()) && f != math.Trunc(f) {
+		return errors.New(fmt.Sprintf("the value %v is NOT an integer", f))
+	}
+	var overflow bool
+	switch {
+	case isIntKind(target.Kind()):
+		switch {
+		case isIntKind(value.Kind()):
+			overflow = target.OverflowInt(value.Int())
+		case isUintKind(value.Kind()):
+			overflow = value.Uint() > math.MaxInt64 || target.OverflowInt(int64(value.Uint()))
+		default:
+			overflow = f < math.MinInt64 || f >= math.MaxInt64 || target.OverflowInt(int64(f))
+		}
+	case isUintKind(target.Kind()):
+		switch {
+		case isIntKind(value.Kind()):
+			overflow = value.Int() < 0 || target.OverflowUint(uint64(value.Int()))
+		case isUintKind(value.Kind()):
+			overflow = target.OverflowUint(value.Uint())
+		default:
+			overflow = f < 0 || f >= math.MaxUint64 || target.OverflowUint(uint64(f))
+		}
+	default:
+		overflow = target.OverflowFloat(f)
+	}
+	if overflow {
+		return errors.New(fmt.Sprintf("the value %v is out of the range of %s", value.Interface(), target.Type()))
+	}
+	converted := value.Convert(target.Type())
+	if isFloatKind(target.Kind()) && !sameNumber(converted.Float(), value) {
+		return errors.New(fmt.Sprintf("the value %v can NOT be represented exactly by %s",
+			value.Interface(), target.Type()))
+	}
+	target.Set(converted)
+	return nil
+}
+
+// 判断浮点数是否与给定的数值完全相等。
+func sameNumber(f float64, value reflect.Value) bool {
+	switch {
+	case isIntKind(value.Kind()):
+		// 2的63次方无法被int64表示，需提前排除。
+		return f >= math.MinInt64 && f < math.MaxInt64 && int64(f) == value.Int()
+	case isUintKind(value.Kind()):
+		return f >= 0 && f < math.MaxUint64 && uint64(f) == value.Uint()
+	}
+	return f == value.Float()
+}
+
+// 判断是否为有符号整数种类。
+func isIntKind(k reflect.Kind) bool {
+	return k >= reflect.Int && k <= reflect.Int64
+}
+
+// 判断是否为无符号整数种类。
+func isUintKind(k reflect.Kind) bool {
+	return k >= reflect.Uint && k <= reflect.Uintptr
+}
+
+// 判断是否为浮点数种类。
+func isFloatKind(k reflect.Kind) bool {
+	return k == reflect.Float32 || k == reflect.Float64
+}
+
+// 判断是否为数值种类。
+func isNumberKind(k reflect.Kind) bool {
+	return k >= reflect.Int && k <= reflect.Float64
+}
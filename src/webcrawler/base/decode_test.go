@@ -0,0 +1,78 @@
+package base
+
+import (
+	"math"
+	"testing"
+)
+
+type page struct {
+	Title   string   `item:"a.text"`
+	Index   int64    `item:"a.index"`
+	Score   *float64 `item:"score"`
+	Tags    []string
+	Ignored string `item:"-"`
+	hidden  string
+}
+
+func TestItemDecode(t *testing.T) {
+	item := Item{
+		"a.text":  "Go",
+		"a.index": 3,
+		"score":   9.5,
+		"Tags":    []interface{}{"lang", "concurrency"},
+		"Ignored": "x",
+		"hidden":  "y",
+	}
+	var p page
+	if err := item.Decode(&p); err != nil {
+		t.Fatalf("Decode error: %s\n", err)
+	}
+	if p.Title != "Go" || p.Index != 3 || p.Score == nil || *p.Score != 9.5 {
+		t.Errorf("Unexpected result %+v!\n", p)
+	}
+	if len(p.Tags) != 2 || p.Tags[1] != "concurrency" {
+		t.Errorf("Unexpected tags %v!\n", p.Tags)
+	}
+	if p.Ignored != "" || p.hidden != "" {
+		t.Errorf("The ignored fields should not be set! (%+v)\n", p)
+	}
+	if err := (Item{"a.text": 1}).Decode(&p); err == nil {
+		t.Errorf("The incompatible value should be reported!\n")
+	}
+	if err := item.Decode(p); err == nil {
+		t.Errorf("The non-pointer target should be rejected!\n")
+	}
+}
+
+func TestItemDecodeNumber(t *testing.T) {
+	var n struct {
+		Int    int
+		Int8   int8
+		Uint   uint16
+		Float  float32
+		Double float64
+	}
+	// 经过JSON等方式传输后，整数会变为float64类型。
+	if err := (Item{"Int": 42.0, "Int8": -128, "Uint": 65535.0, "Float": 1, "Double": int64(1 << 53)}).Decode(&n); err != nil {
+		t.Fatalf("Decode error: %s\n", err)
+	}
+	if n.Int != 42 || n.Int8 != -128 || n.Uint != 65535 || n.Float != 1 || n.Double != 1<<53 {
+		t.Errorf("Unexpected result %+v!\n", n)
+	}
+	for _, item := range []Item{
+		{"Int": 3.7},
+		{"Int": math.NaN()},
+		{"Int8": 128},
+		{"Int8": 200.0},
+		{"Uint": -1},
+		{"Uint": 65536.0},
+		{"Float": 1e300},
+		{"Float": 0.1},
+		{"Float": uint64(1<<63 + 1)},
+		{"Double": int64(1<<53 + 1)},
+	} {
+		if err := item.Decode(&n); err == nil {
+			t.Errorf("The lossy conversion of %v should be rejected!\n", item)
+		}
+	}
+}
@@ -1,60 +1,142 @@
-package base
-
-import (
-	"bytes"
-	"fmt"
-)
-
-// 错误类型。
-type ErrorType string
-
-// 错误类型常量。
-const (
-	DOWNLOADER_ERROR     ErrorType = "Downloader Error"
-	ANALYZER_ERROR       ErrorType = "Analyzer Error"
-	ITEM_PROCESSOR_ERROR ErrorType = "Item Processor Error"
-)
-
-// 爬虫错误的接口。
-type CrawlerError interface {
-	Type() ErrorType // 获得错误类型。
-	Error() string   // 获得错误提示信息。
-}
-
-// 爬虫错误的实现。
-type myCrawlerError struct {
-	errType    ErrorType // 错误类型。
-	errMsg     string    // 错误提示信息。
-	fullErrMsg string    // 完整的错误提示信息。
-}
-
-// 创建一个新的爬虫错误。
-func NewCrawlerError(errType ErrorType, errMsg string) CrawlerError {
-	return &myCrawlerError{errType: errType, errMsg: errMsg}
-}
-
-// 获得错误类型。
-func (ce *myCrawlerError) Type() ErrorType {
-	return ce.errType
-}
-
-// 获得错误提示信息。
-func (ce *myCrawlerError) Error() string {
-	if ce.fullErrMsg == "" {
-		ce.genFullErrMsg()
-	}
-	return ce.fullErrMsg
-}
-
-// 生成错误提示信息，并给相应的字段赋值。
-func (ce *myCrawlerError) genFullErrMsg() {
-	var buffer bytes.Buffer
-	buffer.WriteString("Crawler Error: ")
-	if ce.errType != "" {
-		buffer.WriteString(string(ce.errType))
-		buffer.WriteString(": ")
-	}
-	buffer.WriteString(ce.errMsg)
-	ce.fullErrMsg = fmt.Sprintf("%s\n", buffer.String())
-	return
-}
+package base
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// 错误类型。
+type ErrorType string
+
+// 错误类型常量。
+const (
+	DOWNLOADER_ERROR     ErrorType = "Downloader Error"
+	ANALYZER_ERROR       ErrorType = "Analyzer Error"
+	ITEM_PROCESSOR_ERROR ErrorType = "Item Processor Error"
+	SCHEDULER_ERROR      ErrorType = "Scheduler Error"
+	FILTER_ERROR         ErrorType = "Filter Error"
+	TIMEOUT_ERROR        ErrorType = "Timeout Error"
+	NETWORK_ERROR        ErrorType = "Network Error"
+	HTTP_STATUS_ERROR    ErrorType = "HTTP Status Error"
+)
+
+// 爬虫错误的接口。
+type CrawlerError interface {
+	Type() ErrorType     // 获得错误类型。
+	Error() string       // 获得错误提示信息。
+	Url() string         // 获得与错误相关的请求的URL。可能为空。
+	Depth() uint32       // 获得与错误相关的请求的深度。
+	ComponentId() string // 获得出错的组件实例的代号。可能为空。
+	Unwrap() error       // 获得被包装的原始错误。可能为nil。
+}
+
+// 爬虫错误的实现。
+type myCrawlerError struct {
+	errType     ErrorType // 错误类型。
+	errMsg      string    // 错误提示信息。
+	fullErrMsg  string    // 完整的错误提示信息。在创建时生成，之后不会再变。
+	url         string    // 与错误相关的请求的URL。
+	depth       uint32    // 与错误相关的请求的深度。
+	componentId string    // 出错的组件实例的代号。
+	cause       error     // 被包装的原始错误。
+}
+
+// 创建一个新的爬虫错误。
+func NewCrawlerError(errType ErrorType, errMsg string) CrawlerError {
+	ce := &myCrawlerError{errType: errType, errMsg: errMsg}
+	ce.genFullErrMsg()
+	return ce
+}
+
+// 包装原始错误并创建一个新的爬虫错误。
+// 参数reqUrl和depth代表与错误相关的请求的URL和深度。
+// 参数componentId代表出错的组件实例的代号。
+func WrapCrawlerError(
+	errType ErrorType,
+	cause error,
+	reqUrl string,
+	depth uint32,
+	componentId string) CrawlerError {
+	var errMsg string
+	if cause != nil {
+		errMsg = cause.Error()
+	}
+	ce := &myCrawlerError{
+		errType:     errType,
+		errMsg:      errMsg,
+		url:         reqUrl,
+		depth:       depth,
+		componentId: componentId,
+		cause:       cause,
+	}
+	ce.genFullErrMsg()
+	return ce
+}
+
+// 获得错误类型。
+func (ce *myCrawlerError) Type() ErrorType {
+	return ce.errType
+}
+
+// 获得错误提示信息。
+func (ce *myCrawlerError) Error() string {
+	return ce.fullErrMsg
+}
+
+func (ce *myCrawlerError) Url() string {
+	return ce.url
+}
+
+func (ce *myCrawlerError) Depth() uint32 {
+	return ce.depth
+}
+
+func (ce *myCrawlerError) ComponentId() string {
+	return ce.componentId
+}
+
+func (ce *myCrawlerError) Unwrap() error {
+	return ce.cause
+}
+
+// 生成错误提示信息，并给相应的字段赋值。它只应在创建爬虫错误时被调用。
+func (ce *myCrawlerError) genFullErrMsg() {
+	var buffer bytes.Buffer
+	buffer.WriteString("Crawler Error: ")
+	if ce.errType != "" {
+		buffer.WriteString(string(ce.errType))
+		buffer.WriteString(": ")
+	}
+	buffer.WriteString(ce.errMsg)
+	if ce.url != "" || ce.componentId != "" {
+		buffer.WriteString(fmt.Sprintf(" (url=%s, depth=%d, component=%s)",
+			ce.url, ce.depth, ce.componentId))
+	}
+	ce.fullErrMsg = fmt.Sprintf("%s\n", buffer.String())
+}
+
+// HTTP状态错误。在HTTP响应的状态码表示失败时使用。
+type HttpStatusError struct {
+	statusCode int    // 状态码。
+	url        string // 请求的URL。
+}
+
+// 创建HTTP状态错误。
+func NewHttpStatusError(statusCode int, url string) *HttpStatusError {
+	return &HttpStatusError{statusCode: statusCode, url: url}
+}
+
+// 判断状态码是否表示失败。网页下载器会为这样的响应报告HTTP状态错误，
+// 所以响应解析函数只需跳过它们，不必再次报告。
+func IsFailureStatus(statusCode int) bool {
+	return statusCode >= 400
+}
+
+// 获得状态码。
+func (hse *HttpStatusError) StatusCode() int {
+	return hse.statusCode
+}
+
+func (hse *HttpStatusError) Error() string {
+	return fmt.Sprintf("Unsuccessful HTTP status code %d! (url=%s)", hse.statusCode, hse.url)
+}
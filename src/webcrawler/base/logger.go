@@ -1,8 +1,8 @@
-package base
-
-import "logging"
-
-// 创建日志记录器。
-func NewLogger() logging.Logger {
-	return logging.NewSimpleLogger()
-}
+package base
+
+import "logging"
+
+// 创建日志记录器。
+func NewLogger() logging.Logger {
+	return logging.NewSimpleLogger()
+}
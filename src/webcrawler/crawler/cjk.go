@@ -0,0 +1,7 @@
+//go:build !nocjk
+
+package main
+
+// 中文字符集的解码函数依赖于golang.org/x/text。
+// 在无法获取该依赖的环境中，可以使用“-tags nocjk”构建不支持中文字符集的版本。
+import _ "webcrawler/analyzer/cjk"
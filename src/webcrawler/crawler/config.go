@@ -0,0 +1,384 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+	base "webcrawler/base"
+	"webcrawler/linkgraph"
+)
+
+// 时长。它在配置文件中以time.ParseDuration所支持的字符串表示，如“500ms”。
+type Duration time.Duration
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return errors.New(fmt.Sprintf("The duration should be a string like \"1s\", but %s!", data))
+	}
+	duration, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(duration)
+	return nil
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// 爬取配置。它对应于一个JSON格式的配置文件。
+type Config struct {
+	Seeds       []string       `json:"seeds"`       // 种子URL的列表。第一个种子URL所属的主域名即爬取的范围。
+	Depth       uint32         `json:"depth"`       // 爬取的最大深度。
+	Scope       ScopeConfig    `json:"scope"`       // 范围配置。
+	Channel     ChannelConfig  `json:"channel"`     // 通道配置。
+	Pool        PoolConfig     `json:"pool"`        // 池配置。
+	Filters     FilterConfig   `json:"filters"`     // 过滤配置。
+	Politeness  PoliteConfig   `json:"politeness"`  // 礼貌配置。
+	Parsers     []ParserConfig `json:"parsers"`     // 响应解析函数的配置的列表。
+	Outputs     []OutputConfig `json:"outputs"`     // 输出配置的列表。
+	Budget      *BudgetConfig  `json:"budget"`      // 爬取预算的配置。为空时不限制预算。
+	Graph       *GraphConfig   `json:"graph"`       // 链接图的配置。为空时不记录链接图。
+	IdleTimeout Duration       `json:"idleTimeout"` // 调度器持续空闲多久之后停止爬取。
+	Report      string         `json:"report"`      // 摘要报告的文件路径。为空时会输出到标准输出。
+}
+
+// 范围配置。请求的URL需匹配任一包含模式（若有），且不能匹配任何排除模式。
+type ScopeConfig struct {
+	Include []string `json:"include"` // 包含模式（正则表达式）的列表。
+	Exclude []string `json:"exclude"` // 排除模式（正则表达式）的列表。
+}
+
+// 通道配置。
+type ChannelConfig struct {
+	ReqChanLen          uint   `json:"reqChanLen"`          // 请求通道的长度。
+	RespChanLen         uint   `json:"respChanLen"`         // 响应通道的长度。
+	ItemChanLen         uint   `json:"itemChanLen"`         // 条目通道的长度。
+	ErrorChanLen        uint   `json:"errorChanLen"`        // 错误通道的长度。
+	ErrorOverflowPolicy string `json:"errorOverflowPolicy"` // 错误缓冲区的溢出策略的名称。
+}
+
+// 池配置。
+type PoolConfig struct {
+	PageDownloaderPoolSize uint32 `json:"pageDownloaderPoolSize"` // 网页下载器池的尺寸。
+	AnalyzerPoolSize       uint32 `json:"analyzerPoolSize"`       // 分析器池的尺寸。
+}
+
+// 过滤配置。
+type FilterConfig struct {
+	SkipExtensions         []string `json:"skipExtensions"`         // 需要跳过的URL路径的扩展名的列表，如“.jpg”。
+	NearDuplicateThreshold *uint8   `json:"nearDuplicateThreshold"` // 网页去重的汉明距离阈值。为空时不去重。
+}
+
+// 礼貌配置。
+type PoliteConfig struct {
+	Delay      Duration          `json:"delay"`      // 针对同一主机的请求间隔。
+	Timeout    Duration          `json:"timeout"`    // 单个请求的超时时间。为0时不超时。
+	UserAgents []string          `json:"userAgents"` // 轮流使用的User-Agent的列表。
+	Headers    map[string]string `json:"headers"`    // 默认的请求头。
+}
+
+// 响应解析函数的配置。
+type ParserConfig struct {
+	Type       string             `json:"type"`       // 类型。可选值为“links”、“title”和“json”。
+	Items      string             `json:"items"`      // 条目的JSON路径。仅用于“json”类型。
+	Fields     map[string]string  `json:"fields"`     // 字段名称与JSON路径的映射。仅用于“json”类型。
+	Pagination []PaginationConfig `json:"pagination"` // 翻页方式的配置的列表。仅用于“json”类型。
+}
+
+// 翻页方式的配置。
+type PaginationConfig struct {
+	Type        string `json:"type"`        // 类型。可选值为“cursor”、“offset”、“link”和“next”。
+	Path        string `json:"path"`        // 游标或下一页URL的JSON路径。
+	Param       string `json:"param"`       // 承载游标的请求参数的名称。
+	OffsetParam string `json:"offsetParam"` // 承载偏移量的请求参数的名称。
+	LimitParam  string `json:"limitParam"`  // 承载每页条目数量的请求参数的名称。
+	PageSize    uint32 `json:"pageSize"`    // 每页条目数量。
+	TotalPath   string `json:"totalPath"`   // 条目总数的JSON路径。
+}
+
+// 爬取预算的配置。其中的各个值为0时表示不限制。
+type BudgetConfig struct {
+	MaxPages    uint64                 `json:"maxPages"`    // 全局的最大网页数。
+	MaxBytes    uint64                 `json:"maxBytes"`    // 全局的最大下载字节数。
+	MaxDuration Duration               `json:"maxDuration"` // 最长爬取时间。
+	MaxItems    uint64                 `json:"maxItems"`    // 全局的最大条目数。
+	Domain      QuotaConfig            `json:"domain"`      // 适用于每一个主机的默认的域名配额。
+	Domains     map[string]QuotaConfig `json:"domains"`     // 针对特定域名（包括其子域名）的配额。
+}
+
+// 域名配额的配置。
+type QuotaConfig struct {
+	MaxPages uint64 `json:"maxPages"` // 最大网页数。
+	MaxBytes uint64 `json:"maxBytes"` // 最大下载字节数。
+}
+
+// 链接图的配置。爬取结束后，链接图会被导出，并且排名靠前的网页会被列在摘要报告中。
+type GraphConfig struct {
+	Outputs    []GraphOutputConfig `json:"outputs"`    // 导出配置的列表。
+	Damping    float64             `json:"damping"`    // 计算PageRank时的阻尼系数。为0时使用0.85。
+	Iterations uint32              `json:"iterations"` // 计算PageRank时的最大迭代次数。为0时使用100。
+	Top        int                 `json:"top"`        // 摘要报告中列出的网页的数量。为0时使用10。
+}
+
+// 链接图的导出配置。
+type GraphOutputConfig struct {
+	Format string `json:"format"` // 格式。可选值为“graphml”、“dot”和“csv”。
+	Path   string `json:"path"`   // 文件路径。
+}
+
+// 输出配置。
+type OutputConfig struct {
+	Type        string   `json:"type"`        // 类型。可选值为“jsonl”、“csv”、“stdout”和“warc”。
+	Path        string   `json:"path"`        // 文件路径。对于“warc”类型则是目录路径。
+	Fields      []string `json:"fields"`      // 字段名称的列表。仅用于“csv”类型。
+	MaxFileSize int64    `json:"maxFileSize"` // 单个文件的最大尺寸。仅用于“warc”类型。
+}
+
+// 加载并检查爬取配置。配置文件中的未知字段会被视为错误。
+func LoadConfig(path string) (*Config, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseConfig(content)
+}
+
+// 解析并检查爬取配置。
+func ParseConfig(content []byte) (*Config, error) {
+	decoder := json.NewDecoder(strings.NewReader(string(content)))
+	decoder.DisallowUnknownFields()
+	cfg := &Config{}
+	if err := decoder.Decode(cfg); err != nil {
+		return nil, errors.New(fmt.Sprintf("Invalid config: %s", err))
+	}
+	if err := cfg.Check(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// 确保爬取配置实现了参数容器的接口。
+var _ base.Args = (*Config)(nil)
+
+func (cfg *Config) Check() error {
+	if len(cfg.Seeds) == 0 {
+		return errors.New("At least one seed is required!\n")
+	}
+	for _, seed := range cfg.Seeds {
+		seedUrl, err := url.Parse(seed)
+		if err != nil {
+			return errors.New(fmt.Sprintf("The seed '%s' is invalid: %s\n", seed, err))
+		}
+		if scheme := strings.ToLower(seedUrl.Scheme); scheme != "http" && scheme != "https" {
+			return errors.New(fmt.Sprintf("Unsupported scheme '%s' of seed '%s'!\n", seedUrl.Scheme, seed))
+		}
+		if seedUrl.Host == "" {
+			return errors.New(fmt.Sprintf("The host of seed '%s' is empty!\n", seed))
+		}
+	}
+	if _, err := cfg.Scope.compile(); err != nil {
+		return err
+	}
+	channelArgs, err := cfg.ChannelArgs()
+	if err != nil {
+		return err
+	}
+	if err := channelArgs.Check(); err != nil {
+		return err
+	}
+	poolBaseArgs := cfg.PoolBaseArgs()
+	if err := poolBaseArgs.Check(); err != nil {
+		return err
+	}
+	if threshold := cfg.Filters.NearDuplicateThreshold; threshold != nil && *threshold > 63 {
+		return errors.New(fmt.Sprintf("The near duplicate threshold %d is greater than 63!\n", *threshold))
+	}
+	if cfg.Politeness.Delay < 0 || cfg.Politeness.Timeout < 0 {
+		return errors.New("The politeness delay and timeout can not be negative!\n")
+	}
+	decoratorArgs := cfg.DecoratorArgs()
+	if err := decoratorArgs.Check(); err != nil {
+		return err
+	}
+	if len(cfg.Parsers) == 0 {
+		return errors.New("At least one parser is required!\n")
+	}
+	for i, parserCfg := range cfg.Parsers {
+		if _, err := parserCfg.build(); err != nil {
+			return errors.New(fmt.Sprintf("The %dth parser is invalid: %s\n", i, err))
+		}
+	}
+	for i, output := range cfg.Outputs {
+		if err := output.check(); err != nil {
+			return errors.New(fmt.Sprintf("The %dth output is invalid: %s\n", i, err))
+		}
+	}
+	if cfg.IdleTimeout < 0 {
+		return errors.New("The idle timeout can not be negative!\n")
+	}
+	if budgetArgs, ok := cfg.BudgetArgs(); ok {
+		if err := budgetArgs.Check(); err != nil {
+			return err
+		}
+	}
+	if cfg.Graph != nil {
+		if err := cfg.Graph.check(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 爬取配置的描述模板。
+var configTemplate string = "{ seeds: %v, depth: %d, scope: %d/%d, channel: %s, pool: %s," +
+	" politeness: { delay: %s, timeout: %s }, parsers: %d, outputs: %d, idleTimeout: %s }"
+
+func (cfg *Config) String() string {
+	channelArgs, _ := cfg.ChannelArgs()
+	poolBaseArgs := cfg.PoolBaseArgs()
+	return fmt.Sprintf(configTemplate,
+		cfg.Seeds,
+		cfg.Depth,
+		len(cfg.Scope.Include),
+		len(cfg.Scope.Exclude),
+		channelArgs.String(),
+		poolBaseArgs.String(),
+		time.Duration(cfg.Politeness.Delay),
+		time.Duration(cfg.Politeness.Timeout),
+		len(cfg.Parsers),
+		len(cfg.Outputs),
+		time.Duration(cfg.IdleTimeout))
+}
+
+// 生成通道参数的容器。
+func (cfg *Config) ChannelArgs() (base.ChannelArgs, error) {
+	channel := cfg.Channel
+	policy, err := parseOverflowPolicy(channel.ErrorOverflowPolicy)
+	if err != nil {
+		return base.ChannelArgs{}, err
+	}
+	return base.NewChannelArgsWithPolicy(
+		channel.ReqChanLen,
+		channel.RespChanLen,
+		channel.ItemChanLen,
+		channel.ErrorChanLen,
+		policy), nil
+}
+
+// 根据名称获得溢出策略。名称为空时会使用默认的策略。
+func parseOverflowPolicy(name string) (base.OverflowPolicy, error) {
+	if name == "" {
+		return base.OVERFLOW_POLICY_DROP_OLDEST, nil
+	}
+	for policy := base.OverflowPolicy(0); ; policy++ {
+		policyName, ok := base.OverflowPolicyName(policy)
+		if !ok {
+			break
+		}
+		if strings.EqualFold(policyName, name) {
+			return policy, nil
+		}
+	}
+	return 0, errors.New(fmt.Sprintf("Unsupported error overflow policy '%s'!\n", name))
+}
+
+// 生成池基本参数的容器。
+func (cfg *Config) PoolBaseArgs() base.PoolBaseArgs {
+	return base.NewPoolBaseArgs(cfg.Pool.PageDownloaderPoolSize, cfg.Pool.AnalyzerPoolSize)
+}
+
+// 生成请求装饰参数的容器。
+func (cfg *Config) DecoratorArgs() base.DecoratorArgs {
+	return base.NewDecoratorArgs(
+		cfg.Politeness.Headers, cfg.Politeness.UserAgents, nil, nil, base.PROXY_POLICY_ROUND_ROBIN)
+}
+
+// 生成爬取预算参数的容器。若没有配置爬取预算，则第二个结果值为false。
+func (cfg *Config) BudgetArgs() (base.BudgetArgs, bool) {
+	budget := cfg.Budget
+	if budget == nil {
+		return base.BudgetArgs{}, false
+	}
+	domainQuotas := make(map[string]base.DomainQuota, len(budget.Domains))
+	for domain, quota := range budget.Domains {
+		domainQuotas[domain] = base.DomainQuota(quota)
+	}
+	return base.NewBudgetArgs(
+		budget.MaxPages,
+		budget.MaxBytes,
+		time.Duration(budget.MaxDuration),
+		budget.MaxItems,
+		base.DomainQuota(budget.Domain),
+		domainQuotas), true
+}
+
+// 编译范围配置中的模式。
+func (scope ScopeConfig) compile() (*urlFilter, error) {
+	filter := &urlFilter{}
+	for _, pattern := range scope.Include {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("The include pattern '%s' is invalid: %s\n", pattern, err))
+		}
+		filter.include = append(filter.include, re)
+	}
+	for _, pattern := range scope.Exclude {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("The exclude pattern '%s' is invalid: %s\n", pattern, err))
+		}
+		filter.exclude = append(filter.exclude, re)
+	}
+	return filter, nil
+}
+
+// 检查链接图的配置。
+func (graph *GraphConfig) check() error {
+	if graph.Damping < 0 || graph.Damping >= 1 {
+		return errors.New(fmt.Sprintf("The damping factor %v is not in (0, 1)!\n", graph.Damping))
+	}
+	if graph.Top < 0 {
+		return errors.New("The top number of link graph can not be negative!\n")
+	}
+	for i, output := range graph.Outputs {
+		if _, err := linkgraph.ParseFormat(output.Format); err != nil {
+			return errors.New(fmt.Sprintf("The %dth graph output is invalid: %s\n", i, err))
+		}
+		if output.Path == "" {
+			return errors.New(fmt.Sprintf("The path of %dth graph output is empty!\n", i))
+		}
+	}
+	return nil
+}
+
+// 检查输出配置。
+func (output OutputConfig) check() error {
+	switch output.Type {
+	case "stdout":
+	case "jsonl", "warc":
+		if output.Path == "" {
+			return errors.New(fmt.Sprintf("The path of %s output is empty!", output.Type))
+		}
+	case "csv":
+		if output.Path == "" {
+			return errors.New("The path of csv output is empty!")
+		}
+		if len(output.Fields) == 0 {
+			return errors.New("The fields of csv output are empty!")
+		}
+	default:
+		return errors.New(fmt.Sprintf("Unsupported output type '%s'!", output.Type))
+	}
+	if output.MaxFileSize < 0 {
+		return errors.New("The max file size can not be negative!")
+	}
+	return nil
+}
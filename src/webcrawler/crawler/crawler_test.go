@@ -0,0 +1,135 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var testConfig = `{
+	"seeds": ["%s/"],
+	"depth": 2,
+	"scope": {"exclude": ["/private"]},
+	"channel": {"reqChanLen": 10, "respChanLen": 10, "itemChanLen": 10, "errorChanLen": 10,
+		"errorOverflowPolicy": "drop-newest"},
+	"pool": {"pageDownloaderPoolSize": 2, "analyzerPoolSize": 2},
+	"filters": {"skipExtensions": ["jpg"], "nearDuplicateThreshold": 0},
+	"politeness": {"delay": "1ms", "timeout": "5s", "userAgents": ["test-crawler/1.0"]},
+	"parsers": [{"type": "links"}, {"type": "title"}],
+	"outputs": [{"type": "jsonl", "path": "%s"}, {"type": "csv", "path": "%s", "fields": ["url", "title"]}],
+	"graph": {"outputs": [{"format": "csv", "path": "%s"}], "top": 3},
+	"idleTimeout": "1s"
+}`
+
+func TestParseConfigErrors(t *testing.T) {
+	cases := map[string]string{
+		"no seeds":         `{"seeds": [], "parsers": [{"type": "links"}]}`,
+		"bad seed":         `{"seeds": ["ftp://a.com"], "parsers": [{"type": "links"}]}`,
+		"unknown field":    `{"seeds": ["http://a.com"], "depht": 1}`,
+		"zero channel":     `{"seeds": ["http://a.com"], "pool": {"pageDownloaderPoolSize": 1, "analyzerPoolSize": 1}, "parsers": [{"type": "links"}]}`,
+		"bad duration":     `{"seeds": ["http://a.com"], "idleTimeout": 10}`,
+		"bad pattern":      `{"seeds": ["http://a.com"], "scope": {"include": ["("]}}`,
+		"bad graph format": `{"seeds": ["http://a.com"], "channel": {"reqChanLen": 1, "respChanLen": 1, "itemChanLen": 1, "errorChanLen": 1}, "pool": {"pageDownloaderPoolSize": 1, "analyzerPoolSize": 1}, "parsers": [{"type": "links"}], "graph": {"outputs": [{"format": "gexf", "path": "a"}]}}`,
+		"empty budget":     `{"seeds": ["http://a.com"], "channel": {"reqChanLen": 1, "respChanLen": 1, "itemChanLen": 1, "errorChanLen": 1}, "pool": {"pageDownloaderPoolSize": 1, "analyzerPoolSize": 1}, "parsers": [{"type": "links"}], "budget": {}}`,
+		"bad parser type":  `{"seeds": ["http://a.com"], "channel": {"reqChanLen": 1, "respChanLen": 1, "itemChanLen": 1, "errorChanLen": 1}, "pool": {"pageDownloaderPoolSize": 1, "analyzerPoolSize": 1}, "parsers": [{"type": "xpath"}]}`,
+	}
+	for name, content := range cases {
+		if _, err := ParseConfig([]byte(content)); err == nil {
+			t.Errorf("An error is expected for config with %s!\n", name)
+		}
+	}
+}
+
+func TestUrlFilter(t *testing.T) {
+	cfg := &Config{
+		Scope:   ScopeConfig{Include: []string{`^http://a\.com/`}, Exclude: []string{`\?print=`}},
+		Filters: FilterConfig{SkipExtensions: []string{".PNG", "pdf"}},
+	}
+	filter, err := cfg.urlFilter()
+	if err != nil {
+		t.Fatalf("Filter error: %s\n", err)
+	}
+	cases := map[string]bool{
+		"http://a.com/index.html":     true,
+		"http://b.com/index.html":     false,
+		"http://a.com/doc?print=1":    false,
+		"http://a.com/logo.png?v=2":   false,
+		"http://a.com/paper.PDF":      false,
+		"http://a.com/pdf/index.html": true,
+	}
+	for reqUrl, expected := range cases {
+		if filter.accept(reqUrl) != expected {
+			t.Errorf("The acceptance of %s should be %v!\n", reqUrl, expected)
+		}
+	}
+}
+
+func TestRun(t *testing.T) {
+	mux := http.NewServeMux()
+	var userAgent string
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		userAgent = r.UserAgent()
+		fmt.Fprint(w, `<html><head><title>Home</title></head><body>
+			<a href="/a">A</a><a href="/private">P</a><a href="/logo.jpg">L</a></body></html>`)
+	})
+	mux.HandleFunc("/a", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<html><head><title>Page &amp; A</title></head><body><a href="/">Home</a></body></html>`)
+	})
+	requested := make(chan string, 10)
+	mux.HandleFunc("/private", func(w http.ResponseWriter, r *http.Request) {
+		requested <- r.URL.Path
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "crawler")
+	if err != nil {
+		t.Fatalf("TempDir error: %s\n", err)
+	}
+	defer os.RemoveAll(dir)
+	jsonlPath := filepath.Join(dir, "items.jsonl")
+	csvPath := filepath.Join(dir, "items.csv")
+	edgesPath := filepath.Join(dir, "links.csv")
+	cfg, err := ParseConfig([]byte(fmt.Sprintf(testConfig, server.URL, jsonlPath, csvPath, edgesPath)))
+	if err != nil {
+		t.Fatalf("Config error: %s\n", err)
+	}
+	report, err := run(cfg)
+	if err != nil {
+		t.Fatalf("Run error: %s\n", err)
+	}
+	if len(requested) != 0 {
+		t.Errorf("The excluded page should not be requested!\n")
+	}
+	if userAgent != "test-crawler/1.0" {
+		t.Errorf("Unexpected user agent %q!\n", userAgent)
+	}
+	lines, _ := ioutil.ReadFile(jsonlPath)
+	if n := strings.Count(string(lines), "\n"); n != 2 {
+		t.Errorf("The number of items should be 2, but %d!\n%s", n, lines)
+	}
+	records, _ := ioutil.ReadFile(csvPath)
+	if !strings.HasPrefix(string(records), "url,title\n") ||
+		!strings.Contains(string(records), server.URL+"/a,Page & A\n") {
+		t.Errorf("Unexpected CSV output:\n%s", records)
+	}
+	edges, _ := ioutil.ReadFile(edgesPath)
+	expectedEdges := "from,to,anchor,depth\n" +
+		server.URL + "/," + server.URL + "/a,A,1\n" +
+		server.URL + "/a," + server.URL + "/,Home,2\n"
+	if string(edges) != expectedEdges {
+		t.Errorf("Unexpected edge list:\n%s", edges)
+	}
+	text := report.String()
+	for _, expected := range []string{"Crawl Report:", "jsonl:" + jsonlPath + ": 2 items",
+		"Summary: nodes: 2, edges: 2", "Exported csv: " + edgesPath, "Top PageRank:", "Scheduler:"} {
+		if !strings.Contains(text, expected) {
+			t.Errorf("The report should contain %q:\n%s", expected, text)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"webcrawler/linkgraph"
+)
+
+// 计算PageRank时的默认参数。
+const (
+	defaultDamping    = 0.85
+	defaultIterations = 100
+	defaultTop        = 10
+	rankTolerance     = 1e-9
+)
+
+// 链接图的报告。
+type graphReport struct {
+	summary     string                // 链接图的摘要信息。
+	topInDegree []linkgraph.NodeScore // 入度最高的网页的列表。
+	topPageRank []linkgraph.NodeScore // PageRank值最高的网页的列表。
+	exported    []string              // 已导出的文件的列表。
+}
+
+// 分析并导出链接图。
+func analyzeGraph(cfg *GraphConfig, graph linkgraph.LinkGraph) (*graphReport, error) {
+	damping := cfg.Damping
+	if damping == 0 {
+		damping = defaultDamping
+	}
+	iterations := cfg.Iterations
+	if iterations == 0 {
+		iterations = defaultIterations
+	}
+	top := cfg.Top
+	if top == 0 {
+		top = defaultTop
+	}
+	pageRanks, err := linkgraph.PageRank(graph, damping, iterations, rankTolerance)
+	if err != nil {
+		return nil, err
+	}
+	report := &graphReport{
+		summary:     graph.Summary(),
+		topInDegree: linkgraph.RankNodes(linkgraph.InDegreeScores(graph.InDegree()), top),
+		topPageRank: linkgraph.RankNodes(pageRanks, top),
+	}
+	for _, output := range cfg.Outputs {
+		format, err := linkgraph.ParseFormat(output.Format)
+		if err != nil {
+			return report, err
+		}
+		if err := linkgraph.Export(graph, format, output.Path, pageRanks); err != nil {
+			return report, err
+		}
+		report.exported = append(report.exported, fmt.Sprintf("%s: %s", format, output.Path))
+	}
+	return report, nil
+}
+
+func (report *graphReport) String() string {
+	var buffer bytes.Buffer
+	buffer.WriteString(fmt.Sprintf("    Summary: %s\n", report.summary))
+	for _, exported := range report.exported {
+		buffer.WriteString(fmt.Sprintf("    Exported %s\n", exported))
+	}
+	buffer.WriteString("    Top in-degree:\n")
+	for i, score := range report.topInDegree {
+		buffer.WriteString(fmt.Sprintf("      %d. %s (%d)\n", i+1, score.Node, int(score.Score)))
+	}
+	buffer.WriteString("    Top PageRank:\n")
+	for i, score := range report.topPageRank {
+		buffer.WriteString(fmt.Sprintf("      %d. %s (%.6f)\n", i+1, score.Node, score.Score))
+	}
+	return buffer.String()
+}
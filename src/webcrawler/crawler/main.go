@@ -0,0 +1,274 @@
+// crawler命令会根据JSON格式的配置文件执行一次完整的爬取，并在结束时写出摘要报告。
+//
+// 用法：
+//
+//	crawler -config crawler.json [-report report.txt] [-check]
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"logging"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+	anlz "webcrawler/analyzer"
+	base "webcrawler/base"
+	dl "webcrawler/downloader"
+	ipl "webcrawler/itempipeline"
+	"webcrawler/linkgraph"
+	sched "webcrawler/scheduler"
+	"webcrawler/tool"
+	"webcrawler/warc"
+)
+
+// 日志记录器。
+var logger logging.Logger = base.NewLogger()
+
+// 默认的空闲超时时间。
+const defaultIdleTimeout = 10 * time.Second
+
+func main() {
+	configPath := flag.String("config", "crawler.json", "the path of crawl config file")
+	reportPath := flag.String("report", "", "the path of summary report (overrides the config)")
+	checkOnly := flag.Bool("check", false, "only check the config file")
+	flag.Parse()
+
+	cfg, err := LoadConfig(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Load config error: %s\n", err)
+		os.Exit(2)
+	}
+	if *checkOnly {
+		fmt.Printf("The config is valid: %s\n", cfg)
+		return
+	}
+	if *reportPath != "" {
+		cfg.Report = *reportPath
+	}
+	report, err := run(cfg)
+	if report != nil {
+		if writeErr := writeReport(cfg.Report, report); writeErr != nil {
+			fmt.Fprintf(os.Stderr, "Write report error: %s\n", writeErr)
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Crawl error: %s\n", err)
+		os.Exit(1)
+	}
+}
+
+// 爬取报告。
+type crawlReport struct {
+	seeds     []string                  // 种子URL的列表。
+	startTime time.Time                 // 开始时间。
+	endTime   time.Time                 // 结束时间。
+	sinks     []itemSink                // 条目输出的列表。
+	warc      warc.Writer               // WARC写入器。
+	summary   sched.SchedSummary        // 调度器的摘要信息。
+	graph     *graphReport              // 链接图的报告。
+	errors    map[base.ErrorType]uint64 // 各类错误的数量。
+}
+
+// 执行爬取，并返回爬取报告。即使发生错误，也可能会有爬取报告。
+func run(cfg *Config) (*crawlReport, error) {
+	scheduler := sched.NewScheduler()
+	report := &crawlReport{seeds: cfg.Seeds}
+
+	respParsers, err := cfg.respParsers()
+	if err != nil {
+		return nil, err
+	}
+	itemProcessors := make([]ipl.ProcessItem, 0, len(cfg.Outputs))
+	defer func() {
+		for _, sink := range report.sinks {
+			if err := sink.Close(); err != nil {
+				logger.Errorf("Close output %s error: %s\n", sink.Name(), err)
+			}
+		}
+	}()
+	for _, output := range cfg.Outputs {
+		if output.Type == "warc" {
+			writer, err := warc.NewWriter(output.Path, "crawler", output.MaxFileSize)
+			if err != nil {
+				return nil, err
+			}
+			scheduler.SetWarcWriter(writer)
+			report.warc = writer
+			continue
+		}
+		sink, err := newItemSink(output)
+		if err != nil {
+			return nil, err
+		}
+		report.sinks = append(report.sinks, sink)
+		itemProcessors = append(itemProcessors, genSinkProcessor(sink))
+	}
+
+	if threshold := cfg.Filters.NearDuplicateThreshold; threshold != nil {
+		scheduler.SetPageDeduplicator(anlz.NewSimHashDeduplicator(*threshold))
+	}
+	decoratorArgs := cfg.DecoratorArgs()
+	if len(decoratorArgs.DefaultHeaders()) > 0 || len(decoratorArgs.UserAgents()) > 0 {
+		decorator, err := dl.NewRequestDecorator(decoratorArgs)
+		if err != nil {
+			return nil, err
+		}
+		scheduler.SetRequestDecorator(decorator)
+	}
+	if budgetArgs, ok := cfg.BudgetArgs(); ok {
+		if err := scheduler.SetCrawlBudget(budgetArgs); err != nil {
+			return nil, err
+		}
+	}
+	var graph linkgraph.LinkGraph
+	if cfg.Graph != nil {
+		graph = linkgraph.NewLinkGraph()
+		scheduler.SetLinkGraph(graph)
+	}
+	seeds := make([]*http.Request, 0, len(cfg.Seeds))
+	for _, seed := range cfg.Seeds {
+		httpReq, err := http.NewRequest("GET", seed, nil)
+		if err != nil {
+			return nil, err
+		}
+		seeds = append(seeds, httpReq)
+	}
+	scheduler.SetSeeds(seeds[1:])
+
+	idleTimeout := time.Duration(cfg.IdleTimeout)
+	if idleTimeout == 0 {
+		idleTimeout = defaultIdleTimeout
+	}
+	checkCountChan := tool.Monitoring(
+		scheduler,
+		idleTimeout,
+		1,
+		true,
+		false,
+		report.record)
+
+	channelArgs, _ := cfg.ChannelArgs()
+	report.startTime = time.Now()
+	err = scheduler.Start(
+		channelArgs,
+		cfg.PoolBaseArgs(),
+		cfg.Depth,
+		genHttpClientGenerator(cfg.Politeness),
+		respParsers,
+		itemProcessors,
+		seeds[0])
+	if err != nil {
+		scheduler.Stop()
+		return nil, err
+	}
+	<-checkCountChan
+	report.endTime = time.Now()
+	report.summary = scheduler.Summary("    ")
+	report.errors = scheduler.ErrorCounts()
+	if graph != nil {
+		report.graph, err = analyzeGraph(cfg.Graph, graph)
+		if err != nil {
+			return report, err
+		}
+	}
+	return report, nil
+}
+
+// 生成HTTP客户端的生成函数。所有的HTTP客户端共用同一个礼貌的HTTP传输。
+func genHttpClientGenerator(politeness PoliteConfig) sched.GenHttpClient {
+	transport := dl.NewPoliteTransport(nil, time.Duration(politeness.Delay))
+	return func() *http.Client {
+		return &http.Client{
+			Transport: transport,
+			Timeout:   time.Duration(politeness.Timeout),
+		}
+	}
+}
+
+// 记录监控信息。错误的计数由调度器负责，这里只需输出日志。
+func (report *crawlReport) record(level byte, content string) {
+	if content == "" {
+		return
+	}
+	switch level {
+	case 0:
+		logger.Infoln(content)
+	case 1:
+		logger.Warnln(content)
+	case 2:
+		logger.Errorln(content)
+	}
+}
+
+// 爬取报告的模板。
+var reportTemplate = "Crawl Report:\n" +
+	"  Seeds: %s\n" +
+	"  Start time: %s\n" +
+	"  End time: %s\n" +
+	"  Elapsed time: %s\n" +
+	"  Outputs:\n%s" +
+	"  Errors:\n%s" +
+	"  Link graph:\n%s" +
+	"  Scheduler:\n%s"
+
+func (report *crawlReport) String() string {
+	var outputs bytes.Buffer
+	for _, sink := range report.sinks {
+		outputs.WriteString(fmt.Sprintf("    %s: %d items\n", sink.Name(), sink.Count()))
+	}
+	if report.warc != nil {
+		outputs.WriteString(fmt.Sprintf("    warc: %s\n", report.warc.Summary()))
+	}
+	if outputs.Len() == 0 {
+		outputs.WriteString("    <none>\n")
+	}
+	var errs bytes.Buffer
+	errTypes := make([]string, 0, len(report.errors))
+	for errType, count := range report.errors {
+		if count > 0 {
+			errTypes = append(errTypes, string(errType))
+		}
+	}
+	sort.Strings(errTypes)
+	for _, errType := range errTypes {
+		errs.WriteString(fmt.Sprintf("    %s: %d\n", errType, report.errors[base.ErrorType(errType)]))
+	}
+	if errs.Len() == 0 {
+		errs.WriteString("    <none>\n")
+	}
+	graph := "    <disabled>\n"
+	if report.graph != nil {
+		graph = report.graph.String()
+	}
+	summary := ""
+	if report.summary != nil {
+		summary = report.summary.Detail()
+	}
+	return fmt.Sprintf(reportTemplate,
+		strings.Join(report.seeds, ", "),
+		report.startTime.Format(time.RFC3339),
+		report.endTime.Format(time.RFC3339),
+		report.endTime.Sub(report.startTime),
+		outputs.String(),
+		errs.String(),
+		graph,
+		summary)
+}
+
+// 写出爬取报告。参数path为空时会输出到标准输出。
+func writeReport(path string, report *crawlReport) error {
+	if report == nil {
+		return errors.New("The report is invalid!")
+	}
+	if path == "" {
+		_, err := fmt.Print(report.String())
+		return err
+	}
+	return ioutil.WriteFile(path, []byte(report.String()), 0644)
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html"
+	"io/ioutil"
+	"net/http"
+	"path"
+	"regexp"
+	"strings"
+	anlz "webcrawler/analyzer"
+	base "webcrawler/base"
+)
+
+// URL过滤器。
+type urlFilter struct {
+	include        []*regexp.Regexp // 包含模式的列表。
+	exclude        []*regexp.Regexp // 排除模式的列表。
+	skipExtensions map[string]bool  // 需要跳过的扩展名的集合。
+}
+
+// 判断URL是否应被保留。
+func (filter *urlFilter) accept(reqUrl string) bool {
+	if len(filter.include) > 0 {
+		matched := false
+		for _, re := range filter.include {
+			if re.MatchString(reqUrl) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	for _, re := range filter.exclude {
+		if re.MatchString(reqUrl) {
+			return false
+		}
+	}
+	if len(filter.skipExtensions) > 0 {
+		p := reqUrl
+		if i := strings.IndexAny(p, "?#"); i >= 0 {
+			p = p[:i]
+		}
+		if filter.skipExtensions[strings.ToLower(path.Ext(p))] {
+			return false
+		}
+	}
+	return true
+}
+
+// 生成URL过滤器。
+func (cfg *Config) urlFilter() (*urlFilter, error) {
+	filter, err := cfg.Scope.compile()
+	if err != nil {
+		return nil, err
+	}
+	filter.skipExtensions = make(map[string]bool)
+	for _, ext := range cfg.Filters.SkipExtensions {
+		ext = strings.ToLower(strings.TrimSpace(ext))
+		if ext != "" && !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		filter.skipExtensions[ext] = true
+	}
+	return filter, nil
+}
+
+// 包装响应解析函数，使其产生的请求都经过URL过滤器的过滤。
+func withUrlFilter(parser anlz.ParseResponse, filter *urlFilter) anlz.ParseResponse {
+	return func(httpResp *http.Response, respDepth uint32, respMeta base.Meta) ([]base.Data, []error) {
+		dataList, errs := parser(httpResp, respDepth, respMeta)
+		filtered := dataList[:0]
+		for _, data := range dataList {
+			if req, ok := data.(*base.Request); ok {
+				httpReq := req.HttpReq()
+				if httpReq == nil || httpReq.URL == nil || !filter.accept(httpReq.URL.String()) {
+					continue
+				}
+			}
+			filtered = append(filtered, data)
+		}
+		return filtered, errs
+	}
+}
+
+// 生成所有的响应解析函数。
+func (cfg *Config) respParsers() ([]anlz.ParseResponse, error) {
+	filter, err := cfg.urlFilter()
+	if err != nil {
+		return nil, err
+	}
+	parsers := make([]anlz.ParseResponse, 0, len(cfg.Parsers))
+	for _, parserCfg := range cfg.Parsers {
+		parser, err := parserCfg.build()
+		if err != nil {
+			return nil, err
+		}
+		parsers = append(parsers, withUrlFilter(parser, filter))
+	}
+	return parsers, nil
+}
+
+// 生成响应解析函数。
+func (parserCfg ParserConfig) build() (anlz.ParseResponse, error) {
+	switch parserCfg.Type {
+	case "links":
+		return anlz.ParseLinks, nil
+	case "title":
+		return parseTitle, nil
+	case "json":
+		if parserCfg.Items == "" {
+			return nil, errors.New("The items path of json parser is empty!")
+		}
+		paginations := make([]anlz.Pagination, 0, len(parserCfg.Pagination))
+		for _, paginationCfg := range parserCfg.Pagination {
+			pagination, err := paginationCfg.build()
+			if err != nil {
+				return nil, err
+			}
+			paginations = append(paginations, pagination)
+		}
+		return anlz.NewJsonParser(parserCfg.Items, parserCfg.Fields, paginations...)
+	}
+	return nil, errors.New(fmt.Sprintf("Unsupported parser type '%s'!", parserCfg.Type))
+}
+
+// 生成翻页方式。
+func (paginationCfg PaginationConfig) build() (anlz.Pagination, error) {
+	switch paginationCfg.Type {
+	case "cursor":
+		return anlz.NewCursorPagination(paginationCfg.Path, paginationCfg.Param)
+	case "offset":
+		return anlz.NewOffsetPagination(
+			paginationCfg.OffsetParam,
+			paginationCfg.LimitParam,
+			paginationCfg.PageSize,
+			paginationCfg.TotalPath)
+	case "link":
+		return anlz.NewLinkHeaderPagination(), nil
+	case "next":
+		return anlz.NewNextUrlPagination(paginationCfg.Path)
+	}
+	return nil, errors.New(fmt.Sprintf("Unsupported pagination type '%s'!", paginationCfg.Type))
+}
+
+// 用于查找网页标题的正则表达式。
+var titleRegexp = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+
+// 响应解析函数。它会为每个带有标题的HTML网页生成一个包含URL、标题和深度的条目。
+func parseTitle(httpResp *http.Response, respDepth uint32, respMeta base.Meta) ([]base.Data, []error) {
+	if httpResp.StatusCode != 200 || httpResp.Body == nil || httpResp.Request == nil {
+		return nil, nil
+	}
+	body, err := ioutil.ReadAll(httpResp.Body)
+	httpResp.Body.Close()
+	httpResp.Body = ioutil.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return nil, []error{err}
+	}
+	match := titleRegexp.FindSubmatch(body)
+	if match == nil {
+		return nil, nil
+	}
+	title := strings.Join(strings.Fields(html.UnescapeString(string(match[1]))), " ")
+	item := base.Item{
+		"url":   httpResp.Request.URL.String(),
+		"title": title,
+		"depth": respDepth,
+	}
+	return []base.Data{&item}, nil
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	base "webcrawler/base"
+	ipl "webcrawler/itempipeline"
+)
+
+// 条目输出的接口类型。
+type itemSink interface {
+	Name() string               // 获得名称。
+	Write(item base.Item) error // 写入条目。
+	Count() uint64              // 获得已写入的条目的数量。
+	Close() error               // 关闭输出。
+}
+
+// 根据输出配置创建条目输出。对于“warc”类型，结果值为nil。
+func newItemSink(output OutputConfig) (itemSink, error) {
+	switch output.Type {
+	case "stdout":
+		return newJsonLinesSink("stdout", nopCloser{os.Stdout}), nil
+	case "jsonl":
+		file, err := os.Create(output.Path)
+		if err != nil {
+			return nil, err
+		}
+		return newJsonLinesSink("jsonl:"+output.Path, file), nil
+	case "csv":
+		file, err := os.Create(output.Path)
+		if err != nil {
+			return nil, err
+		}
+		return newCsvSink("csv:"+output.Path, file, output.Fields)
+	}
+	return nil, nil
+}
+
+// 不会关闭底层写入器的包装。
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error {
+	return nil
+}
+
+// 以JSON Lines格式写入条目的输出。
+type jsonLinesSink struct {
+	name   string         // 名称。
+	file   io.WriteCloser // 底层的写入器。
+	writer *bufio.Writer  // 带缓冲的写入器。
+	count  uint64         // 已写入的条目的数量。
+	mutex  sync.Mutex     // 互斥锁。
+}
+
+func newJsonLinesSink(name string, file io.WriteCloser) itemSink {
+	return &jsonLinesSink{name: name, file: file, writer: bufio.NewWriter(file)}
+}
+
+func (sink *jsonLinesSink) Name() string {
+	return sink.name
+}
+
+func (sink *jsonLinesSink) Write(item base.Item) error {
+	line, err := json.Marshal(item)
+	if err != nil {
+		return err
+	}
+	sink.mutex.Lock()
+	defer sink.mutex.Unlock()
+	sink.writer.Write(line)
+	if err := sink.writer.WriteByte('\n'); err != nil {
+		return err
+	}
+	sink.count++
+	return nil
+}
+
+func (sink *jsonLinesSink) Count() uint64 {
+	sink.mutex.Lock()
+	defer sink.mutex.Unlock()
+	return sink.count
+}
+
+func (sink *jsonLinesSink) Close() error {
+	sink.mutex.Lock()
+	defer sink.mutex.Unlock()
+	if err := sink.writer.Flush(); err != nil {
+		sink.file.Close()
+		return err
+	}
+	return sink.file.Close()
+}
+
+// 以CSV格式写入条目的输出。第一行是字段名称，缺失的字段值为空。
+type csvSink struct {
+	name   string         // 名称。
+	file   io.WriteCloser // 底层的写入器。
+	writer *csv.Writer    // CSV写入器。
+	fields []string       // 字段名称的列表。
+	count  uint64         // 已写入的条目的数量。
+	mutex  sync.Mutex     // 互斥锁。
+}
+
+func newCsvSink(name string, file io.WriteCloser, fields []string) (itemSink, error) {
+	writer := csv.NewWriter(file)
+	if err := writer.Write(fields); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return &csvSink{name: name, file: file, writer: writer, fields: fields}, nil
+}
+
+func (sink *csvSink) Name() string {
+	return sink.name
+}
+
+func (sink *csvSink) Write(item base.Item) error {
+	record := make([]string, len(sink.fields))
+	for i, field := range sink.fields {
+		if value, ok := item[field]; ok && value != nil {
+			record[i] = fmt.Sprint(value)
+		}
+	}
+	sink.mutex.Lock()
+	defer sink.mutex.Unlock()
+	if err := sink.writer.Write(record); err != nil {
+		return err
+	}
+	sink.count++
+	return nil
+}
+
+func (sink *csvSink) Count() uint64 {
+	sink.mutex.Lock()
+	defer sink.mutex.Unlock()
+	return sink.count
+}
+
+func (sink *csvSink) Close() error {
+	sink.mutex.Lock()
+	defer sink.mutex.Unlock()
+	sink.writer.Flush()
+	if err := sink.writer.Error(); err != nil {
+		sink.file.Close()
+		return err
+	}
+	return sink.file.Close()
+}
+
+// 生成把条目写入到输出的条目处理器。条目本身不会被修改。
+func genSinkProcessor(sink itemSink) ipl.ProcessItem {
+	return func(item base.Item) (base.Item, error) {
+		if err := sink.Write(item); err != nil {
+			return nil, err
+		}
+		return item, nil
+	}
+}
@@ -0,0 +1,308 @@
+package downloader
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httputil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+	base "webcrawler/base"
+)
+
+// 缓存模式。
+type CacheMode uint8
+
+// 缓存模式常量。
+const (
+	// 遵循Cache-Control等响应头。只有新鲜的缓存会被使用，且不会存储不允许缓存的响应。
+	CACHE_MODE_NORMAL CacheMode = 0
+	// 强制缓存。忽略响应头，只要有缓存就使用；否则下载并存储响应。适用于录制语料。
+	CACHE_MODE_FORCE CacheMode = 1
+	// 离线重放。只使用缓存，不会访问网络。缓存缺失时会返回错误。
+	CACHE_MODE_OFFLINE CacheMode = 2
+)
+
+// 缓存模式的名称的字典。
+var cacheModeNameMap = map[CacheMode]string{
+	CACHE_MODE_NORMAL:  "normal",
+	CACHE_MODE_FORCE:   "force",
+	CACHE_MODE_OFFLINE: "offline",
+}
+
+// 获得缓存模式的名称。若缓存模式不受支持，则第二个结果值为false。
+func CacheModeName(mode CacheMode) (string, bool) {
+	name, ok := cacheModeNameMap[mode]
+	return name, ok
+}
+
+// 存储时间的响应头。它会被添加到被存储的响应中。
+const cacheStoredAtHeader = "X-Webcrawler-Stored-At"
+
+// 可被缓存的状态码的字典。
+var cacheableStatusMap = map[int]bool{
+	200: true, 203: true, 300: true, 301: true, 404: true, 410: true,
+}
+
+// HTTP缓存的接口类型。它的所有方法都是并发安全的。
+type HttpCache interface {
+	// 获得缓存模式。
+	Mode() CacheMode
+	// 获得与请求对应的可用的缓存响应。若没有，则结果值为nil。
+	Get(req *http.Request) (*http.Response, error)
+	// 存储响应。若该响应不应被缓存，则会被忽略。
+	// 响应的主体会被完整读取，然后被替换为一个等价的主体。
+	Put(resp *http.Response) error
+	// 获取摘要信息。
+	Summary() string
+}
+
+// 创建基于磁盘的HTTP缓存。每个响应都以原始的HTTP报文的形式被存储在目录dir下的一个文件中。
+func NewDiskHttpCache(dir string, mode CacheMode) (HttpCache, error) {
+	if _, ok := CacheModeName(mode); !ok {
+		errMsg := fmt.Sprintf("Unsupported cache mode %d!\n", mode)
+		return nil, errors.New(errMsg)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &diskHttpCache{dir: dir, mode: mode}, nil
+}
+
+// 基于磁盘的HTTP缓存的实现类型。
+type diskHttpCache struct {
+	dir    string    // 缓存目录。
+	mode   CacheMode // 缓存模式。
+	hits   uint64    // 命中的次数。
+	misses uint64    // 未命中的次数。
+	stores uint64    // 存储的次数。
+}
+
+func (cache *diskHttpCache) Mode() CacheMode {
+	return cache.mode
+}
+
+// 获得与请求对应的缓存文件的路径。
+func (cache *diskHttpCache) path(req *http.Request) string {
+	sum := sha256.Sum256([]byte(req.Method + " " + req.URL.String()))
+	key := hex.EncodeToString(sum[:])
+	return filepath.Join(cache.dir, key[:2], key+".http")
+}
+
+func (cache *diskHttpCache) Get(req *http.Request) (*http.Response, error) {
+	if req.Method != "GET" && req.Method != "HEAD" {
+		atomic.AddUint64(&cache.misses, 1)
+		return nil, nil
+	}
+	if cache.mode == CACHE_MODE_NORMAL {
+		reqDirectives := parseCacheControl(req.Header)
+		if _, ok := reqDirectives["no-cache"]; ok {
+			atomic.AddUint64(&cache.misses, 1)
+			return nil, nil
+		}
+	}
+	data, err := ioutil.ReadFile(cache.path(req))
+	if err != nil {
+		atomic.AddUint64(&cache.misses, 1)
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	resp, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(data)), req)
+	if err != nil {
+		atomic.AddUint64(&cache.misses, 1)
+		return nil, err
+	}
+	if cache.mode == CACHE_MODE_NORMAL && !isFresh(req, resp) {
+		resp.Body.Close()
+		atomic.AddUint64(&cache.misses, 1)
+		return nil, nil
+	}
+	atomic.AddUint64(&cache.hits, 1)
+	return resp, nil
+}
+
+func (cache *diskHttpCache) Put(resp *http.Response) error {
+	req := resp.Request
+	if req == nil || req.URL == nil {
+		return errors.New("The request of HTTP response is invalid!")
+	}
+	if req.Method != "GET" || !cacheableStatusMap[resp.StatusCode] {
+		return nil
+	}
+	if cache.mode == CACHE_MODE_NORMAL {
+		if _, ok := parseCacheControl(req.Header)["no-store"]; ok {
+			return nil
+		}
+		if _, ok := parseCacheControl(resp.Header)["no-store"]; ok {
+			return nil
+		}
+	}
+	var body []byte
+	if resp.Body != nil {
+		var err error
+		body, err = ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+	}
+	stored := *resp
+	stored.Header = resp.Header.Clone()
+	stored.Header.Set(cacheStoredAtHeader, time.Now().UTC().Format(time.RFC3339Nano))
+	stored.Body = ioutil.NopCloser(bytes.NewReader(body))
+	stored.ContentLength = int64(len(body))
+	stored.TransferEncoding = nil
+	stored.Header.Del("Content-Length")
+	data, err := httputil.DumpResponse(&stored, true)
+	if err != nil {
+		return err
+	}
+	path := cache.path(req)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	// 先写入临时文件再重命名，以免读取到不完整的文件。
+	tempFile, err := ioutil.TempFile(filepath.Dir(path), "tmp-")
+	if err != nil {
+		return err
+	}
+	_, err = tempFile.Write(data)
+	if closeErr := tempFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tempFile.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tempFile.Name())
+		return err
+	}
+	atomic.AddUint64(&cache.stores, 1)
+	return nil
+}
+
+var httpCacheSummaryTemplate = "mode: %s, hits: %d, misses: %d, stores: %d, dir: %s"
+
+func (cache *diskHttpCache) Summary() string {
+	modeName, _ := CacheModeName(cache.mode)
+	return fmt.Sprintf(httpCacheSummaryTemplate, modeName,
+		atomic.LoadUint64(&cache.hits),
+		atomic.LoadUint64(&cache.misses),
+		atomic.LoadUint64(&cache.stores),
+		cache.dir)
+}
+
+// 解析Cache-Control头。结果值中的键都是小写的。
+func parseCacheControl(header http.Header) map[string]string {
+	directives := make(map[string]string)
+	for _, value := range header["Cache-Control"] {
+		for _, part := range strings.Split(value, ",") {
+			part = strings.TrimSpace(part)
+			if part == "" {
+				continue
+			}
+			name, arg := part, ""
+			if i := strings.Index(part, "="); i >= 0 {
+				name, arg = part[:i], strings.Trim(part[i+1:], "\" ")
+			}
+			directives[strings.ToLower(strings.TrimSpace(name))] = arg
+		}
+	}
+	return directives
+}
+
+// 判断缓存的响应是否仍然新鲜。
+func isFresh(req *http.Request, resp *http.Response) bool {
+	respDirectives := parseCacheControl(resp.Header)
+	if _, ok := respDirectives["no-cache"]; ok {
+		return false
+	}
+	storedAt, err := time.Parse(time.RFC3339Nano, resp.Header.Get(cacheStoredAtHeader))
+	if err != nil {
+		return false
+	}
+	var lifetime time.Duration
+	if maxAge, ok := respDirectives["max-age"]; ok {
+		seconds, err := strconv.ParseInt(maxAge, 10, 64)
+		if err != nil {
+			return false
+		}
+		lifetime = time.Duration(seconds) * time.Second
+	} else if expires := resp.Header.Get("Expires"); expires != "" {
+		expiresTime, err := http.ParseTime(expires)
+		if err != nil {
+			return false
+		}
+		date, err := http.ParseTime(resp.Header.Get("Date"))
+		if err != nil {
+			date = storedAt
+		}
+		lifetime = expiresTime.Sub(date)
+	} else {
+		return false
+	}
+	if maxAge, ok := parseCacheControl(req.Header)["max-age"]; ok {
+		seconds, err := strconv.ParseInt(maxAge, 10, 64)
+		if err == nil && time.Duration(seconds)*time.Second < lifetime {
+			lifetime = time.Duration(seconds) * time.Second
+		}
+	}
+	return time.Since(storedAt) < lifetime
+}
+
+// 创建带有缓存的网页下载器。它会先在缓存中查找响应，只有在缓存缺失时才会使用给定的网页下载器下载。
+func NewCachingPageDownloader(downloader PageDownloader, cache HttpCache) PageDownloader {
+	return &cachingPageDownloader{downloader: downloader, cache: cache}
+}
+
+// 带有缓存的网页下载器的实现类型。
+type cachingPageDownloader struct {
+	downloader PageDownloader // 被包装的网页下载器。
+	cache      HttpCache      // HTTP缓存。
+}
+
+func (dl *cachingPageDownloader) Id() uint32 {
+	return dl.downloader.Id()
+}
+
+func (dl *cachingPageDownloader) Download(req base.Request) (*base.Response, error) {
+	httpReq := req.HttpReq()
+	if httpReq == nil || httpReq.URL == nil {
+		return nil, errors.New("The HTTP request is invalid!")
+	}
+	httpResp, err := dl.cache.Get(httpReq)
+	if err != nil {
+		logger.Warnf("Read HTTP cache error: %s (url=%s)\n", err, httpReq.URL)
+	}
+	if httpResp != nil {
+		logger.Infof("Use the cached response (url=%s)... \n", httpReq.URL)
+		resp := base.NewResponseWithMeta(httpResp, req.Depth(), req.Meta().Copy())
+		if base.IsFailureStatus(httpResp.StatusCode) {
+			return resp, base.NewHttpStatusError(httpResp.StatusCode, httpReq.URL.String())
+		}
+		return resp, nil
+	}
+	if dl.cache.Mode() == CACHE_MODE_OFFLINE {
+		errMsg := fmt.Sprintf("No cached response in offline mode! (url=%s)", httpReq.URL)
+		return nil, errors.New(errMsg)
+	}
+	resp, err := dl.downloader.Download(req)
+	if resp != nil && resp.HttpResp() != nil {
+		if putErr := dl.cache.Put(resp.HttpResp()); putErr != nil {
+			logger.Warnf("Write HTTP cache error: %s (url=%s)\n", putErr, httpReq.URL)
+		}
+	}
+	return resp, err
+}
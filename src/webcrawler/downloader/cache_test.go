@@ -0,0 +1,76 @@
+package downloader
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	base "webcrawler/base"
+)
+
+// 发起下载并返回响应的主体。
+func download(t *testing.T, downloader PageDownloader, url string) (string, error) {
+	httpReq, _ := http.NewRequest("GET", url, nil)
+	resp, err := downloader.Download(*base.NewRequest(httpReq, 0))
+	if resp == nil {
+		return "", err
+	}
+	body, readErr := ioutil.ReadAll(resp.HttpResp().Body)
+	if readErr != nil {
+		t.Fatalf("Read body error: %s", readErr)
+	}
+	return string(body), err
+}
+
+func TestCachingPageDownloader(t *testing.T) {
+	var requestCount int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&requestCount, 1)
+		switch r.URL.Path {
+		case "/fresh":
+			w.Header().Set("Cache-Control", "max-age=3600")
+		case "/nostore":
+			w.Header().Set("Cache-Control", "no-store")
+		}
+		fmt.Fprintf(w, "response %d", n)
+	}))
+	defer server.Close()
+	dir := t.TempDir()
+
+	cache, err := NewDiskHttpCache(dir, CACHE_MODE_NORMAL)
+	if err != nil {
+		t.Fatalf("Create HTTP cache error: %s", err)
+	}
+	downloader := NewCachingPageDownloader(NewPageDownloader(nil), cache)
+	for _, path := range []string{"/fresh", "/nostore", "/plain"} {
+		first, err := download(t, downloader, server.URL+path)
+		if err != nil {
+			t.Fatalf("Download error: %s", err)
+		}
+		second, _ := download(t, downloader, server.URL+path)
+		if path == "/fresh" && first != second {
+			t.Errorf("The fresh response is not served from the cache! (%q != %q)", first, second)
+		}
+		if path != "/fresh" && first == second {
+			t.Errorf("The response of %s should not be served from the cache!", path)
+		}
+	}
+
+	// 在离线模式下，只有被存储过的响应才可用。
+	offlineCache, _ := NewDiskHttpCache(dir, CACHE_MODE_OFFLINE)
+	offline := NewCachingPageDownloader(NewPageDownloader(nil), offlineCache)
+	before := atomic.LoadInt32(&requestCount)
+	for _, path := range []string{"/fresh", "/plain"} {
+		if _, err := download(t, offline, server.URL+path); err != nil {
+			t.Errorf("Unexpected error for cached %s: %s", path, err)
+		}
+	}
+	if _, err := download(t, offline, server.URL+"/nostore"); err == nil {
+		t.Errorf("Expected an error for uncached response in offline mode, but got nil.")
+	}
+	if after := atomic.LoadInt32(&requestCount); after != before {
+		t.Errorf("The network is accessed in offline mode! (%d requests)", after-before)
+	}
+}
@@ -0,0 +1,171 @@
+package downloader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	base "webcrawler/base"
+)
+
+// 上下文中存放代理URL的键的类型。
+type proxyContextKey struct{}
+
+// 在上下文中设置代理URL。
+func WithProxy(ctx context.Context, proxyUrl *url.URL) context.Context {
+	return context.WithValue(ctx, proxyContextKey{}, proxyUrl)
+}
+
+// 获得请求的上下文中的代理URL。若没有设置，则结果值为nil，即不使用代理。
+// 它可以直接被用作http.Transport的Proxy字段的值。
+func ProxyFromContext(httpReq *http.Request) (*url.URL, error) {
+	proxyUrl, _ := httpReq.Context().Value(proxyContextKey{}).(*url.URL)
+	return proxyUrl, nil
+}
+
+// 请求装饰器的接口类型。它会在下载之前为请求添加请求头并选择代理。
+// 它的所有方法都是并发安全的。
+type RequestDecorator interface {
+	// 装饰请求。结果值是一个新的请求，其上下文中可能包含被选中的代理。
+	Decorate(req base.Request) (base.Request, error)
+	// 判断是否配置了代理。
+	HasProxies() bool
+	// 获取摘要信息。
+	Summary() string
+}
+
+// 创建请求装饰器。
+func NewRequestDecorator(args base.DecoratorArgs) (RequestDecorator, error) {
+	if err := args.Check(); err != nil {
+		return nil, err
+	}
+	decorator := &myRequestDecorator{
+		args:          args,
+		userAgents:    args.UserAgents(),
+		domainHeaders: args.DomainHeaders(),
+		stickyMap:     make(map[string]int),
+	}
+	// 先应用较短的域名，以使更具体的域名的请求头能够覆盖它们。
+	for domain := range decorator.domainHeaders {
+		decorator.domains = append(decorator.domains, domain)
+	}
+	sort.Slice(decorator.domains, func(i, j int) bool {
+		return len(decorator.domains[i]) < len(decorator.domains[j])
+	})
+	for _, proxy := range args.Proxies() {
+		proxyUrl, err := url.Parse(proxy)
+		if err != nil {
+			return nil, err
+		}
+		decorator.proxies = append(decorator.proxies, proxyUrl)
+	}
+	decorator.defaultHeaders = args.DefaultHeaders()
+	// 预先生成描述，以免在获取摘要信息时并发地修改它。
+	_ = decorator.args.String()
+	return decorator, nil
+}
+
+// 请求装饰器的实现类型。
+type myRequestDecorator struct {
+	args           base.DecoratorArgs           // 请求装饰参数。
+	defaultHeaders map[string]string            // 默认的请求头。
+	userAgents     []string                     // User-Agent的列表。
+	domainHeaders  map[string]map[string]string // 针对域名的请求头。
+	domains        []string                     // 按长度排序的域名的列表。
+	proxies        []*url.URL                   // 代理URL的列表。
+	uaIndex        uint64                       // 下一个User-Agent的序号。
+	proxyIndex     uint64                       // 下一个代理的序号。
+	stickyMap      map[string]int               // 主机名与代理序号的映射。
+	decorated      uint64                       // 已装饰的请求的数量。
+	mutex          sync.Mutex                   // 针对stickyMap的互斥锁。
+}
+
+func (decorator *myRequestDecorator) Decorate(req base.Request) (base.Request, error) {
+	httpReq := req.HttpReq()
+	if httpReq == nil || httpReq.URL == nil {
+		return req, errors.New("The HTTP request is invalid!")
+	}
+	host := strings.ToLower(httpReq.URL.Host)
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	header := httpReq.Header
+	if header == nil {
+		header = make(http.Header)
+		httpReq.Header = header
+	}
+	for name, value := range decorator.defaultHeaders {
+		if header.Get(name) == "" {
+			header.Set(name, value)
+		}
+	}
+	if len(decorator.userAgents) > 0 && header.Get("User-Agent") == "" {
+		i := atomic.AddUint64(&decorator.uaIndex, 1) - 1
+		header.Set("User-Agent", decorator.userAgents[i%uint64(len(decorator.userAgents))])
+	}
+	for _, domain := range decorator.domains {
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			for name, value := range decorator.domainHeaders[domain] {
+				header.Set(name, value)
+			}
+		}
+	}
+	atomic.AddUint64(&decorator.decorated, 1)
+	if len(decorator.proxies) == 0 {
+		return req, nil
+	}
+	proxyUrl := decorator.proxies[decorator.selectProxy(host)]
+	newHttpReq := httpReq.WithContext(WithProxy(httpReq.Context(), proxyUrl))
+	return *base.NewRequestWithMeta(newHttpReq, req.Depth(), req.Meta()), nil
+}
+
+// 为主机选择代理。结果值为代理的序号。
+func (decorator *myRequestDecorator) selectProxy(host string) int {
+	if decorator.args.ProxyPolicy() == base.PROXY_POLICY_STICKY_PER_HOST {
+		decorator.mutex.Lock()
+		defer decorator.mutex.Unlock()
+		if index, ok := decorator.stickyMap[host]; ok {
+			return index
+		}
+		i := atomic.AddUint64(&decorator.proxyIndex, 1) - 1
+		index := int(i % uint64(len(decorator.proxies)))
+		decorator.stickyMap[host] = index
+		return index
+	}
+	i := atomic.AddUint64(&decorator.proxyIndex, 1) - 1
+	return int(i % uint64(len(decorator.proxies)))
+}
+
+func (decorator *myRequestDecorator) HasProxies() bool {
+	return len(decorator.proxies) > 0
+}
+
+var decoratorSummaryTemplate = "args: %s, decorated: %d"
+
+func (decorator *myRequestDecorator) Summary() string {
+	return fmt.Sprintf(decoratorSummaryTemplate,
+		decorator.args.String(), atomic.LoadUint64(&decorator.decorated))
+}
+
+// 使HTTP客户端使用请求的上下文中的代理。
+// 若客户端的Transport不是*http.Transport类型，则不会做任何修改。
+func UseContextProxy(client *http.Client) {
+	var transport *http.Transport
+	switch t := client.Transport.(type) {
+	case nil:
+		transport = http.DefaultTransport.(*http.Transport).Clone()
+	case *http.Transport:
+		transport = t.Clone()
+	default:
+		logger.Warnf("Can not use the context proxy with transport of type %T!\n", t)
+		return
+	}
+	transport.Proxy = ProxyFromContext
+	client.Transport = transport
+}
@@ -0,0 +1,98 @@
+package downloader
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	base "webcrawler/base"
+)
+
+func decorate(t *testing.T, decorator RequestDecorator, rawUrl string) base.Request {
+	httpReq, _ := http.NewRequest("GET", rawUrl, nil)
+	req, err := decorator.Decorate(*base.NewRequest(httpReq, 0))
+	if err != nil {
+		t.Fatalf("Decorate error: %s", err)
+	}
+	return req
+}
+
+func TestRequestDecoratorHeaders(t *testing.T) {
+	args := base.NewDecoratorArgs(
+		map[string]string{"Accept-Language": "zh-CN", "X-Default": "1"},
+		[]string{"ua-1", "ua-2"},
+		map[string]map[string]string{
+			"example.com":     {"X-Default": "2", "X-Site": "example"},
+			"api.example.com": {"X-Site": "api"},
+		},
+		nil, base.PROXY_POLICY_ROUND_ROBIN)
+	decorator, err := NewRequestDecorator(args)
+	if err != nil {
+		t.Fatalf("Create decorator error: %s", err)
+	}
+	cases := []struct {
+		url, ua, def, site string
+	}{
+		{"http://www.example.com/", "ua-1", "2", "example"},
+		{"http://api.example.com/", "ua-2", "2", "api"},
+		{"http://other.com/", "ua-1", "1", ""},
+	}
+	for _, c := range cases {
+		req := decorate(t, decorator, c.url)
+		header := req.HttpReq().Header
+		if header.Get("User-Agent") != c.ua || header.Get("X-Default") != c.def ||
+			header.Get("X-Site") != c.site || header.Get("Accept-Language") != "zh-CN" {
+			t.Errorf("Unexpected headers %v for %s.", header, c.url)
+		}
+	}
+}
+
+func TestRequestDecoratorProxies(t *testing.T) {
+	// 模拟的代理会在响应中返回自身的名称以及被代理的URL。
+	newProxy := func(name string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintf(w, "%s %s", name, r.URL)
+		}))
+	}
+	proxyA, proxyB := newProxy("A"), newProxy("B")
+	defer proxyA.Close()
+	defer proxyB.Close()
+	proxies := []string{proxyA.URL, proxyB.URL}
+
+	roundRobin, _ := NewRequestDecorator(
+		base.NewDecoratorArgs(nil, nil, nil, proxies, base.PROXY_POLICY_ROUND_ROBIN))
+	client := &http.Client{}
+	UseContextProxy(client)
+	downloader := NewPageDownloader(client)
+	var bodies []string
+	for i := 0; i < 3; i++ {
+		req := decorate(t, roundRobin, "http://target.example.com/page")
+		resp, err := downloader.Download(req)
+		if err != nil {
+			t.Fatalf("Download error: %s", err)
+		}
+		body, _ := ioutil.ReadAll(resp.HttpResp().Body)
+		bodies = append(bodies, string(body))
+	}
+	expected := "[A http://target.example.com/page B http://target.example.com/page A http://target.example.com/page]"
+	if fmt.Sprint(bodies) != expected {
+		t.Fatalf("Unexpected responses %v, expected %s.", bodies, expected)
+	}
+
+	sticky, _ := NewRequestDecorator(
+		base.NewDecoratorArgs(nil, nil, nil, proxies, base.PROXY_POLICY_STICKY_PER_HOST))
+	proxyOf := func(rawUrl string) string {
+		req := decorate(t, sticky, rawUrl)
+		proxyUrl, _ := ProxyFromContext(req.HttpReq())
+		return proxyUrl.String()
+	}
+	first := proxyOf("http://a.example.com/1")
+	second := proxyOf("http://b.example.com/1")
+	if first == second {
+		t.Fatalf("Different hosts should be assigned different proxies at first!")
+	}
+	if proxyOf("http://a.example.com/2") != first || proxyOf("http://b.example.com/2") != second {
+		t.Fatalf("The proxy of a host should be sticky!")
+	}
+}
@@ -0,0 +1,58 @@
+package downloader
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 创建礼貌的HTTP传输。它会保证针对同一主机的相邻两个请求的发出时间至少间隔delay。
+// 该传输应被所有网页下载器的HTTP客户端共用，否则无法起到限速的作用。
+// 参数transport为nil时会使用http.DefaultTransport。
+func NewPoliteTransport(transport http.RoundTripper, delay time.Duration) http.RoundTripper {
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	return &politeTransport{
+		transport: transport,
+		delay:     delay,
+		nextMap:   make(map[string]time.Time),
+	}
+}
+
+// 礼貌的HTTP传输的实现类型。
+type politeTransport struct {
+	transport http.RoundTripper    // 被包装的HTTP传输。
+	delay     time.Duration        // 针对同一主机的请求间隔。
+	nextMap   map[string]time.Time // 主机与其下一个请求的最早发出时间的映射。
+	mutex     sync.Mutex           // 互斥锁。
+}
+
+func (pt *politeTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if pt.delay > 0 && req.URL != nil {
+		if wait := pt.reserve(strings.ToLower(req.URL.Host)); wait > 0 {
+			timer := time.NewTimer(wait)
+			select {
+			case <-timer.C:
+			case <-req.Context().Done():
+				timer.Stop()
+				return nil, req.Context().Err()
+			}
+		}
+	}
+	return pt.transport.RoundTrip(req)
+}
+
+// 为针对给定主机的请求预约发出时间，并返回需要等待的时长。
+func (pt *politeTransport) reserve(host string) time.Duration {
+	pt.mutex.Lock()
+	defer pt.mutex.Unlock()
+	now := time.Now()
+	next := pt.nextMap[host]
+	if next.Before(now) {
+		next = now
+	}
+	pt.nextMap[host] = next.Add(pt.delay)
+	return next.Sub(now)
+}
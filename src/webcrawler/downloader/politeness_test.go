@@ -0,0 +1,51 @@
+package downloader
+
+import (
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+// 记录请求发出时间的HTTP传输。
+type timingTransport struct {
+	times map[string][]time.Time
+	mutex sync.Mutex
+}
+
+func (tt *timingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	tt.mutex.Lock()
+	defer tt.mutex.Unlock()
+	tt.times[req.URL.Host] = append(tt.times[req.URL.Host], time.Now())
+	return &http.Response{StatusCode: 200, Request: req}, nil
+}
+
+func TestPoliteTransport(t *testing.T) {
+	recorder := &timingTransport{times: make(map[string][]time.Time)}
+	delay := 30 * time.Millisecond
+	client := &http.Client{Transport: NewPoliteTransport(recorder, delay)}
+	var wg sync.WaitGroup
+	for _, url := range []string{"http://a.com/1", "http://a.com/2", "http://a.com/3", "http://b.com/1"} {
+		wg.Add(1)
+		go func(url string) {
+			defer wg.Done()
+			if _, err := client.Get(url); err != nil {
+				t.Errorf("Get error: %s\n", err)
+			}
+		}(url)
+	}
+	wg.Wait()
+	times := recorder.times["a.com"]
+	if len(times) != 3 {
+		t.Fatalf("The number of requests to a.com should be 3, but %d!\n", len(times))
+	}
+	for i := 1; i < len(times); i++ {
+		// 允许少量的计时误差。
+		if gap := times[i].Sub(times[i-1]); gap < delay-5*time.Millisecond {
+			t.Errorf("The gap between requests should be at least %s, but %s!\n", delay, gap)
+		}
+	}
+	if len(recorder.times["b.com"]) != 1 {
+		t.Errorf("The request to b.com should be sent!\n")
+	}
+}
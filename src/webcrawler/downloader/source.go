@@ -0,0 +1,116 @@
+package downloader
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	base "webcrawler/base"
+)
+
+// 响应源的接口类型。在重放模式下，它会代替网页下载器为调度器提供响应。
+// 它的所有方法都是并发安全的。
+type ResponseSource interface {
+	// 获得下一个响应。若已没有更多的响应，则返回io.EOF。
+	// 返回其他错误之后，仍可以继续调用该方法以获得后续的响应。
+	Next() (*base.Response, error)
+	// 关闭响应源。
+	Close() error
+	// 获取摘要信息。
+	Summary() string
+}
+
+// 创建基于目录的响应源。该目录应是一个网站的镜像：
+// 其下的第一级目录的名称代表主机名，其余的相对路径则代表URL的路径。
+// 例如，文件dir/example.com/a/b.html会被当作URL为http://example.com/a/b.html的响应的主体。
+// 文件会按照路径的字典顺序被依次提供，所有响应的深度都为0。
+func NewDirResponseSource(dir string) (ResponseSource, error) {
+	var paths []string
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.Mode().IsRegular() {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+	return &dirResponseSource{dir: dir, paths: paths}, nil
+}
+
+// 基于目录的响应源的实现类型。
+type dirResponseSource struct {
+	dir   string     // 镜像目录。
+	paths []string   // 文件路径的列表。
+	next  int        // 下一个文件在列表中的位置。
+	mutex sync.Mutex // 互斥锁。
+}
+
+func (source *dirResponseSource) Next() (*base.Response, error) {
+	source.mutex.Lock()
+	if source.next >= len(source.paths) {
+		source.mutex.Unlock()
+		return nil, io.EOF
+	}
+	path := source.paths[source.next]
+	source.next++
+	source.mutex.Unlock()
+	rel, err := filepath.Rel(source.dir, path)
+	if err != nil {
+		return nil, err
+	}
+	rel = filepath.ToSlash(rel)
+	httpReq, err := http.NewRequest("GET", "http://"+rel, nil)
+	if err != nil {
+		return nil, err
+	}
+	if httpReq.URL.Path == "" {
+		errMsg := fmt.Sprintf("The file '%s' is not in a host directory!", path)
+		return nil, errors.New(errMsg)
+	}
+	body, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	header := make(http.Header)
+	contentType := mime.TypeByExtension(filepath.Ext(path))
+	if contentType == "" {
+		contentType = http.DetectContentType(body)
+	}
+	header.Set("Content-Type", contentType)
+	header.Set("Content-Length", strconv.Itoa(len(body)))
+	httpResp := &http.Response{
+		Status:        "200 OK",
+		StatusCode:    200,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          ioutil.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       httpReq,
+	}
+	return base.NewResponse(httpResp, 0), nil
+}
+
+func (source *dirResponseSource) Close() error {
+	return nil
+}
+
+func (source *dirResponseSource) Summary() string {
+	source.mutex.Lock()
+	defer source.mutex.Unlock()
+	return fmt.Sprintf("dir: %s, files: %d/%d", source.dir, source.next, len(source.paths))
+}
@@ -0,0 +1,134 @@
+package frontier
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+	base "webcrawler/base"
+)
+
+// 爬取边界客户端的接口类型。工作方（即调度器）通过它与爬取边界服务端交互。
+// 它的所有方法都是并发安全的。
+type Client interface {
+	// 获得工作方的序号。
+	WorkerIndex() uint32
+	// 获得工作方的数量。
+	WorkerNumber() uint32
+	// 放入请求。结果值代表被接受的请求的数量。
+	Push(reqs []*base.Request) (uint32, error)
+	// 取出最多max个属于当前工作方的请求。
+	Pull(max uint32) ([]*base.Request, error)
+	// 获得最近一次放入或取出请求时爬取边界中待取出的请求的总数。
+	Pending() uint32
+	// 关闭客户端。
+	Close() error
+}
+
+// 连接爬取边界服务端并创建客户端。
+// 参数workerIndex代表工作方的序号，它应小于服务端配置的工作方的数量。
+// 参数timeout代表连接以及每次交互的超时时间。
+func Dial(addr string, workerIndex uint32, timeout time.Duration) (Client, error) {
+	conn, err := net.DialTimeout("tcp", addr, timeout)
+	if err != nil {
+		return nil, err
+	}
+	client := &myClient{
+		conn:        conn,
+		decoder:     json.NewDecoder(bufio.NewReader(conn)),
+		encoder:     json.NewEncoder(conn),
+		timeout:     timeout,
+		workerIndex: workerIndex,
+	}
+	resp, err := client.call(message{Op: OP_HELLO, Worker: workerIndex})
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	client.workerNumber = resp.WorkerNumber
+	return client, nil
+}
+
+// 爬取边界客户端的实现类型。
+type myClient struct {
+	conn         net.Conn      // 连接。
+	decoder      *json.Decoder // 响应消息的解码器。
+	encoder      *json.Encoder // 请求消息的编码器。
+	timeout      time.Duration // 每次交互的超时时间。
+	workerIndex  uint32        // 工作方的序号。
+	workerNumber uint32        // 工作方的数量。
+	pending      uint32        // 最近一次放入或取出请求时爬取边界中待取出的请求的总数。
+	mutex        sync.Mutex    // 保证交互过程不被打断的互斥锁。
+}
+
+// 发送请求消息并接收响应消息。
+func (client *myClient) call(req message) (message, error) {
+	client.mutex.Lock()
+	defer client.mutex.Unlock()
+	var resp message
+	if client.timeout > 0 {
+		client.conn.SetDeadline(time.Now().Add(client.timeout))
+	}
+	if err := client.encoder.Encode(req); err != nil {
+		return resp, err
+	}
+	if err := client.decoder.Decode(&resp); err != nil {
+		return resp, err
+	}
+	if resp.Err != "" {
+		return resp, errors.New(resp.Err)
+	}
+	return resp, nil
+}
+
+func (client *myClient) WorkerIndex() uint32 {
+	return client.workerIndex
+}
+
+func (client *myClient) WorkerNumber() uint32 {
+	return client.workerNumber
+}
+
+func (client *myClient) Push(reqs []*base.Request) (uint32, error) {
+	freqs := make([]Request, 0, len(reqs))
+	for _, req := range reqs {
+		freq, err := FromRequest(req)
+		if err != nil {
+			return 0, err
+		}
+		freqs = append(freqs, freq)
+	}
+	resp, err := client.call(message{Op: OP_PUSH, Requests: freqs})
+	if err == nil {
+		atomic.StoreUint32(&client.pending, resp.Pending)
+	}
+	return resp.Accepted, err
+}
+
+func (client *myClient) Pull(max uint32) ([]*base.Request, error) {
+	resp, err := client.call(message{Op: OP_PULL, Worker: client.workerIndex, Max: max})
+	if err != nil {
+		return nil, err
+	}
+	atomic.StoreUint32(&client.pending, resp.Pending)
+	reqs := make([]*base.Request, 0, len(resp.Requests))
+	for _, freq := range resp.Requests {
+		req, err := freq.ToRequest()
+		if err != nil {
+			return reqs, err
+		}
+		reqs = append(reqs, req)
+	}
+	return reqs, nil
+}
+
+func (client *myClient) Pending() uint32 {
+	return atomic.LoadUint32(&client.pending)
+}
+
+func (client *myClient) Close() error {
+	return client.conn.Close()
+}
@@ -0,0 +1,178 @@
+package frontier
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	base "webcrawler/base"
+)
+
+// 在调度器之间传输的请求。
+type Request struct {
+	Method string      `json:"method"`           // HTTP方法。
+	Url    string      `json:"url"`              // URL。
+	Header http.Header `json:"header,omitempty"` // 请求头。
+	Depth  uint32      `json:"depth"`            // 请求的深度。
+	Meta   base.Meta   `json:"meta"`             // 元数据。经过传输后，数值会变为float64类型。
+	Body   []byte      `json:"body,omitempty"`   // 请求体。
+}
+
+// 根据请求创建可传输的请求。
+func FromRequest(req *base.Request) (Request, error) {
+	httpReq := req.HttpReq()
+	if httpReq == nil || httpReq.URL == nil {
+		return Request{}, errors.New("The HTTP request is invalid!")
+	}
+	method := httpReq.Method
+	if method == "" {
+		method = "GET"
+	}
+	return Request{
+		Method: method,
+		Url:    httpReq.URL.String(),
+		Header: httpReq.Header,
+		Depth:  req.Depth(),
+		Meta:   req.Meta(),
+		Body:   req.Body(),
+	}, nil
+}
+
+// 把可传输的请求还原为请求。
+func (freq Request) ToRequest() (*base.Request, error) {
+	httpReq, err := http.NewRequest(freq.Method, freq.Url, nil)
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range freq.Header {
+		httpReq.Header[key] = append([]string(nil), values...)
+	}
+	return base.NewRequestWithBody(httpReq, freq.Depth, freq.Body, freq.Meta), nil
+}
+
+// 根据主机名计算其所属的工作方的序号。同一个主机总会被分配给同一个工作方。
+func Partition(host string, workerNumber uint32) uint32 {
+	h := fnv.New32a()
+	h.Write([]byte(strings.ToLower(host)))
+	return h.Sum32() % workerNumber
+}
+
+// 爬取边界的接口类型。它负责对请求去重，并把请求按照主机分配给各个工作方。
+// 去重时会同时考虑HTTP方法、URL以及请求体。
+type Frontier interface {
+	// 放入请求。重复的请求会被忽略。结果值代表被接受的请求的数量。
+	// URL无法解析或没有主机的请求会被跳过，但不影响同一批中的其他请求。
+	// 若有请求被跳过，则错误值会列出它们的URL。
+	Push(reqs []Request) (accepted uint32, err error)
+	// 为给定序号的工作方取出最多max个请求。
+	Pull(workerIndex uint32, max uint32) ([]Request, error)
+	// 获得工作方的数量。
+	WorkerNumber() uint32
+	// 获得待取出的请求的总数。
+	Len() uint32
+	// 获取摘要信息。
+	Summary() string
+}
+
+// 创建爬取边界。参数workerNumber代表工作方的数量。
+func NewFrontier(workerNumber uint32) (Frontier, error) {
+	if workerNumber == 0 {
+		errMsg := fmt.Sprintf("The frontier can not be initialized! (workerNumber=%d)\n", workerNumber)
+		return nil, errors.New(errMsg)
+	}
+	return &myFrontier{
+		queues:  make([][]Request, workerNumber),
+		seenMap: make(map[string]bool),
+	}, nil
+}
+
+// 爬取边界的实现类型。
+type myFrontier struct {
+	queues   [][]Request     // 各个工作方的请求队列。
+	seenMap  map[string]bool // 已接受过的请求的键的字典。
+	accepted uint64          // 已接受的请求的数量。
+	repeated uint64          // 因重复而被忽略的请求的数量。
+	invalid  uint64          // 因无效而被跳过的请求的数量。
+	pulled   uint64          // 已被取出的请求的数量。
+	mutex    sync.Mutex      // 互斥锁。
+}
+
+func (frontier *myFrontier) Push(reqs []Request) (uint32, error) {
+	frontier.mutex.Lock()
+	defer frontier.mutex.Unlock()
+	var accepted uint32
+	var invalidUrls []string
+	for _, req := range reqs {
+		reqUrl, err := url.Parse(req.Url)
+		if err != nil || reqUrl.Host == "" {
+			frontier.invalid++
+			invalidUrls = append(invalidUrls, fmt.Sprintf("%q", req.Url))
+			continue
+		}
+		key := base.DedupeKey(strings.ToUpper(req.Method), reqUrl.String(), req.Body)
+		if frontier.seenMap[key] {
+			frontier.repeated++
+			continue
+		}
+		frontier.seenMap[key] = true
+		index := Partition(reqUrl.Host, uint32(len(frontier.queues)))
+		frontier.queues[index] = append(frontier.queues[index], req)
+		frontier.accepted++
+		accepted++
+	}
+	if len(invalidUrls) > 0 {
+		errMsg := fmt.Sprintf("Skip %d invalid request(s) without a parsable url or host: %s",
+			len(invalidUrls), strings.Join(invalidUrls, ", "))
+		return accepted, errors.New(errMsg)
+	}
+	return accepted, nil
+}
+
+func (frontier *myFrontier) Pull(workerIndex uint32, max uint32) ([]Request, error) {
+	frontier.mutex.Lock()
+	defer frontier.mutex.Unlock()
+	if workerIndex >= uint32(len(frontier.queues)) {
+		errMsg := fmt.Sprintf("Illegal worker index %d! (workerNumber=%d)", workerIndex, len(frontier.queues))
+		return nil, errors.New(errMsg)
+	}
+	queue := frontier.queues[workerIndex]
+	n := int(max)
+	if n > len(queue) {
+		n = len(queue)
+	}
+	result := make([]Request, n)
+	copy(result, queue[:n])
+	frontier.queues[workerIndex] = queue[n:]
+	frontier.pulled += uint64(n)
+	return result, nil
+}
+
+func (frontier *myFrontier) WorkerNumber() uint32 {
+	return uint32(len(frontier.queues))
+}
+
+func (frontier *myFrontier) Len() uint32 {
+	frontier.mutex.Lock()
+	defer frontier.mutex.Unlock()
+	var total int
+	for _, queue := range frontier.queues {
+		total += len(queue)
+	}
+	return uint32(total)
+}
+
+var frontierSummaryTemplate = "workerNumber: %d, accepted: %d, repeated: %d, invalid: %d, pulled: %d, queues: %v"
+
+func (frontier *myFrontier) Summary() string {
+	frontier.mutex.Lock()
+	defer frontier.mutex.Unlock()
+	lens := make([]int, len(frontier.queues))
+	for i, queue := range frontier.queues {
+		lens[i] = len(queue)
+	}
+	return fmt.Sprintf(frontierSummaryTemplate,
+		len(frontier.queues), frontier.accepted, frontier.repeated, frontier.invalid, frontier.pulled, lens)
+}
@@ -0,0 +1,150 @@
+package frontier
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+	base "webcrawler/base"
+)
+
+func newTestRequest(t *testing.T, rawUrl string) *base.Request {
+	httpReq, err := http.NewRequest("GET", rawUrl, nil)
+	if err != nil {
+		t.Fatalf("Create request error: %s", err)
+	}
+	return base.NewRequest(httpReq, 1)
+}
+
+func TestFrontierOverTcp(t *testing.T) {
+	f, err := NewFrontier(2)
+	if err != nil {
+		t.Fatalf("Create frontier error: %s", err)
+	}
+	server := NewServer(f)
+	if err := server.Listen("127.0.0.1:0"); err != nil {
+		t.Fatalf("Listen error: %s", err)
+	}
+	defer server.Close()
+	addr := server.Addr().String()
+
+	if _, err := Dial(addr, 2, time.Second); err == nil {
+		t.Fatalf("Expected an error for illegal worker index, but got nil.")
+	}
+	clients := make([]Client, 2)
+	for i := range clients {
+		client, err := Dial(addr, uint32(i), time.Second)
+		if err != nil {
+			t.Fatalf("Dial error: %s", err)
+		}
+		defer client.Close()
+		if client.WorkerNumber() != 2 {
+			t.Fatalf("Unexpected worker number %d.", client.WorkerNumber())
+		}
+		clients[i] = client
+	}
+
+	hosts := []string{"a.example.com", "b.example.com", "c.example.com", "d.example.com"}
+	var reqs []*base.Request
+	for _, host := range hosts {
+		reqs = append(reqs, newTestRequest(t, "http://"+host+"/"))
+		reqs = append(reqs, newTestRequest(t, "http://"+host+"/page"))
+	}
+	accepted, err := clients[0].Push(reqs)
+	if err != nil {
+		t.Fatalf("Push error: %s", err)
+	}
+	if int(accepted) != len(reqs) {
+		t.Fatalf("Unexpected accepted number %d, expected %d.", accepted, len(reqs))
+	}
+	// 重复的请求应被忽略，即使它来自其他的工作方。
+	accepted, err = clients[1].Push(reqs[:3])
+	if err != nil {
+		t.Fatalf("Push error: %s", err)
+	}
+	if accepted != 0 {
+		t.Fatalf("Unexpected accepted number %d for repeated requests.", accepted)
+	}
+
+	// 请求体不同的POST请求不应被视为重复。
+	for _, body := range []string{"page=1", "page=2", "page=1"} {
+		httpReq, _ := http.NewRequest("POST", "http://a.example.com/api", strings.NewReader(body))
+		httpReq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		reqs = append(reqs, base.NewRequest(httpReq, 1))
+	}
+	accepted, err = clients[1].Push(reqs[len(reqs)-3:])
+	if err != nil {
+		t.Fatalf("Push error: %s", err)
+	}
+	if accepted != 2 {
+		t.Fatalf("Unexpected accepted number %d for POST requests, expected 2.", accepted)
+	}
+	reqs = reqs[:len(reqs)-1]
+
+	var total int
+	for i, client := range clients {
+		pulled, err := client.Pull(100)
+		if err != nil {
+			t.Fatalf("Pull error: %s", err)
+		}
+		for _, req := range pulled {
+			host := req.HttpReq().URL.Host
+			if index := Partition(host, 2); index != uint32(i) {
+				t.Errorf("The request for host %s is pulled by worker %d, expected %d.",
+					host, i, index)
+			}
+			if req.Depth() != 1 {
+				t.Errorf("Unexpected depth %d.", req.Depth())
+			}
+			if req.HttpReq().Method != "POST" {
+				continue
+			}
+			// POST请求的请求头和请求体都应被原样还原。
+			contentType := req.HttpReq().Header.Get("Content-Type")
+			if contentType != "application/x-www-form-urlencoded" {
+				t.Errorf("Unexpected content type %q of POST request.", contentType)
+			}
+			if body := string(req.Body()); body != "page=1" && body != "page=2" {
+				t.Errorf("Unexpected body %q of POST request.", body)
+			}
+		}
+		total += len(pulled)
+	}
+	if total != len(reqs) {
+		t.Fatalf("Unexpected pulled number %d, expected %d.", total, len(reqs))
+	}
+	if f.Len() != 0 {
+		t.Fatalf("Unexpected frontier length %d.", f.Len())
+	}
+
+	// 无效的请求会被跳过，但同一批中的其他请求仍会被接受。
+	accepted, err = f.Push([]Request{
+		{Method: "GET", Url: "http://f.example.com/"},
+		{Method: "GET", Url: "/no-host"},
+		{Method: "GET", Url: "http://%zz/"},
+		{Method: "GET", Url: "http://f.example.com/next"},
+	})
+	if err == nil || !strings.Contains(err.Error(), "/no-host") {
+		t.Fatalf("Expected an error for invalid requests, but got %v.", err)
+	}
+	if accepted != 2 || f.Len() != 2 {
+		t.Fatalf("Unexpected accepted number %d (length=%d), expected 2.", accepted, f.Len())
+	}
+	f.Pull(Partition("f.example.com", 2), 100)
+
+	// 客户端应得知爬取边界中待取出的请求的总数，即使这些请求属于其他工作方。
+	if _, err := clients[0].Push([]*base.Request{newTestRequest(t, "http://e.example.com/")}); err != nil {
+		t.Fatalf("Push error: %s", err)
+	}
+	if pending := clients[0].Pending(); pending != 1 {
+		t.Fatalf("Unexpected pending number %d, expected 1.", pending)
+	}
+
+	// 关闭服务端时，已建立的连接也应被关闭。
+	if !server.Close() {
+		t.Fatalf("The server should be closed.")
+	}
+	if _, err := clients[1].Pull(1); err == nil {
+		t.Fatalf("Expected an error after the server is closed, but got nil.")
+	}
+}
@@ -0,0 +1,21 @@
+package frontier
+
+// 操作的代号。
+const (
+	OP_HELLO = "hello" // 握手。工作方借此告知自己的序号并获得工作方的数量。
+	OP_PUSH  = "push"  // 放入请求。
+	OP_PULL  = "pull"  // 取出请求。
+)
+
+// 协议消息。每条消息都是一个以换行符结尾的JSON对象。
+// 客户端每发送一条请求消息，服务端都会回复一条响应消息。
+type message struct {
+	Op           string    `json:"op,omitempty"`           // 操作的代号。
+	Worker       uint32    `json:"worker,omitempty"`       // 工作方的序号。
+	Max          uint32    `json:"max,omitempty"`          // 最多取出的请求的数量。
+	Requests     []Request `json:"requests,omitempty"`     // 请求的列表。
+	Accepted     uint32    `json:"accepted,omitempty"`     // 被接受的请求的数量。
+	WorkerNumber uint32    `json:"workerNumber,omitempty"` // 工作方的数量。
+	Pending      uint32    `json:"pending,omitempty"`      // 爬取边界中待取出的请求的总数。
+	Err          string    `json:"err,omitempty"`          // 错误提示信息。
+}
@@ -0,0 +1,165 @@
+package frontier
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"logging"
+	"net"
+	"sync"
+	base "webcrawler/base"
+)
+
+// 日志记录器。
+var logger logging.Logger = base.NewLogger()
+
+// 爬取边界服务端的接口类型。它通过TCP协议对外提供爬取边界的功能。
+type Server interface {
+	// 在给定的地址上开始监听。该方法不会阻塞。
+	Listen(addr string) error
+	// 获得监听的地址。
+	Addr() net.Addr
+	// 关闭服务端。已建立的连接也会被关闭，该方法会等到处理它们的goroutine都退出之后再返回。
+	Close() bool
+	// 获得爬取边界。
+	Frontier() Frontier
+}
+
+// 创建爬取边界服务端。
+func NewServer(frontier Frontier) Server {
+	return &myServer{frontier: frontier, conns: make(map[net.Conn]bool)}
+}
+
+// 爬取边界服务端的实现类型。
+type myServer struct {
+	frontier Frontier          // 爬取边界。
+	listener net.Listener      // 监听器。
+	conns    map[net.Conn]bool // 已建立的连接的集合。
+	wg       sync.WaitGroup    // 用于等待接受连接和处理连接的goroutine退出。
+	mutex    sync.Mutex        // 互斥锁。
+}
+
+func (server *myServer) Listen(addr string) error {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+	if server.listener != nil {
+		return errors.New("The server has been listening!")
+	}
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	server.listener = listener
+	server.wg.Add(1)
+	go func() {
+		defer server.wg.Done()
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			if !server.track(conn) {
+				conn.Close()
+				return
+			}
+			go server.serve(conn)
+		}
+	}()
+	return nil
+}
+
+// 登记连接。若服务端已关闭，则结果值为false。
+func (server *myServer) track(conn net.Conn) bool {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+	if server.listener == nil {
+		return false
+	}
+	server.conns[conn] = true
+	server.wg.Add(1)
+	return true
+}
+
+// 处理连接。连接在被处理之前应已被登记。
+func (server *myServer) serve(conn net.Conn) {
+	defer func() {
+		conn.Close()
+		server.mutex.Lock()
+		delete(server.conns, conn)
+		server.mutex.Unlock()
+		server.wg.Done()
+	}()
+	decoder := json.NewDecoder(bufio.NewReader(conn))
+	encoder := json.NewEncoder(conn)
+	for {
+		var req message
+		if err := decoder.Decode(&req); err != nil {
+			return
+		}
+		resp := server.handle(req)
+		if err := encoder.Encode(resp); err != nil {
+			logger.Warnf("Send response error: %s (remoteAddr=%s)\n", err, conn.RemoteAddr())
+			return
+		}
+	}
+}
+
+// 处理请求消息并生成响应消息。
+func (server *myServer) handle(req message) message {
+	var resp message
+	workerNumber := server.frontier.WorkerNumber()
+	switch req.Op {
+	case OP_HELLO:
+		if req.Worker >= workerNumber {
+			resp.Err = fmt.Sprintf("Illegal worker index %d! (workerNumber=%d)", req.Worker, workerNumber)
+		}
+		resp.WorkerNumber = workerNumber
+	case OP_PUSH:
+		accepted, err := server.frontier.Push(req.Requests)
+		resp.Accepted = accepted
+		resp.Pending = server.frontier.Len()
+		if err != nil {
+			resp.Err = err.Error()
+		}
+	case OP_PULL:
+		reqs, err := server.frontier.Pull(req.Worker, req.Max)
+		resp.Requests = reqs
+		resp.Pending = server.frontier.Len()
+		if err != nil {
+			resp.Err = err.Error()
+		}
+	default:
+		resp.Err = fmt.Sprintf("Unsupported operation '%s'!", req.Op)
+	}
+	return resp
+}
+
+func (server *myServer) Addr() net.Addr {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+	if server.listener == nil {
+		return nil
+	}
+	return server.listener.Addr()
+}
+
+func (server *myServer) Close() bool {
+	server.mutex.Lock()
+	if server.listener == nil {
+		server.mutex.Unlock()
+		return false
+	}
+	server.listener.Close()
+	server.listener = nil
+	for conn := range server.conns {
+		conn.Close()
+	}
+	server.mutex.Unlock()
+	server.wg.Wait()
+	return true
+}
+
+func (server *myServer) Frontier() Frontier {
+	return server.frontier
+}
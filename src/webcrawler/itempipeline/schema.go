@@ -0,0 +1,274 @@
+package itemproc
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	base "webcrawler/base"
+)
+
+// 字段种类。
+type FieldKind uint8
+
+// 字段种类常量。
+const (
+	FIELD_KIND_ANY    FieldKind = 0 // 任意种类。
+	FIELD_KIND_STRING FieldKind = 1 // 字符串。
+	FIELD_KIND_INT    FieldKind = 2 // 整数。
+	FIELD_KIND_FLOAT  FieldKind = 3 // 浮点数。整数也会被接受。
+	FIELD_KIND_BOOL   FieldKind = 4 // 布尔值。
+	FIELD_KIND_SLICE  FieldKind = 5 // 切片或数组。
+	FIELD_KIND_MAP    FieldKind = 6 // 字典。
+)
+
+// 表示字段种类与其名称之间的映射关系的字典。
+var fieldKindNameMap = map[FieldKind]string{
+	FIELD_KIND_ANY:    "any",
+	FIELD_KIND_STRING: "string",
+	FIELD_KIND_INT:    "int",
+	FIELD_KIND_FLOAT:  "float",
+	FIELD_KIND_BOOL:   "bool",
+	FIELD_KIND_SLICE:  "slice",
+	FIELD_KIND_MAP:    "map",
+}
+
+// 被用来校验字段值的函数类型。
+type ValidateField func(value interface{}) error
+
+// 字段规格。
+type FieldSpec struct {
+	name       string          // 字段名称。
+	kind       FieldKind       // 字段种类。
+	required   bool            // 是否必须存在。
+	validators []ValidateField // 字段校验函数的列表。
+}
+
+// 创建字段规格。
+func NewFieldSpec(
+	name string,
+	kind FieldKind,
+	required bool,
+	validators ...ValidateField) FieldSpec {
+	return FieldSpec{
+		name:       name,
+		kind:       kind,
+		required:   required,
+		validators: validators,
+	}
+}
+
+// 获得字段名称。
+func (spec FieldSpec) Name() string {
+	return spec.name
+}
+
+// 获得字段种类。
+func (spec FieldSpec) Kind() FieldKind {
+	return spec.kind
+}
+
+// 判断字段是否必须存在。
+func (spec FieldSpec) Required() bool {
+	return spec.required
+}
+
+// 字段错误。
+type FieldError struct {
+	field  string // 字段名称。
+	errMsg string // 错误提示信息。
+}
+
+// 创建字段错误。
+func NewFieldError(field string, errMsg string) *FieldError {
+	return &FieldError{field: field, errMsg: errMsg}
+}
+
+// 获得字段名称。
+func (fe *FieldError) Field() string {
+	return fe.field
+}
+
+func (fe *FieldError) Error() string {
+	return fmt.Sprintf("Invalid item field '%s': %s", fe.field, fe.errMsg)
+}
+
+// 条目模式的接口类型。
+type ItemSchema interface {
+	// 校验条目。结果值中的每个元素都是*FieldError类型的。
+	// 若结果值的长度为0，则说明条目通过了校验。
+	Validate(item base.Item) []error
+	// 获得字段规格的列表。
+	Fields() []FieldSpec
+	// 获得条目模式的字符串表现形式。
+	String() string
+}
+
+// 创建条目模式。
+func NewItemSchema(fields ...FieldSpec) (ItemSchema, error) {
+	if len(fields) == 0 {
+		return nil, errors.New("The field spec list is empty!")
+	}
+	names := make(map[string]bool)
+	for i, spec := range fields {
+		if spec.name == "" {
+			return nil, errors.New(fmt.Sprintf("The name of field spec [%d] is empty!", i))
+		}
+		if names[spec.name] {
+			return nil, errors.New(fmt.Sprintf("Repeated field name '%s'!", spec.name))
+		}
+		if _, ok := fieldKindNameMap[spec.kind]; !ok {
+			return nil, errors.New(fmt.Sprintf("Unsupported kind %d of field '%s'!", spec.kind, spec.name))
+		}
+		names[spec.name] = true
+	}
+	innerFields := make([]FieldSpec, len(fields))
+	copy(innerFields, fields)
+	return &myItemSchema{fields: innerFields, description: describeFields(innerFields)}, nil
+}
+
+// 生成字段规格列表的描述。
+func describeFields(fields []FieldSpec) string {
+	var buffer bytes.Buffer
+	buffer.WriteString("{ ")
+	for i, spec := range fields {
+		if i > 0 {
+			buffer.WriteString(", ")
+		}
+		buffer.WriteString(spec.name)
+		buffer.WriteString(": ")
+		buffer.WriteString(fieldKindNameMap[spec.kind])
+		if spec.required {
+			buffer.WriteString("!")
+		}
+	}
+	buffer.WriteString(" }")
+	return buffer.String()
+}
+
+// 条目模式的实现类型。
+type myItemSchema struct {
+	fields      []FieldSpec // 字段规格的列表。
+	description string      // 描述。在创建时生成。
+}
+
+func (schema *myItemSchema) Validate(item base.Item) []error {
+	errs := make([]error, 0)
+	for _, spec := range schema.fields {
+		value, ok := item[spec.name]
+		if !ok || value == nil {
+			if spec.required {
+				errs = append(errs, NewFieldError(spec.name, "missing required field"))
+			}
+			continue
+		}
+		if !matchKind(value, spec.kind) {
+			errMsg := fmt.Sprintf("the kind of value is NOT %s (type=%T)",
+				fieldKindNameMap[spec.kind], value)
+			errs = append(errs, NewFieldError(spec.name, errMsg))
+			continue
+		}
+		for _, validate := range spec.validators {
+			if validate == nil {
+				continue
+			}
+			if err := validate(value); err != nil {
+				errs = append(errs, NewFieldError(spec.name, err.Error()))
+			}
+		}
+	}
+	return errs
+}
+
+func (schema *myItemSchema) Fields() []FieldSpec {
+	fields := make([]FieldSpec, len(schema.fields))
+	copy(fields, schema.fields)
+	return fields
+}
+
+func (schema *myItemSchema) String() string {
+	return schema.description
+}
+
+// 判断值是否属于给定的字段种类。
+func matchKind(value interface{}, kind FieldKind) bool {
+	k := reflect.ValueOf(value).Kind()
+	switch kind {
+	case FIELD_KIND_ANY:
+		return true
+	case FIELD_KIND_STRING:
+		return k == reflect.String
+	case FIELD_KIND_INT:
+		return isIntKind(k)
+	case FIELD_KIND_FLOAT:
+		return k == reflect.Float32 || k == reflect.Float64 || isIntKind(k)
+	case FIELD_KIND_BOOL:
+		return k == reflect.Bool
+	case FIELD_KIND_SLICE:
+		return k == reflect.Slice || k == reflect.Array
+	case FIELD_KIND_MAP:
+		return k == reflect.Map
+	}
+	return false
+}
+
+// 判断是否为整数种类。
+func isIntKind(k reflect.Kind) bool {
+	switch k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	}
+	return false
+}
+
+// 生成校验非空值的函数。字符串、切片、数组和字典的长度不能为0。
+func NotEmpty() ValidateField {
+	return func(value interface{}) error {
+		v := reflect.ValueOf(value)
+		switch v.Kind() {
+		case reflect.String, reflect.Slice, reflect.Array, reflect.Map:
+			if v.Len() == 0 {
+				return errors.New("the value is empty")
+			}
+		}
+		return nil
+	}
+}
+
+// 生成校验字符串格式的函数。
+func MatchRegexp(re *regexp.Regexp) ValidateField {
+	return func(value interface{}) error {
+		s, ok := value.(string)
+		if !ok {
+			return errors.New(fmt.Sprintf("the value is NOT string (type=%T)", value))
+		}
+		if !re.MatchString(s) {
+			return errors.New(fmt.Sprintf("the value '%s' does not match '%s'", s, re))
+		}
+		return nil
+	}
+}
+
+// 生成校验数值范围的函数。合法的数值x应满足min <= x <= max。
+func InRange(min float64, max float64) ValidateField {
+	return func(value interface{}) error {
+		v := reflect.ValueOf(value)
+		var x float64
+		switch {
+		case v.Kind() == reflect.Float32 || v.Kind() == reflect.Float64:
+			x = v.Float()
+		case v.Kind() >= reflect.Int && v.Kind() <= reflect.Int64:
+			x = float64(v.Int())
+		case isIntKind(v.Kind()):
+			x = float64(v.Uint())
+		default:
+			return errors.New(fmt.Sprintf("the value is NOT number (type=%T)", value))
+		}
+		if x < min || x > max {
+			return errors.New(fmt.Sprintf("the value %v is out of range [%v, %v]", value, min, max))
+		}
+		return nil
+	}
+}
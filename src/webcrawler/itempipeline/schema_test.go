@@ -0,0 +1,85 @@
+package itemproc
+
+import (
+	"regexp"
+	"testing"
+	base "webcrawler/base"
+)
+
+func newTestSchema(t *testing.T) ItemSchema {
+	schema, err := NewItemSchema(
+		NewFieldSpec("title", FIELD_KIND_STRING, true, NotEmpty()),
+		NewFieldSpec("url", FIELD_KIND_STRING, true,
+			MatchRegexp(regexp.MustCompile(`^http://`))),
+		NewFieldSpec("score", FIELD_KIND_FLOAT, false, InRange(0, 10)),
+	)
+	if err != nil {
+		t.Fatalf("Item schema initialization failing: %s\n", err)
+	}
+	return schema
+}
+
+func TestNewItemSchema(t *testing.T) {
+	_, err := NewItemSchema(
+		NewFieldSpec("a", FIELD_KIND_ANY, false),
+		NewFieldSpec("a", FIELD_KIND_INT, false))
+	if err == nil {
+		t.Errorf("The repeated field name should be rejected!\n")
+	}
+	_, err = NewItemSchema(NewFieldSpec("", FIELD_KIND_ANY, false))
+	if err == nil {
+		t.Errorf("The empty field name should be rejected!\n")
+	}
+	schema := newTestSchema(t)
+	expected := "{ title: string!, url: string!, score: float }"
+	if schema.String() != expected {
+		t.Errorf("The schema string should be %q, but %q!\n", expected, schema.String())
+	}
+}
+
+func TestItemSchemaValidate(t *testing.T) {
+	schema := newTestSchema(t)
+	item := base.Item{"title": "Go", "url": "http://golang.org", "score": 9}
+	if errs := schema.Validate(item); len(errs) != 0 {
+		t.Errorf("The item should be valid, but: %v\n", errs)
+	}
+	item = base.Item{"title": "", "url": 1, "score": 11.5}
+	errs := schema.Validate(item)
+	if len(errs) != 3 {
+		t.Fatalf("The number of errors should be 3, but %d! (%v)\n", len(errs), errs)
+	}
+	fields := []string{"title", "url", "score"}
+	for i, err := range errs {
+		fe, ok := err.(*FieldError)
+		if !ok {
+			t.Fatalf("The type of error should be *FieldError, but %T!\n", err)
+		}
+		if fe.Field() != fields[i] {
+			t.Errorf("The field of error [%d] should be %s, but %s!\n", i, fields[i], fe.Field())
+		}
+	}
+	if errs := schema.Validate(base.Item{"title": "Go"}); len(errs) != 1 {
+		t.Errorf("The missing required field should be reported! (%v)\n", errs)
+	}
+}
+
+func TestItemPipelineWithSchema(t *testing.T) {
+	var processed int
+	ip := NewItemPipeline([]ProcessItem{
+		func(item base.Item) (base.Item, error) {
+			processed++
+			return item, nil
+		},
+	})
+	ip.SetSchema(newTestSchema(t))
+	if errs := ip.Send(base.Item{"title": "Go"}); len(errs) == 0 {
+		t.Errorf("The invalid item should be rejected!\n")
+	}
+	if errs := ip.Send(base.Item{"title": "Go", "url": "http://golang.org"}); len(errs) != 0 {
+		t.Errorf("The valid item should be accepted, but: %v\n", errs)
+	}
+	counts := ip.Count()
+	if counts[0] != 2 || counts[1] != 1 || counts[2] != 1 || processed != 1 {
+		t.Errorf("Unexpected counts %v (processed=%d)!\n", counts, processed)
+	}
+}
@@ -0,0 +1,145 @@
+package linkgraph
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// 导出格式。
+type Format string
+
+// 导出格式常量。
+const (
+	FORMAT_GRAPHML Format = "graphml" // GraphML格式。
+	FORMAT_DOT     Format = "dot"     // Graphviz的DOT格式。
+	FORMAT_CSV     Format = "csv"     // CSV格式的边列表。
+)
+
+// 根据名称获得导出格式。名称不区分大小写。
+func ParseFormat(name string) (Format, error) {
+	switch format := Format(strings.ToLower(name)); format {
+	case FORMAT_GRAPHML, FORMAT_DOT, FORMAT_CSV:
+		return format, nil
+	}
+	return "", errors.New(fmt.Sprintf("Unsupported link graph format '%s'!", name))
+}
+
+// 把链接图以给定的格式写入到文件。参数pageRanks的含义与WriteGraphML函数的相同。
+func Export(graph LinkGraph, format Format, path string, pageRanks map[string]float64) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	writer := bufio.NewWriter(file)
+	switch format {
+	case FORMAT_GRAPHML:
+		err = WriteGraphML(graph, writer, pageRanks)
+	case FORMAT_DOT:
+		err = WriteDot(graph, writer, pageRanks)
+	case FORMAT_CSV:
+		err = WriteCsv(graph, writer)
+	default:
+		err = errors.New(fmt.Sprintf("Unsupported link graph format '%s'!", format))
+	}
+	if err == nil {
+		err = writer.Flush()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// 转义XML文本。
+func escapeXml(s string) string {
+	var builder strings.Builder
+	xml.EscapeText(&builder, []byte(s))
+	return builder.String()
+}
+
+// 以GraphML格式写出链接图。节点会带有URL和入度，链接会带有锚文本和深度。
+// 参数pageRanks代表各个节点的PageRank值。若它不为nil，则节点还会带有PageRank值。
+func WriteGraphML(graph LinkGraph, w io.Writer, pageRanks map[string]float64) error {
+	nodes := graph.Nodes()
+	inDegree := graph.InDegree()
+	idMap := make(map[string]string, len(nodes))
+	var builder strings.Builder
+	builder.WriteString(xml.Header)
+	builder.WriteString(`<graphml xmlns="http://graphml.graphdrawing.org/xmlns">` + "\n")
+	builder.WriteString(`  <key id="url" for="node" attr.name="url" attr.type="string"/>` + "\n")
+	builder.WriteString(`  <key id="indegree" for="node" attr.name="indegree" attr.type="int"/>` + "\n")
+	if pageRanks != nil {
+		builder.WriteString(`  <key id="pagerank" for="node" attr.name="pagerank" attr.type="double"/>` + "\n")
+	}
+	builder.WriteString(`  <key id="anchor" for="edge" attr.name="anchor" attr.type="string"/>` + "\n")
+	builder.WriteString(`  <key id="depth" for="edge" attr.name="depth" attr.type="int"/>` + "\n")
+	builder.WriteString(`  <graph id="links" edgedefault="directed">` + "\n")
+	for i, node := range nodes {
+		id := "n" + strconv.Itoa(i)
+		idMap[node] = id
+		builder.WriteString(fmt.Sprintf(`    <node id="%s">`+"\n", id))
+		builder.WriteString(fmt.Sprintf(`      <data key="url">%s</data>`+"\n", escapeXml(node)))
+		builder.WriteString(fmt.Sprintf(`      <data key="indegree">%d</data>`+"\n", inDegree[node]))
+		if pageRanks != nil {
+			builder.WriteString(fmt.Sprintf(`      <data key="pagerank">%s</data>`+"\n",
+				strconv.FormatFloat(pageRanks[node], 'g', -1, 64)))
+		}
+		builder.WriteString("    </node>\n")
+	}
+	for i, edge := range graph.Edges() {
+		builder.WriteString(fmt.Sprintf(`    <edge id="e%d" source="%s" target="%s">`+"\n",
+			i, idMap[edge.From], idMap[edge.To]))
+		builder.WriteString(fmt.Sprintf(`      <data key="anchor">%s</data>`+"\n", escapeXml(edge.Anchor)))
+		builder.WriteString(fmt.Sprintf(`      <data key="depth">%d</data>`+"\n", edge.Depth))
+		builder.WriteString("    </edge>\n")
+	}
+	builder.WriteString("  </graph>\n</graphml>\n")
+	_, err := io.WriteString(w, builder.String())
+	return err
+}
+
+// 以Graphviz的DOT格式写出链接图。节点以URL标识，链接以锚文本作为标签。
+// 参数pageRanks的含义与WriteGraphML函数的相同。
+func WriteDot(graph LinkGraph, w io.Writer, pageRanks map[string]float64) error {
+	inDegree := graph.InDegree()
+	var builder strings.Builder
+	builder.WriteString("digraph links {\n")
+	for _, node := range graph.Nodes() {
+		builder.WriteString(fmt.Sprintf("  %s [indegree=%d", strconv.Quote(node), inDegree[node]))
+		if pageRanks != nil {
+			builder.WriteString(fmt.Sprintf(", pagerank=%s",
+				strconv.FormatFloat(pageRanks[node], 'g', -1, 64)))
+		}
+		builder.WriteString("];\n")
+	}
+	for _, edge := range graph.Edges() {
+		builder.WriteString(fmt.Sprintf("  %s -> %s [label=%s, depth=%d];\n",
+			strconv.Quote(edge.From), strconv.Quote(edge.To), strconv.Quote(edge.Anchor), edge.Depth))
+	}
+	builder.WriteString("}\n")
+	_, err := io.WriteString(w, builder.String())
+	return err
+}
+
+// 以CSV格式写出链接图的边列表。第一行是标题行。
+func WriteCsv(graph LinkGraph, w io.Writer) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{"from", "to", "anchor", "depth"}); err != nil {
+		return err
+	}
+	for _, edge := range graph.Edges() {
+		record := []string{edge.From, edge.To, edge.Anchor, strconv.FormatUint(uint64(edge.Depth), 10)}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
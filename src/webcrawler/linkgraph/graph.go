@@ -0,0 +1,112 @@
+package linkgraph
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// 链接，即链接图中的一条有向边。
+type Edge struct {
+	From   string // 源网页的URL。
+	To     string // 目标网页的URL。
+	Anchor string // 锚文本。
+	Depth  uint32 // 目标请求的深度。
+}
+
+// 链接图的接口类型。它记录爬取期间发现的网页之间的链接。它的所有方法都是并发安全的。
+type LinkGraph interface {
+	// 添加链接。源和目标都相同的链接只会被记录一次，此时结果值为false。
+	AddEdge(edge Edge) bool
+	// 获得所有链接。结果值按添加的顺序排列。
+	Edges() []Edge
+	// 获得所有节点（即网页的URL）。结果值按URL排序。
+	Nodes() []string
+	// 获得各个节点的入度，即指向它的不同网页的数量。
+	InDegree() map[string]uint32
+	// 获得节点的数量。
+	NodeNumber() uint32
+	// 获得链接的数量。
+	EdgeNumber() uint32
+	// 获取摘要信息。
+	Summary() string
+}
+
+// 创建链接图。
+func NewLinkGraph() LinkGraph {
+	return &myLinkGraph{
+		nodeMap: make(map[string]bool),
+		edgeMap: make(map[[2]string]bool),
+	}
+}
+
+// 链接图的实现类型。
+type myLinkGraph struct {
+	edges   []Edge             // 链接的列表。
+	nodeMap map[string]bool    // 节点的集合。
+	edgeMap map[[2]string]bool // 由源和目标组成的链接的集合。
+	mutex   sync.RWMutex       // 读写锁。
+}
+
+func (graph *myLinkGraph) AddEdge(edge Edge) bool {
+	if edge.From == "" || edge.To == "" {
+		return false
+	}
+	graph.mutex.Lock()
+	defer graph.mutex.Unlock()
+	key := [2]string{edge.From, edge.To}
+	if graph.edgeMap[key] {
+		return false
+	}
+	graph.edgeMap[key] = true
+	graph.nodeMap[edge.From] = true
+	graph.nodeMap[edge.To] = true
+	graph.edges = append(graph.edges, edge)
+	return true
+}
+
+func (graph *myLinkGraph) Edges() []Edge {
+	graph.mutex.RLock()
+	defer graph.mutex.RUnlock()
+	return append([]Edge(nil), graph.edges...)
+}
+
+func (graph *myLinkGraph) Nodes() []string {
+	graph.mutex.RLock()
+	defer graph.mutex.RUnlock()
+	nodes := make([]string, 0, len(graph.nodeMap))
+	for node := range graph.nodeMap {
+		nodes = append(nodes, node)
+	}
+	sort.Strings(nodes)
+	return nodes
+}
+
+func (graph *myLinkGraph) InDegree() map[string]uint32 {
+	graph.mutex.RLock()
+	defer graph.mutex.RUnlock()
+	inDegree := make(map[string]uint32, len(graph.nodeMap))
+	for node := range graph.nodeMap {
+		inDegree[node] = 0
+	}
+	for _, edge := range graph.edges {
+		inDegree[edge.To]++
+	}
+	return inDegree
+}
+
+func (graph *myLinkGraph) NodeNumber() uint32 {
+	graph.mutex.RLock()
+	defer graph.mutex.RUnlock()
+	return uint32(len(graph.nodeMap))
+}
+
+func (graph *myLinkGraph) EdgeNumber() uint32 {
+	graph.mutex.RLock()
+	defer graph.mutex.RUnlock()
+	return uint32(len(graph.edges))
+}
+
+func (graph *myLinkGraph) Summary() string {
+	return fmt.Sprintf("nodes: %d, edges: %d", graph.NodeNumber(), graph.EdgeNumber())
+}
@@ -0,0 +1,155 @@
+package linkgraph
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// 创建用于测试的链接图：a与b互相链接，c链接到a和d，d没有出链。
+func newTestGraph() LinkGraph {
+	graph := NewLinkGraph()
+	graph.AddEdge(Edge{From: "http://x.com/a", To: "http://x.com/b", Anchor: "B", Depth: 1})
+	graph.AddEdge(Edge{From: "http://x.com/b", To: "http://x.com/a", Anchor: "A & <home>", Depth: 2})
+	graph.AddEdge(Edge{From: "http://x.com/c", To: "http://x.com/a", Anchor: "Home", Depth: 1})
+	graph.AddEdge(Edge{From: "http://x.com/c", To: "http://x.com/d", Anchor: "D, \"quoted\"", Depth: 1})
+	return graph
+}
+
+func TestLinkGraph(t *testing.T) {
+	graph := newTestGraph()
+	if graph.AddEdge(Edge{From: "http://x.com/a", To: "http://x.com/b", Anchor: "again"}) {
+		t.Errorf("The repeated edge should not be added!")
+	}
+	if graph.AddEdge(Edge{From: "", To: "http://x.com/b"}) {
+		t.Errorf("The edge without source should not be added!")
+	}
+	if graph.NodeNumber() != 4 || graph.EdgeNumber() != 4 {
+		t.Errorf("Unexpected graph size: %s", graph.Summary())
+	}
+	expected := map[string]uint32{
+		"http://x.com/a": 2, "http://x.com/b": 1, "http://x.com/c": 0, "http://x.com/d": 1,
+	}
+	for node, degree := range graph.InDegree() {
+		if expected[node] != degree {
+			t.Errorf("The in-degree of %s should be %d, but %d!", node, expected[node], degree)
+		}
+	}
+	top := RankNodes(InDegreeScores(graph.InDegree()), 2)
+	if len(top) != 2 || top[0].Node != "http://x.com/a" || top[1].Node != "http://x.com/b" {
+		t.Errorf("Unexpected top in-degree nodes: %v", top)
+	}
+}
+
+func TestPageRank(t *testing.T) {
+	if _, err := PageRank(newTestGraph(), 1, 100, 1e-9); err == nil {
+		t.Errorf("An error should be returned for the damping factor 1!")
+	}
+	ranks, err := PageRank(newTestGraph(), 0.85, 100, 1e-12)
+	if err != nil {
+		t.Fatalf("PageRank error: %s", err)
+	}
+	var sum float64
+	for _, rank := range ranks {
+		sum += rank
+	}
+	if math.Abs(sum-1) > 1e-9 {
+		t.Errorf("The sum of PageRank values should be 1, but %v!", sum)
+	}
+	top := RankNodes(ranks, 0)
+	order := []string{"http://x.com/a", "http://x.com/b", "http://x.com/d", "http://x.com/c"}
+	for i, node := range order {
+		if top[i].Node != node {
+			t.Errorf("The %dth node should be %s, but %s! (ranks=%v)", i, node, top[i].Node, top)
+		}
+	}
+	// 链接图中只有一个环时，各个节点的PageRank值相等。
+	cycle := NewLinkGraph()
+	cycle.AddEdge(Edge{From: "a", To: "b"})
+	cycle.AddEdge(Edge{From: "b", To: "c"})
+	cycle.AddEdge(Edge{From: "c", To: "a"})
+	ranks, _ = PageRank(cycle, 0.85, 100, 1e-12)
+	for node, rank := range ranks {
+		if math.Abs(rank-1.0/3) > 1e-9 {
+			t.Errorf("The PageRank value of %s should be 1/3, but %v!", node, rank)
+		}
+	}
+}
+
+func TestExport(t *testing.T) {
+	graph := newTestGraph()
+	ranks, _ := PageRank(graph, 0.85, 100, 1e-9)
+
+	var buffer bytes.Buffer
+	if err := WriteGraphML(graph, &buffer, ranks); err != nil {
+		t.Fatalf("Write GraphML error: %s", err)
+	}
+	var doc struct {
+		Graph struct {
+			Nodes []struct {
+				Id string `xml:"id,attr"`
+			} `xml:"node"`
+			Edges []struct {
+				Source string `xml:"source,attr"`
+				Data   []struct {
+					Key   string `xml:"key,attr"`
+					Value string `xml:",chardata"`
+				} `xml:"data"`
+			} `xml:"edge"`
+		} `xml:"graph"`
+	}
+	if err := xml.Unmarshal(buffer.Bytes(), &doc); err != nil {
+		t.Fatalf("The GraphML output is invalid: %s\n%s", err, buffer.String())
+	}
+	if len(doc.Graph.Nodes) != 4 || len(doc.Graph.Edges) != 4 {
+		t.Errorf("Unexpected GraphML output:\n%s", buffer.String())
+	} else if anchor := doc.Graph.Edges[1].Data[0].Value; anchor != "A & <home>" {
+		t.Errorf("Unexpected anchor text %q in GraphML output!", anchor)
+	}
+
+	buffer.Reset()
+	if err := WriteDot(graph, &buffer, nil); err != nil {
+		t.Fatalf("Write DOT error: %s", err)
+	}
+	dot := buffer.String()
+	for _, expected := range []string{
+		"digraph links {\n",
+		`  "http://x.com/a" [indegree=2];`,
+		`  "http://x.com/c" -> "http://x.com/d" [label="D, \"quoted\"", depth=1];`,
+	} {
+		if !strings.Contains(dot, expected) {
+			t.Errorf("The DOT output should contain %q:\n%s", expected, dot)
+		}
+	}
+
+	dir, err := ioutil.TempDir("", "linkgraph")
+	if err != nil {
+		t.Fatalf("TempDir error: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "links.csv")
+	format, err := ParseFormat("CSV")
+	if err != nil {
+		t.Fatalf("Parse format error: %s", err)
+	}
+	if err := Export(graph, format, path, nil); err != nil {
+		t.Fatalf("Export error: %s", err)
+	}
+	content, _ := ioutil.ReadFile(path)
+	expected := "from,to,anchor,depth\n" +
+		"http://x.com/a,http://x.com/b,B,1\n" +
+		"http://x.com/b,http://x.com/a,A & <home>,2\n" +
+		"http://x.com/c,http://x.com/a,Home,1\n" +
+		"http://x.com/c,http://x.com/d,\"D, \"\"quoted\"\"\",1\n"
+	if string(content) != expected {
+		t.Errorf("Unexpected CSV output:\n%s", content)
+	}
+	if _, err := ParseFormat("gexf"); err == nil {
+		t.Errorf("An error should be returned for the unsupported format!")
+	}
+}
@@ -0,0 +1,108 @@
+package linkgraph
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// 节点的得分。
+type NodeScore struct {
+	Node  string  // 节点，即网页的URL。
+	Score float64 // 得分。
+}
+
+// 计算链接图中各个节点的PageRank值。所有节点的值之和为1。
+// 参数damping代表阻尼系数，其值应在0和1之间（不含），通常为0.85。
+// 参数maxIterations代表最大迭代次数。当相邻两次迭代的结果之差（L1范数）小于参数tolerance时，迭代会提前结束。
+// 没有出链的节点的值会被平均地分配给所有节点。
+func PageRank(graph LinkGraph, damping float64, maxIterations uint32, tolerance float64) (map[string]float64, error) {
+	if graph == nil {
+		return nil, errors.New("The link graph is invalid!")
+	}
+	if damping <= 0 || damping >= 1 {
+		return nil, errors.New(fmt.Sprintf("The damping factor %v is not in (0, 1)!", damping))
+	}
+	if maxIterations == 0 {
+		return nil, errors.New("The max iterations can not be 0!")
+	}
+	nodes := graph.Nodes()
+	n := len(nodes)
+	ranks := make(map[string]float64, n)
+	if n == 0 {
+		return ranks, nil
+	}
+	indexMap := make(map[string]int, n)
+	for i, node := range nodes {
+		indexMap[node] = i
+	}
+	outLinks := make([][]int, n)
+	for _, edge := range graph.Edges() {
+		from := indexMap[edge.From]
+		outLinks[from] = append(outLinks[from], indexMap[edge.To])
+	}
+	rank := make([]float64, n)
+	for i := range rank {
+		rank[i] = 1 / float64(n)
+	}
+	next := make([]float64, n)
+	for iteration := uint32(0); iteration < maxIterations; iteration++ {
+		var danglingSum float64
+		for i := range next {
+			next[i] = 0
+		}
+		for i, links := range outLinks {
+			if len(links) == 0 {
+				danglingSum += rank[i]
+				continue
+			}
+			share := rank[i] / float64(len(links))
+			for _, j := range links {
+				next[j] += share
+			}
+		}
+		base := (1-damping)/float64(n) + damping*danglingSum/float64(n)
+		var delta float64
+		for i := range next {
+			next[i] = base + damping*next[i]
+			delta += math.Abs(next[i] - rank[i])
+		}
+		rank, next = next, rank
+		if delta < tolerance {
+			break
+		}
+	}
+	for i, node := range nodes {
+		ranks[node] = rank[i]
+	}
+	return ranks, nil
+}
+
+// 按得分从高到低对节点排序。得分相同的节点按URL排序。
+// 参数limit代表结果值的最大长度。为0时表示不限制。
+func RankNodes(scores map[string]float64, limit int) []NodeScore {
+	result := make([]NodeScore, 0, len(scores))
+	for node, score := range scores {
+		result = append(result, NodeScore{Node: node, Score: score})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Score != result[j].Score {
+			return result[i].Score > result[j].Score
+		}
+		return result[i].Node < result[j].Node
+	})
+	if limit > 0 && len(result) > limit {
+		result = result[:limit]
+	}
+	return result
+}
+
+// 把入度转换为得分，以便排序。
+func InDegreeScores(inDegree map[string]uint32) map[string]float64 {
+	scores := make(map[string]float64, len(inDegree))
+	for node, degree := range inDegree {
+		scores[node] = float64(degree)
+	}
+	return scores
+}
@@ -0,0 +1,171 @@
+package middleware
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"sync"
+)
+
+// 可伸缩布隆过滤器的参数。
+const (
+	bloomGrowthFactor    = 2   // 新的过滤器相对于上一个过滤器的容量的倍数。
+	bloomTighteningRatio = 0.5 // 新的过滤器相对于上一个过滤器的误判率的比率。
+)
+
+// 创建基于可伸缩布隆过滤器的已访问集合。它占用的内存远小于精确的实现，但存在误判：
+// 一个从未被添加过的键有可能被判定为已存在。
+// 参数initialCapacity代表第一个过滤器的容量。当过滤器已满时，会追加一个容量更大的过滤器。
+// 参数fpRate代表整体的误判率上限，应在(0, 1)之间。
+func NewBloomVisitedSet(initialCapacity uint64, fpRate float64) (VisitedSet, error) {
+	if initialCapacity == 0 {
+		errMsg := fmt.Sprintf("The bloom filter can not be initialized! (initialCapacity=%d)\n",
+			initialCapacity)
+		return nil, errors.New(errMsg)
+	}
+	if !(fpRate > 0 && fpRate < 1) {
+		errMsg := fmt.Sprintf("The bloom filter can not be initialized! (fpRate=%f)\n", fpRate)
+		return nil, errors.New(errMsg)
+	}
+	set := &bloomVisitedSet{
+		initialCapacity: initialCapacity,
+		fpRate:          fpRate,
+	}
+	set.grow()
+	return set, nil
+}
+
+// 单个布隆过滤器。
+type bloomStage struct {
+	bits     []uint64 // 位数组。
+	m        uint64   // 位的数量。
+	k        uint32   // 散列函数的数量。
+	capacity uint64   // 容量。
+	count    uint64   // 已添加的键的数量。
+}
+
+// 创建布隆过滤器。参数fpRate代表该过滤器在达到容量时的误判率。
+func newBloomStage(capacity uint64, fpRate float64) *bloomStage {
+	m := uint64(math.Ceil(-float64(capacity) * math.Log(fpRate) / (math.Ln2 * math.Ln2)))
+	k := uint32(math.Ceil(-math.Log2(fpRate)))
+	if k == 0 {
+		k = 1
+	}
+	return &bloomStage{
+		bits:     make([]uint64, (m+63)/64),
+		m:        m,
+		k:        k,
+		capacity: capacity,
+	}
+}
+
+func (stage *bloomStage) add(h1, h2 uint64) {
+	for i := uint32(0); i < stage.k; i++ {
+		pos := (h1 + uint64(i)*h2) % stage.m
+		stage.bits[pos/64] |= 1 << (pos % 64)
+	}
+	stage.count++
+}
+
+func (stage *bloomStage) contains(h1, h2 uint64) bool {
+	for i := uint32(0); i < stage.k; i++ {
+		pos := (h1 + uint64(i)*h2) % stage.m
+		if stage.bits[pos/64]&(1<<(pos%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// 基于可伸缩布隆过滤器的已访问集合的实现类型。
+type bloomVisitedSet struct {
+	initialCapacity uint64        // 第一个过滤器的容量。
+	fpRate          float64       // 整体的误判率上限。
+	stages          []*bloomStage // 过滤器的列表。
+	count           uint64        // 已添加的键的数量。
+	rwmutex         sync.RWMutex  // 读写锁。
+}
+
+// 追加一个过滤器。调用方需持有写锁。
+// 各个过滤器的误判率构成一个等比数列，以保证整体的误判率不超过上限。
+func (set *bloomVisitedSet) grow() {
+	n := len(set.stages)
+	capacity := set.initialCapacity
+	for i := 0; i < n; i++ {
+		capacity *= bloomGrowthFactor
+	}
+	fpRate := set.fpRate * (1 - bloomTighteningRatio) * math.Pow(bloomTighteningRatio, float64(n))
+	set.stages = append(set.stages, newBloomStage(capacity, fpRate))
+}
+
+// 计算键的两个散列值。
+func bloomHash(key string) (uint64, uint64) {
+	h := fnv.New128a()
+	h.Write([]byte(key))
+	sum := h.Sum(nil)
+	h1 := binary.BigEndian.Uint64(sum[:8])
+	h2 := binary.BigEndian.Uint64(sum[8:]) | 1
+	return h1, h2
+}
+
+// 判断键是否存在。调用方需持有锁。
+func (set *bloomVisitedSet) contains(h1, h2 uint64) bool {
+	for _, stage := range set.stages {
+		if stage.contains(h1, h2) {
+			return true
+		}
+	}
+	return false
+}
+
+func (set *bloomVisitedSet) Add(key string) bool {
+	h1, h2 := bloomHash(key)
+	set.rwmutex.Lock()
+	defer set.rwmutex.Unlock()
+	if set.contains(h1, h2) {
+		return false
+	}
+	last := set.stages[len(set.stages)-1]
+	if last.count >= last.capacity {
+		set.grow()
+		last = set.stages[len(set.stages)-1]
+	}
+	last.add(h1, h2)
+	set.count++
+	return true
+}
+
+func (set *bloomVisitedSet) Contains(key string) bool {
+	h1, h2 := bloomHash(key)
+	set.rwmutex.RLock()
+	defer set.rwmutex.RUnlock()
+	return set.contains(h1, h2)
+}
+
+func (set *bloomVisitedSet) Len() uint64 {
+	set.rwmutex.RLock()
+	defer set.rwmutex.RUnlock()
+	return set.count
+}
+
+func (set *bloomVisitedSet) Keys() []string {
+	return nil
+}
+
+func (set *bloomVisitedSet) Close() error {
+	return nil
+}
+
+var bloomSummaryTemplate = "bloom, length: %d, fpRate: %g, stages: %d, memory: %d bytes"
+
+func (set *bloomVisitedSet) Summary() string {
+	set.rwmutex.RLock()
+	defer set.rwmutex.RUnlock()
+	var bytes int
+	for _, stage := range set.stages {
+		bytes += len(stage.bits) * 8
+	}
+	return fmt.Sprintf(bloomSummaryTemplate, set.count, set.fpRate, len(set.stages), bytes)
+}
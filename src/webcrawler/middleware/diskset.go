@@ -0,0 +1,346 @@
+package middleware
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// 磁盘段的参数。
+const (
+	diskSegmentIndexInterval = 64 // 稀疏索引的间隔。每隔这么多个键会在内存中保留一个索引项。
+	diskSegmentMaxNumber     = 8  // 磁盘段的最大数量。超出后所有的磁盘段会被合并为一个。
+)
+
+// 创建可溢出到磁盘的已访问集合。它是精确的，且其内存占用与键的总数基本无关。
+// 键会先被保存在内存中，当数量达到memLimit时，它们会被排序并写入到目录dir下的一个新的磁盘段。
+// 每个磁盘段都在内存中保留一个稀疏索引，所以查找一个键最多只需为每个磁盘段读取一个数据块。
+// 关闭该集合时，所有的磁盘段文件都会被删除。
+func NewDiskVisitedSet(dir string, memLimit uint32) (VisitedSet, error) {
+	if memLimit == 0 {
+		errMsg := fmt.Sprintf("The disk visited set can not be initialized! (memLimit=%d)\n", memLimit)
+		return nil, errors.New(errMsg)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &diskVisitedSet{
+		dir:      dir,
+		memLimit: memLimit,
+		keyMap:   make(map[string]struct{}),
+	}, nil
+}
+
+// 磁盘段的索引项。
+type diskIndexEntry struct {
+	key    string // 键。
+	offset int64  // 该键的记录在文件中的偏移量。
+}
+
+// 磁盘段。它是一个文件，其中按顺序存储着排好序的键的记录。
+// 每条记录由键的长度（uvarint编码）和键本身组成。
+type diskSegment struct {
+	path  string           // 文件的路径。
+	file  *os.File         // 文件。
+	index []diskIndexEntry // 稀疏索引。
+	size  int64            // 文件的大小。
+	count uint64           // 键的数量。
+}
+
+// 判断磁盘段中是否存在给定的键。
+func (seg *diskSegment) contains(key string) (bool, error) {
+	i := sort.Search(len(seg.index), func(i int) bool {
+		return seg.index[i].key > key
+	}) - 1
+	if i < 0 {
+		return false, nil
+	}
+	start := seg.index[i].offset
+	end := seg.size
+	if i+1 < len(seg.index) {
+		end = seg.index[i+1].offset
+	}
+	block := make([]byte, end-start)
+	if _, err := seg.file.ReadAt(block, start); err != nil {
+		return false, err
+	}
+	for len(block) > 0 {
+		n, w := binary.Uvarint(block)
+		if w <= 0 || uint64(len(block)-w) < n {
+			return false, errors.New(fmt.Sprintf("The segment '%s' is corrupted!", seg.path))
+		}
+		current := string(block[w : w+int(n)])
+		if current == key {
+			return true, nil
+		}
+		if current > key {
+			break
+		}
+		block = block[w+int(n):]
+	}
+	return false, nil
+}
+
+// 按顺序遍历磁盘段中的键。若f返回false，则停止遍历。
+func (seg *diskSegment) each(f func(key string) bool) error {
+	reader := bufio.NewReader(io.NewSectionReader(seg.file, 0, seg.size))
+	for {
+		key, err := readDiskRecord(reader)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if !f(key) {
+			return nil
+		}
+	}
+}
+
+// 关闭并删除磁盘段文件。
+func (seg *diskSegment) remove() error {
+	seg.file.Close()
+	return os.Remove(seg.path)
+}
+
+// 读取一条记录。
+func readDiskRecord(reader *bufio.Reader) (string, error) {
+	n, err := binary.ReadUvarint(reader)
+	if err != nil {
+		return "", err
+	}
+	buf := make([]byte, n)
+	if _, err := io.ReadFull(reader, buf); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return "", err
+	}
+	return string(buf), nil
+}
+
+// 可溢出到磁盘的已访问集合的实现类型。
+type diskVisitedSet struct {
+	dir      string              // 存放磁盘段文件的目录。
+	memLimit uint32              // 内存中的键的数量上限。
+	keyMap   map[string]struct{} // 内存中的键的字典。
+	segments []*diskSegment      // 磁盘段的列表。
+	count    uint64              // 已添加的键的数量。
+	seq      uint32              // 磁盘段文件的序号。
+	closed   bool                // 是否已关闭。
+	mutex    sync.Mutex          // 互斥锁。
+}
+
+// 判断键是否存在。调用方需持有互斥锁。
+func (set *diskVisitedSet) contains(key string) (bool, error) {
+	if _, ok := set.keyMap[key]; ok {
+		return true, nil
+	}
+	for _, seg := range set.segments {
+		ok, err := seg.contains(key)
+		if err != nil || ok {
+			return ok, err
+		}
+	}
+	return false, nil
+}
+
+func (set *diskVisitedSet) Add(key string) bool {
+	set.mutex.Lock()
+	defer set.mutex.Unlock()
+	if set.closed {
+		return false
+	}
+	ok, err := set.contains(key)
+	if err != nil {
+		logger.Errorf("Visited set lookup error: %s\n", err)
+		return false
+	}
+	if ok {
+		return false
+	}
+	set.keyMap[key] = struct{}{}
+	set.count++
+	if uint32(len(set.keyMap)) >= set.memLimit {
+		if err := set.spill(); err != nil {
+			logger.Errorf("Visited set spill error: %s\n", err)
+		}
+	}
+	return true
+}
+
+// 把内存中的键写入到一个新的磁盘段。调用方需持有互斥锁。
+func (set *diskVisitedSet) spill() error {
+	keys := make([]string, 0, len(set.keyMap))
+	for key := range set.keyMap {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	i := 0
+	seg, err := set.writeSegment(func() (string, bool) {
+		if i >= len(keys) {
+			return "", false
+		}
+		i++
+		return keys[i-1], true
+	})
+	if err != nil {
+		return err
+	}
+	set.segments = append(set.segments, seg)
+	set.keyMap = make(map[string]struct{})
+	if len(set.segments) > diskSegmentMaxNumber {
+		return set.compact()
+	}
+	return nil
+}
+
+// 合并所有的磁盘段。调用方需持有互斥锁。
+func (set *diskVisitedSet) compact() error {
+	readers := make([]*bufio.Reader, len(set.segments))
+	heads := make([]string, len(set.segments))
+	alive := make([]bool, len(set.segments))
+	for i, seg := range set.segments {
+		readers[i] = bufio.NewReader(io.NewSectionReader(seg.file, 0, seg.size))
+		key, err := readDiskRecord(readers[i])
+		if err != nil && err != io.EOF {
+			return err
+		}
+		heads[i], alive[i] = key, err == nil
+	}
+	var readErr error
+	seg, err := set.writeSegment(func() (string, bool) {
+		least := -1
+		for i := range heads {
+			if alive[i] && (least < 0 || heads[i] < heads[least]) {
+				least = i
+			}
+		}
+		if least < 0 {
+			return "", false
+		}
+		key := heads[least]
+		next, err := readDiskRecord(readers[least])
+		if err != nil && err != io.EOF {
+			readErr = err
+		}
+		heads[least], alive[least] = next, err == nil
+		return key, true
+	})
+	if err == nil {
+		err = readErr
+	}
+	if err != nil {
+		if seg != nil {
+			seg.remove()
+		}
+		return err
+	}
+	for _, old := range set.segments {
+		old.remove()
+	}
+	set.segments = []*diskSegment{seg}
+	return nil
+}
+
+// 写入一个新的磁盘段。参数next会按从小到大的顺序依次给出键。
+// 调用方需持有互斥锁。
+func (set *diskVisitedSet) writeSegment(next func() (string, bool)) (*diskSegment, error) {
+	set.seq++
+	path := filepath.Join(set.dir, fmt.Sprintf("visited-%06d.seg", set.seq))
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return nil, err
+	}
+	seg := &diskSegment{path: path, file: file}
+	writer := bufio.NewWriter(file)
+	lenBuf := make([]byte, binary.MaxVarintLen64)
+	for {
+		key, ok := next()
+		if !ok {
+			break
+		}
+		if seg.count%diskSegmentIndexInterval == 0 {
+			seg.index = append(seg.index, diskIndexEntry{key: key, offset: seg.size})
+		}
+		w := binary.PutUvarint(lenBuf, uint64(len(key)))
+		writer.Write(lenBuf[:w])
+		writer.WriteString(key)
+		seg.size += int64(w + len(key))
+		seg.count++
+	}
+	if err := writer.Flush(); err != nil {
+		seg.remove()
+		return nil, err
+	}
+	return seg, nil
+}
+
+func (set *diskVisitedSet) Contains(key string) bool {
+	set.mutex.Lock()
+	defer set.mutex.Unlock()
+	ok, err := set.contains(key)
+	if err != nil {
+		logger.Errorf("Visited set lookup error: %s\n", err)
+	}
+	return ok
+}
+
+func (set *diskVisitedSet) Len() uint64 {
+	set.mutex.Lock()
+	defer set.mutex.Unlock()
+	return set.count
+}
+
+func (set *diskVisitedSet) Keys() []string {
+	set.mutex.Lock()
+	defer set.mutex.Unlock()
+	keys := make([]string, 0, set.count)
+	for key := range set.keyMap {
+		keys = append(keys, key)
+	}
+	for _, seg := range set.segments {
+		err := seg.each(func(key string) bool {
+			keys = append(keys, key)
+			return true
+		})
+		if err != nil {
+			logger.Errorf("Visited set read error: %s\n", err)
+		}
+	}
+	return keys
+}
+
+func (set *diskVisitedSet) Close() error {
+	set.mutex.Lock()
+	defer set.mutex.Unlock()
+	if set.closed {
+		return nil
+	}
+	set.closed = true
+	var firstErr error
+	for _, seg := range set.segments {
+		if err := seg.remove(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	set.segments = nil
+	set.keyMap = make(map[string]struct{})
+	return firstErr
+}
+
+var diskVisitedSetSummaryTemplate = "disk, length: %d, memory: %d/%d, segments: %d, dir: %s"
+
+func (set *diskVisitedSet) Summary() string {
+	set.mutex.Lock()
+	defer set.mutex.Unlock()
+	return fmt.Sprintf(diskVisitedSetSummaryTemplate,
+		set.count, len(set.keyMap), set.memLimit, len(set.segments), set.dir)
+}
@@ -0,0 +1,207 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	base "webcrawler/base"
+)
+
+// 错误分发器的接口类型。
+// 错误会先被放入一个有界的环形缓冲区，再由专门的goroutine分发给所有的订阅方。
+// 分发不会阻塞：若某个订阅通道已满，则该错误对这个订阅方而言会被丢弃，且不会影响其他订阅方。
+type ErrorDispatcher interface {
+	// 放入错误。当缓冲区已满时，会按照溢出策略处理。
+	// 若错误被丢弃或分发器已关闭，则结果值为false。
+	Put(err error) bool
+	// 订阅错误。参数bufferLen代表作为结果值的通道的长度，它不能为0。
+	// 每个订阅方都会收到在其订阅之后放入的、且在其通道未满时分发的所有未被丢弃的错误。
+	// 分发器关闭后，所有订阅通道都会被关闭。
+	Subscribe(bufferLen uint) (<-chan error, error)
+	// 获得因缓冲区已满而被丢弃的错误的数量。
+	Dropped() uint64
+	// 获得各个订阅方因其通道已满而被丢弃的错误的数量。结果值按订阅的顺序排列。
+	SubscriberDropped() []uint64
+	// 获得缓冲区中的错误的数量。
+	Len() uint32
+	// 获得缓冲区的容量。
+	Cap() uint32
+	// 关闭错误分发器。
+	Close() bool
+	// 获取摘要信息。
+	Summary() string
+}
+
+// 创建错误分发器。
+// 参数capacity代表环形缓冲区的容量。
+// 参数policy代表缓冲区已满时的溢出策略。
+func NewErrorDispatcher(capacity uint32, policy base.OverflowPolicy) (ErrorDispatcher, error) {
+	if capacity == 0 {
+		errMsg :=
+			fmt.Sprintf("The error dispatcher can not be initialized! (capacity=%d)\n", capacity)
+		return nil, errors.New(errMsg)
+	}
+	if _, ok := base.OverflowPolicyName(policy); !ok {
+		errMsg := fmt.Sprintf("Unsupported overflow policy %d!\n", policy)
+		return nil, errors.New(errMsg)
+	}
+	dispatcher := &myErrorDispatcher{
+		buffer: make([]error, capacity),
+		policy: policy,
+	}
+	dispatcher.notEmpty = sync.NewCond(&dispatcher.mutex)
+	dispatcher.notFull = sync.NewCond(&dispatcher.mutex)
+	go dispatcher.dispatch()
+	return dispatcher, nil
+}
+
+// 错误的订阅方。
+type errorSubscriber struct {
+	ch      chan error // 订阅通道。
+	dropped uint64     // 因订阅通道已满而被丢弃的错误的数量。
+}
+
+// 错误分发器的实现类型。
+type myErrorDispatcher struct {
+	buffer      []error             // 环形缓冲区。
+	head        int                 // 最早放入的错误在缓冲区中的位置。
+	size        int                 // 缓冲区中的错误的数量。
+	policy      base.OverflowPolicy // 溢出策略。
+	subscribers []*errorSubscriber  // 订阅方的列表。
+	closed      bool                // 是否已关闭。
+	dropped     uint64              // 已被丢弃的错误的数量。
+	mutex       sync.Mutex          // 互斥锁。
+	notEmpty    *sync.Cond          // 缓冲区非空或订阅方变化时的条件变量。
+	notFull     *sync.Cond          // 缓冲区未满时的条件变量。
+}
+
+func (dispatcher *myErrorDispatcher) Put(err error) bool {
+	if err == nil {
+		return false
+	}
+	dispatcher.mutex.Lock()
+	defer dispatcher.mutex.Unlock()
+	capacity := len(dispatcher.buffer)
+	for !dispatcher.closed && dispatcher.size == capacity {
+		switch dispatcher.policy {
+		case base.OVERFLOW_POLICY_DROP_OLDEST:
+			dispatcher.head = (dispatcher.head + 1) % capacity
+			dispatcher.size--
+			atomic.AddUint64(&dispatcher.dropped, 1)
+		case base.OVERFLOW_POLICY_DROP_NEWEST:
+			atomic.AddUint64(&dispatcher.dropped, 1)
+			return false
+		default:
+			dispatcher.notFull.Wait()
+		}
+	}
+	if dispatcher.closed {
+		return false
+	}
+	dispatcher.buffer[(dispatcher.head+dispatcher.size)%capacity] = err
+	dispatcher.size++
+	dispatcher.notEmpty.Signal()
+	return true
+}
+
+func (dispatcher *myErrorDispatcher) Subscribe(bufferLen uint) (<-chan error, error) {
+	if bufferLen == 0 {
+		return nil, errors.New("The buffer length of error channel can not be 0!")
+	}
+	dispatcher.mutex.Lock()
+	defer dispatcher.mutex.Unlock()
+	if dispatcher.closed {
+		return nil, errors.New("The error dispatcher has been closed!")
+	}
+	ch := make(chan error, bufferLen)
+	dispatcher.subscribers = append(dispatcher.subscribers, &errorSubscriber{ch: ch})
+	dispatcher.notEmpty.Signal()
+	return ch, nil
+}
+
+// 分发错误。在没有订阅方的时候，错误会被保留在缓冲区中。
+func (dispatcher *myErrorDispatcher) dispatch() {
+	for {
+		dispatcher.mutex.Lock()
+		for !dispatcher.closed &&
+			(dispatcher.size == 0 || len(dispatcher.subscribers) == 0) {
+			dispatcher.notEmpty.Wait()
+		}
+		if dispatcher.closed {
+			subscribers := dispatcher.subscribers
+			dispatcher.subscribers = nil
+			dispatcher.mutex.Unlock()
+			for _, subscriber := range subscribers {
+				close(subscriber.ch)
+			}
+			return
+		}
+		err := dispatcher.buffer[dispatcher.head]
+		dispatcher.buffer[dispatcher.head] = nil
+		dispatcher.head = (dispatcher.head + 1) % len(dispatcher.buffer)
+		dispatcher.size--
+		subscribers := dispatcher.subscribers
+		dispatcher.notFull.Signal()
+		dispatcher.mutex.Unlock()
+		for _, subscriber := range subscribers {
+			select {
+			case subscriber.ch <- err:
+			default:
+				atomic.AddUint64(&subscriber.dropped, 1)
+			}
+		}
+	}
+}
+
+func (dispatcher *myErrorDispatcher) Dropped() uint64 {
+	return atomic.LoadUint64(&dispatcher.dropped)
+}
+
+func (dispatcher *myErrorDispatcher) SubscriberDropped() []uint64 {
+	dispatcher.mutex.Lock()
+	defer dispatcher.mutex.Unlock()
+	return dispatcher.subscriberDropped()
+}
+
+func (dispatcher *myErrorDispatcher) subscriberDropped() []uint64 {
+	dropped := make([]uint64, len(dispatcher.subscribers))
+	for i, subscriber := range dispatcher.subscribers {
+		dropped[i] = atomic.LoadUint64(&subscriber.dropped)
+	}
+	return dropped
+}
+
+func (dispatcher *myErrorDispatcher) Len() uint32 {
+	dispatcher.mutex.Lock()
+	defer dispatcher.mutex.Unlock()
+	return uint32(dispatcher.size)
+}
+
+func (dispatcher *myErrorDispatcher) Cap() uint32 {
+	return uint32(len(dispatcher.buffer))
+}
+
+func (dispatcher *myErrorDispatcher) Close() bool {
+	dispatcher.mutex.Lock()
+	defer dispatcher.mutex.Unlock()
+	if dispatcher.closed {
+		return false
+	}
+	dispatcher.closed = true
+	dispatcher.notEmpty.Broadcast()
+	dispatcher.notFull.Broadcast()
+	return true
+}
+
+var errorDispatcherSummaryTemplate = "%d/%d, policy: %s, dropped: %d, subscribers: %d, subscriberDropped: %v"
+
+func (dispatcher *myErrorDispatcher) Summary() string {
+	dispatcher.mutex.Lock()
+	defer dispatcher.mutex.Unlock()
+	policyName, _ := base.OverflowPolicyName(dispatcher.policy)
+	return fmt.Sprintf(errorDispatcherSummaryTemplate,
+		dispatcher.size, len(dispatcher.buffer),
+		policyName, dispatcher.Dropped(), len(dispatcher.subscribers),
+		dispatcher.subscriberDropped())
+}
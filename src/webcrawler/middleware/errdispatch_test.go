@@ -0,0 +1,153 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+	base "webcrawler/base"
+)
+
+func putErrors(t *testing.T, dispatcher ErrorDispatcher, n int) {
+	for i := 0; i < n; i++ {
+		dispatcher.Put(errors.New(fmt.Sprintf("error-%d", i)))
+	}
+}
+
+func receiveErrors(ch <-chan error, n int) []string {
+	result := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		select {
+		case err := <-ch:
+			result = append(result, err.Error())
+		case <-time.After(time.Second):
+			return result
+		}
+	}
+	return result
+}
+
+func TestErrorDispatcherDropOldest(t *testing.T) {
+	dispatcher, err := NewErrorDispatcher(3, base.OVERFLOW_POLICY_DROP_OLDEST)
+	if err != nil {
+		t.Fatalf("Error dispatcher initialization failing: %s\n", err)
+	}
+	defer dispatcher.Close()
+	// 没有订阅方时，错误会被保留在缓冲区中。
+	putErrors(t, dispatcher, 5)
+	if dispatcher.Len() != 3 || dispatcher.Dropped() != 2 {
+		t.Fatalf("Unexpected state: %s\n", dispatcher.Summary())
+	}
+	ch, _ := dispatcher.Subscribe(3)
+	received := fmt.Sprint(receiveErrors(ch, 3))
+	if received != "[error-2 error-3 error-4]" {
+		t.Errorf("Unexpected errors %s!\n", received)
+	}
+}
+
+func TestErrorDispatcherDropNewest(t *testing.T) {
+	dispatcher, _ := NewErrorDispatcher(3, base.OVERFLOW_POLICY_DROP_NEWEST)
+	defer dispatcher.Close()
+	putErrors(t, dispatcher, 5)
+	if dispatcher.Dropped() != 2 {
+		t.Fatalf("Unexpected state: %s\n", dispatcher.Summary())
+	}
+	if dispatcher.Put(errors.New("error-5")) {
+		t.Errorf("The newest error should be dropped!\n")
+	}
+	ch, _ := dispatcher.Subscribe(3)
+	received := fmt.Sprint(receiveErrors(ch, 3))
+	if received != "[error-0 error-1 error-2]" {
+		t.Errorf("Unexpected errors %s!\n", received)
+	}
+}
+
+func TestErrorDispatcherBlock(t *testing.T) {
+	dispatcher, _ := NewErrorDispatcher(1, base.OVERFLOW_POLICY_BLOCK)
+	defer dispatcher.Close()
+	done := make(chan struct{})
+	go func() {
+		putErrors(t, dispatcher, 3)
+		close(done)
+	}()
+	select {
+	case <-done:
+		t.Fatalf("The sender should be blocked!\n")
+	case <-time.After(50 * time.Millisecond):
+	}
+	ch, _ := dispatcher.Subscribe(3)
+	received := fmt.Sprint(receiveErrors(ch, 3))
+	if received != "[error-0 error-1 error-2]" {
+		t.Errorf("Unexpected errors %s!\n", received)
+	}
+	<-done
+	if dispatcher.Dropped() != 0 {
+		t.Errorf("No error should be dropped!\n")
+	}
+}
+
+func TestErrorDispatcherSubscribers(t *testing.T) {
+	dispatcher, _ := NewErrorDispatcher(10, base.OVERFLOW_POLICY_DROP_OLDEST)
+	ch1, _ := dispatcher.Subscribe(10)
+	ch2, _ := dispatcher.Subscribe(10)
+	putErrors(t, dispatcher, 2)
+	for i, ch := range []<-chan error{ch1, ch2} {
+		received := fmt.Sprint(receiveErrors(ch, 2))
+		if received != "[error-0 error-1]" {
+			t.Errorf("Unexpected errors %s of subscriber [%d]!\n", received, i)
+		}
+	}
+	if !dispatcher.Close() || dispatcher.Close() {
+		t.Fatalf("The dispatcher should be closed only once!\n")
+	}
+	for i, ch := range []<-chan error{ch1, ch2} {
+		select {
+		case _, ok := <-ch:
+			if ok {
+				t.Errorf("The subscriber [%d] should be closed!\n", i)
+			}
+		case <-time.After(time.Second):
+			t.Errorf("The subscriber [%d] should be closed!\n", i)
+		}
+	}
+	if dispatcher.Put(errors.New("x")) {
+		t.Errorf("The closed dispatcher should not accept errors!\n")
+	}
+	if _, err := dispatcher.Subscribe(1); err == nil {
+		t.Errorf("The closed dispatcher should not accept subscribers!\n")
+	}
+}
+
+func TestErrorDispatcherSlowSubscriber(t *testing.T) {
+	dispatcher, _ := NewErrorDispatcher(1, base.OVERFLOW_POLICY_BLOCK)
+	defer dispatcher.Close()
+	if _, err := dispatcher.Subscribe(0); err == nil {
+		t.Errorf("The buffer length of subscriber should not be 0!\n")
+	}
+	// 无人读取的订阅通道既不应阻塞其他订阅方，也不应阻塞错误的发送方。
+	slow, _ := dispatcher.Subscribe(1)
+	fast, _ := dispatcher.Subscribe(10)
+	done := make(chan struct{})
+	go func() {
+		putErrors(t, dispatcher, 5)
+		close(done)
+	}()
+	received := fmt.Sprint(receiveErrors(fast, 5))
+	if received != "[error-0 error-1 error-2 error-3 error-4]" {
+		t.Errorf("Unexpected errors %s!\n", received)
+	}
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("The sender should not be blocked!\n")
+	}
+	if len(slow) != 1 {
+		t.Errorf("The slow subscriber should hold 1 error, but %d!\n", len(slow))
+	}
+	if dropped := fmt.Sprint(dispatcher.SubscriberDropped()); dropped != "[4 0]" {
+		t.Errorf("Unexpected dropped counts %s of subscribers!\n", dropped)
+	}
+	if dispatcher.Dropped() != 0 {
+		t.Errorf("No error should be dropped from the buffer!\n")
+	}
+}
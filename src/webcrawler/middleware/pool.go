@@ -1,125 +1,110 @@
-package middleware
-
-import (
-	"errors"
-	"fmt"
-	"reflect"
-	"sync"
-)
-
-// 实体的接口类型。
-type Entity interface {
-	Id() uint32 // ID的获取方法。
-}
-
-// 实体池的接口类型。
-type Pool interface {
-	Take() (Entity, error)      // 取出实体
-	Return(entity Entity) error // 归还实体。
-	Total() uint32              // 实体池的容量。
-	Used() uint32               // 实体池中已被使用的实体的数量。
-}
-
-// 创建实体池。
-func NewPool(
-	total uint32,
-	entityType reflect.Type,
-	genEntity func() Entity) (Pool, error) {
-	if total == 0 {
-		errMsg :=
-			fmt.Sprintf("The pool can not be initialized! (total=%d)\n", total)
-		return nil, errors.New(errMsg)
-	}
-	size := int(total)
-	container := make(chan Entity, size)
-	idContainer := make(map[uint32]bool)
-	for i := 0; i < size; i++ {
-		newEntity := genEntity()
-		if entityType != reflect.TypeOf(newEntity) {
-			errMsg :=
-				fmt.Sprintf("The type of result of function genEntity() is NOT %s!\n", entityType)
-			return nil, errors.New(errMsg)
-		}
-		container <- newEntity
-		idContainer[newEntity.Id()] = true
-	}
-	pool := &myPool{
-		total:       total,
-		etype:       entityType,
-		genEntity:   genEntity,
-		container:   container,
-		idContainer: idContainer,
-	}
-	return pool, nil
-}
-
-// 实体池的实现类型。
-type myPool struct {
-	total       uint32          // 池的总容量。
-	etype       reflect.Type    // 池中实体的类型。
-	genEntity   func() Entity   // 池中实体的生成函数。
-	container   chan Entity     // 实体容器。
-	idContainer map[uint32]bool // 实体ID的容器。
-	mutex       sync.Mutex      // 针对实体ID容器操作的互斥锁。
-}
-
-func (pool *myPool) Take() (Entity, error) {
-	entity, ok := <-pool.container
-	if !ok {
-		return nil, errors.New("The inner container is invalid!")
-	}
-	pool.mutex.Lock()
-	defer pool.mutex.Unlock()
-	pool.idContainer[entity.Id()] = false
-	return entity, nil
-}
-
-func (pool *myPool) Return(entity Entity) error {
-	if entity == nil {
-		return errors.New("The returning entity is invalid!")
-	}
-	if pool.etype != reflect.TypeOf(entity) {
-		errMsg := fmt.Sprintf("The type of returning entity is NOT %s!\n", pool.etype)
-		return errors.New(errMsg)
-	}
-	entityId := entity.Id()
-	casResult := pool.compareAndSetForIdContainer(entityId, false, true)
-	if casResult == 1 {
-		pool.container <- entity
-		return nil
-	} else if casResult == 0 {
-		errMsg := fmt.Sprintf("The entity (id=%d) is already in the pool!\n", entityId)
-		return errors.New(errMsg)
-	} else {
-		errMsg := fmt.Sprintf("The entity (id=%d) is illegal!\n", entityId)
-		return errors.New(errMsg)
-	}
-}
-
-// 比较并设置实体ID容器中与给定实体ID对应的键值对的元素值。
-// 结果值：
-//       -1：表示键值对不存在。
-//        0：表示操作失败。
-//        1：表示操作成功。
-func (pool *myPool) compareAndSetForIdContainer(
-	entityId uint32, oldValue bool, newValue bool) int8 {
-	pool.mutex.Lock()
-	defer pool.mutex.Unlock()
-	v, ok := pool.idContainer[entityId]
-	if !ok {
-		return -1
-	}
-	if v != oldValue {
-		return 0
-	}
-	pool.idContainer[entityId] = newValue
-	return 1
-}
-
-func (pool *myPool) Total() uint32 {
-	return pool.total
-}
-
-func (pool *myPool) Used() uint32 {
-	return pool.total - uint32(len(pool.container))
-}
+package middleware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"logging"
+	"reflect"
+	"time"
+	base "webcrawler/base"
+)
+
+// 日志记录器。
+var logger logging.Logger = base.NewLogger()
+
+// 实体的接口类型。
+type Entity interface {
+	Id() uint32 // ID的获取方法。
+}
+
+// 检查实体健康状况的函数类型。若实体不再可用，则应返回false。
+type CheckEntity func(entity Entity) bool
+
+// 实体池的统计信息。
+type PoolStats struct {
+	TakeCount    uint64        // 取出实体的次数。
+	WaitCount    uint64        // 取出实体时需要等待的次数。
+	TimeoutCount uint64        // 取出实体时超时或被取消的次数。
+	TotalWait    time.Duration // 取出实体时等待的总时长。
+	MaxWait      time.Duration // 取出实体时等待的最长时长。
+	ReplaceCount uint64        // 因不健康而被替换的实体的数量。
+}
+
+// 获得取出实体时的平均等待时长。只有需要等待的那些次会被计入。
+func (stats PoolStats) AvgWait() time.Duration {
+	if stats.WaitCount == 0 {
+		return 0
+	}
+	return stats.TotalWait / time.Duration(stats.WaitCount)
+}
+
+func (stats PoolStats) String() string {
+	return fmt.Sprintf("take: %d, wait: %d, timeout: %d, avgWait: %s, maxWait: %s, replace: %d",
+		stats.TakeCount, stats.WaitCount, stats.TimeoutCount,
+		stats.AvgWait(), stats.MaxWait, stats.ReplaceCount)
+}
+
+// 实体池的接口类型。
+// 与TypedPool不同，它需要在运行时检查实体的类型。新的代码应优先使用TypedPool。
+type Pool interface {
+	Take() (Entity, error) // 取出实体
+	// 取出实体。若在给定的时间内没有可用的实体，则返回错误。
+	TakeWithTimeout(timeout time.Duration) (Entity, error)
+	// 取出实体。若在上下文结束之前没有可用的实体，则返回错误。
+	// 该错误包装了上下文的错误（如context.DeadlineExceeded）。
+	TakeContext(ctx context.Context) (Entity, error)
+	Return(entity Entity) error // 归还实体。
+	Total() uint32              // 实体池的容量。
+	Used() uint32               // 实体池中已被使用的实体的数量。
+	// 设置健康检查函数。它会在实体被取出和归还时被调用，所以应尽量快速地返回。
+	// 不健康的实体会被淘汰，并由新生成的实体替换。参数check为nil时会禁用健康检查。
+	SetHealthCheck(check CheckEntity)
+	// 获得统计信息。
+	Stats() PoolStats
+	// 调整实体池的容量。
+	// 扩容时会立即生成新的实体。缩容时会先淘汰空闲的实体，
+	// 若仍不足，则正在被使用的实体会在归还时被淘汰。
+	Resize(total uint32) error
+}
+
+// 创建实体池。
+func NewPool(
+	total uint32,
+	entityType reflect.Type,
+	genEntity func() Entity) (Pool, error) {
+	checkNew := func(entity Entity) error {
+		if entityType != reflect.TypeOf(entity) {
+			errMsg :=
+				fmt.Sprintf("The type of result of function genEntity() is NOT %s!\n", entityType)
+			return errors.New(errMsg)
+		}
+		return nil
+	}
+	typedPool, err := newTypedPool(total, genEntity, checkNew)
+	if err != nil {
+		return nil, err
+	}
+	return &myPool{myTypedPool: typedPool, etype: entityType}, nil
+}
+
+// 实体池的实现类型。
+type myPool struct {
+	*myTypedPool[Entity]              // 类型化实体池。
+	etype                reflect.Type // 池中实体的类型。
+}
+
+func (pool *myPool) Return(entity Entity) error {
+	if entity == nil {
+		return errors.New("The returning entity is invalid!")
+	}
+	if pool.etype != reflect.TypeOf(entity) {
+		errMsg := fmt.Sprintf("The type of returning entity is NOT %s!\n", pool.etype)
+		return errors.New(errMsg)
+	}
+	return pool.myTypedPool.Return(entity)
+}
+
+func (pool *myPool) SetHealthCheck(check CheckEntity) {
+	pool.myTypedPool.SetHealthCheck(check)
+}
@@ -0,0 +1,161 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type testEntity struct {
+	id uint32
+}
+
+func (e *testEntity) Id() uint32 {
+	return e.id
+}
+
+func newTestPool(t *testing.T, total uint32) Pool {
+	idGen := NewIdGenerator()
+	pool, err := NewPool(total, reflect.TypeOf(&testEntity{}), func() Entity {
+		return &testEntity{id: idGen.GetUint32()}
+	})
+	if err != nil {
+		t.Fatalf("Pool initialization failing: %s\n", err)
+	}
+	return pool
+}
+
+func TestPoolTakeAndReturn(t *testing.T) {
+	pool := newTestPool(t, 2)
+	e1, _ := pool.Take()
+	e2, _ := pool.Take()
+	if pool.Used() != 2 {
+		t.Errorf("The used number should be 2, but %d!\n", pool.Used())
+	}
+	taken := make(chan Entity)
+	go func() {
+		e, _ := pool.Take()
+		taken <- e
+	}()
+	select {
+	case <-taken:
+		t.Fatalf("The taking should be blocked!\n")
+	case <-time.After(20 * time.Millisecond):
+	}
+	if err := pool.Return(e1); err != nil {
+		t.Fatalf("Return error: %s\n", err)
+	}
+	if e := <-taken; e.Id() != e1.Id() {
+		t.Errorf("The taken entity should be %d, but %d!\n", e1.Id(), e.Id())
+	}
+	if err := pool.Return(e2); err != nil {
+		t.Fatalf("Return error: %s\n", err)
+	}
+	if err := pool.Return(e2); err == nil {
+		t.Errorf("The repeated returning should be rejected!\n")
+	}
+	if err := pool.Return(&testEntity{id: 100}); err == nil {
+		t.Errorf("The illegal entity should be rejected!\n")
+	}
+}
+
+func TestPoolResize(t *testing.T) {
+	pool := newTestPool(t, 2)
+	if err := pool.Resize(4); err != nil {
+		t.Fatalf("Resize error: %s\n", err)
+	}
+	entities := make([]Entity, 0)
+	for i := 0; i < 4; i++ {
+		e, _ := pool.Take()
+		entities = append(entities, e)
+	}
+	if pool.Total() != 4 || pool.Used() != 4 {
+		t.Fatalf("Unexpected total %d and used %d!\n", pool.Total(), pool.Used())
+	}
+	// 所有实体都在被使用，所以缩容只能在归还时完成。
+	if err := pool.Resize(1); err != nil {
+		t.Fatalf("Resize error: %s\n", err)
+	}
+	for _, e := range entities {
+		if err := pool.Return(e); err != nil {
+			t.Fatalf("Return error: %s\n", err)
+		}
+	}
+	if pool.Total() != 1 || pool.Used() != 0 {
+		t.Fatalf("Unexpected total %d and used %d!\n", pool.Total(), pool.Used())
+	}
+	e, _ := pool.Take()
+	if e.Id() != entities[3].Id() {
+		t.Errorf("The last returned entity should be kept, but %d!\n", e.Id())
+	}
+	if err := pool.Return(entities[0]); err == nil {
+		t.Errorf("The retired entity should be rejected!\n")
+	}
+	if err := pool.Resize(0); err == nil {
+		t.Errorf("The zero total should be rejected!\n")
+	}
+}
+
+func TestPoolTakeWithTimeout(t *testing.T) {
+	pool := newTestPool(t, 1)
+	e, err := pool.TakeWithTimeout(10 * time.Millisecond)
+	if err != nil {
+		t.Fatalf("Take error: %s\n", err)
+	}
+	_, err = pool.TakeWithTimeout(10 * time.Millisecond)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("The error should wrap context.DeadlineExceeded, but %v!\n", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+	if _, err = pool.TakeContext(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("The error should wrap context.Canceled, but %v!\n", err)
+	}
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		pool.Return(e)
+	}()
+	if _, err = pool.TakeWithTimeout(time.Second); err != nil {
+		t.Fatalf("Take error: %s\n", err)
+	}
+	stats := pool.Stats()
+	if stats.TakeCount != 2 || stats.WaitCount != 1 || stats.TimeoutCount != 2 {
+		t.Errorf("Unexpected stats: %s\n", stats)
+	}
+	if stats.MaxWait <= 0 || stats.AvgWait() != stats.MaxWait {
+		t.Errorf("Unexpected wait time: %s\n", stats)
+	}
+}
+
+func TestPoolHealthCheck(t *testing.T) {
+	pool := newTestPool(t, 2)
+	unhealthy := map[uint32]bool{0: true}
+	pool.SetHealthCheck(func(entity Entity) bool {
+		return !unhealthy[entity.Id()]
+	})
+	e, _ := pool.Take()
+	if e.Id() != 2 {
+		t.Fatalf("The unhealthy entity should be replaced by entity 2, but %d!\n", e.Id())
+	}
+	unhealthy[2] = true
+	if err := pool.Return(e); err != nil {
+		t.Fatalf("Return error: %s\n", err)
+	}
+	ids := make(map[uint32]bool)
+	for i := 0; i < 2; i++ {
+		e, _ := pool.Take()
+		ids[e.Id()] = true
+	}
+	if !ids[1] || !ids[3] || len(ids) != 2 {
+		t.Errorf("Unexpected entities %v!\n", ids)
+	}
+	if pool.Total() != 2 || pool.Used() != 2 || pool.Stats().ReplaceCount != 2 {
+		t.Errorf("Unexpected total %d, used %d and stats %s!\n",
+			pool.Total(), pool.Used(), pool.Stats())
+	}
+}
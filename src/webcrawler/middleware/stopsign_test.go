@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestStopSignConcurrently(t *testing.T) {
+	ss := NewStopSign()
+	var wg sync.WaitGroup
+	var signedCount uint32
+	var mutex sync.Mutex
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			code := fmt.Sprintf("worker-%d", i%4)
+			for j := 0; j < 100; j++ {
+				if j == 50 && ss.Sign() {
+					mutex.Lock()
+					signedCount++
+					mutex.Unlock()
+				}
+				if ss.Signed() {
+					ss.Deal(code)
+				}
+				ss.DealCount(code)
+				ss.DealTotal()
+				ss.Summary()
+			}
+		}(i)
+	}
+	wg.Wait()
+	if signedCount != 1 {
+		t.Errorf("The stop sign should be signed only once, but %d times!\n", signedCount)
+	}
+	var total uint32
+	for i := 0; i < 4; i++ {
+		total += ss.DealCount(fmt.Sprintf("worker-%d", i))
+	}
+	if total != ss.DealTotal() || total == 0 {
+		t.Errorf("Unexpected deal total %d (sum=%d)!\n", ss.DealTotal(), total)
+	}
+	ss.Reset()
+	if ss.Signed() || ss.DealTotal() != 0 {
+		t.Errorf("The stop sign should be reset!\n")
+	}
+}
@@ -0,0 +1,283 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// 类型化实体池的接口类型。池中实体的类型由类型参数T确定，所以无需在运行时检查类型。
+type TypedPool[T Entity] interface {
+	Take() (T, error) // 取出实体
+	// 取出实体。若在给定的时间内没有可用的实体，则返回错误。
+	TakeWithTimeout(timeout time.Duration) (T, error)
+	// 取出实体。若在上下文结束之前没有可用的实体，则返回错误。
+	// 该错误包装了上下文的错误（如context.DeadlineExceeded）。
+	TakeContext(ctx context.Context) (T, error)
+	Return(entity T) error // 归还实体。
+	Total() uint32         // 实体池的容量。
+	Used() uint32          // 实体池中已被使用的实体的数量。
+	// 设置健康检查函数。它会在实体被取出和归还时被调用，所以应尽量快速地返回。
+	// 不健康的实体会被淘汰，并由新生成的实体替换。参数check为nil时会禁用健康检查。
+	SetHealthCheck(check func(entity T) bool)
+	// 获得统计信息。
+	Stats() PoolStats
+	// 调整实体池的容量。
+	// 扩容时会立即生成新的实体。缩容时会先淘汰空闲的实体，
+	// 若仍不足，则正在被使用的实体会在归还时被淘汰。
+	Resize(total uint32) error
+}
+
+// 创建类型化实体池。
+func NewTypedPool[T Entity](total uint32, genEntity func() T) (TypedPool[T], error) {
+	return newTypedPool(total, genEntity, nil)
+}
+
+// 创建类型化实体池。参数checkNew代表新实体的检查函数，可以为nil。
+func newTypedPool[T Entity](
+	total uint32,
+	genEntity func() T,
+	checkNew func(entity T) error) (*myTypedPool[T], error) {
+	if total == 0 {
+		errMsg :=
+			fmt.Sprintf("The pool can not be initialized! (total=%d)\n", total)
+		return nil, errors.New(errMsg)
+	}
+	if genEntity == nil {
+		return nil, errors.New("The entity generator is invalid!\n")
+	}
+	pool := &myTypedPool[T]{
+		genEntity:   genEntity,
+		checkNew:    checkNew,
+		idle:        make([]T, 0, total),
+		idContainer: make(map[uint32]bool),
+		avail:       make(chan struct{}),
+	}
+	if err := pool.Resize(total); err != nil {
+		return nil, err
+	}
+	return pool, nil
+}
+
+// 类型化实体池的实现类型。
+type myTypedPool[T Entity] struct {
+	total       uint32               // 池的总容量。
+	genEntity   func() T             // 池中实体的生成函数。
+	checkNew    func(entity T) error // 新实体的检查函数。
+	idle        []T                  // 空闲实体的容器。
+	idContainer map[uint32]bool      // 实体ID的容器。值为true表示实体在池中。
+	retiring    uint32               // 待淘汰的实体的数量。它们会在被归还时被淘汰。
+	avail       chan struct{}        // 可用通知通道。有实体被归还时会被关闭并替换。
+	check       func(entity T) bool  // 健康检查函数。
+	stats       PoolStats            // 统计信息。
+	mutex       sync.Mutex           // 针对实体池内部状态操作的互斥锁。
+}
+
+func (pool *myTypedPool[T]) Take() (T, error) {
+	return pool.TakeContext(context.Background())
+}
+
+func (pool *myTypedPool[T]) TakeWithTimeout(timeout time.Duration) (T, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return pool.TakeContext(ctx)
+}
+
+func (pool *myTypedPool[T]) TakeContext(ctx context.Context) (T, error) {
+	var zero T
+	var waited bool
+	startTime := time.Now()
+	for {
+		pool.mutex.Lock()
+		if len(pool.idle) > 0 {
+			entity := pool.idle[0]
+			pool.idle[0] = zero
+			pool.idle = pool.idle[1:]
+			if pool.check != nil && !pool.check(entity) {
+				newEntity, err := pool.replace(entity)
+				if err != nil {
+					pool.mutex.Unlock()
+					return zero, err
+				}
+				entity = newEntity
+			}
+			pool.idContainer[entity.Id()] = false
+			pool.recordTake(waited, time.Since(startTime))
+			pool.mutex.Unlock()
+			return entity, nil
+		}
+		avail := pool.avail
+		pool.mutex.Unlock()
+		waited = true
+		select {
+		case <-avail:
+		case <-ctx.Done():
+			pool.mutex.Lock()
+			pool.stats.TimeoutCount++
+			pool.mutex.Unlock()
+			return zero, fmt.Errorf("No entity is available in the pool: %w", ctx.Err())
+		}
+	}
+}
+
+// 记录取出实体的统计信息。调用方需持有互斥锁。
+func (pool *myTypedPool[T]) recordTake(waited bool, elapsed time.Duration) {
+	pool.stats.TakeCount++
+	if !waited {
+		return
+	}
+	pool.stats.WaitCount++
+	pool.stats.TotalWait += elapsed
+	if elapsed > pool.stats.MaxWait {
+		pool.stats.MaxWait = elapsed
+	}
+}
+
+// 淘汰不健康的实体并生成一个新的实体代替它。新的实体不会被放入容器。
+// 调用方需持有互斥锁。
+func (pool *myTypedPool[T]) replace(entity T) (T, error) {
+	delete(pool.idContainer, entity.Id())
+	newEntity, err := pool.generate()
+	if err != nil {
+		pool.total--
+		return newEntity, err
+	}
+	pool.stats.ReplaceCount++
+	logger.Warnf("Replace the unhealthy entity (id=%d) with a new one (id=%d).\n",
+		entity.Id(), newEntity.Id())
+	return newEntity, nil
+}
+
+// 生成新的实体并检查其ID。调用方需持有互斥锁。
+func (pool *myTypedPool[T]) generate() (T, error) {
+	var zero T
+	newEntity := pool.genEntity()
+	if isNilEntity(newEntity) {
+		return zero, errors.New("The result of function genEntity() is nil!\n")
+	}
+	if pool.checkNew != nil {
+		if err := pool.checkNew(newEntity); err != nil {
+			return zero, err
+		}
+	}
+	if _, ok := pool.idContainer[newEntity.Id()]; ok {
+		errMsg := fmt.Sprintf("The entity (id=%d) is repeated!\n", newEntity.Id())
+		return zero, errors.New(errMsg)
+	}
+	return newEntity, nil
+}
+
+func (pool *myTypedPool[T]) Return(entity T) error {
+	if isNilEntity(entity) {
+		return errors.New("The returning entity is invalid!")
+	}
+	entityId := entity.Id()
+	pool.mutex.Lock()
+	defer pool.mutex.Unlock()
+	v, ok := pool.idContainer[entityId]
+	if !ok {
+		errMsg := fmt.Sprintf("The entity (id=%d) is illegal!\n", entityId)
+		return errors.New(errMsg)
+	}
+	if v {
+		errMsg := fmt.Sprintf("The entity (id=%d) is already in the pool!\n", entityId)
+		return errors.New(errMsg)
+	}
+	if pool.retiring > 0 {
+		pool.retiring--
+		delete(pool.idContainer, entityId)
+		return nil
+	}
+	if pool.check != nil && !pool.check(entity) {
+		newEntity, err := pool.replace(entity)
+		if err != nil {
+			return err
+		}
+		entity = newEntity
+	}
+	pool.idContainer[entity.Id()] = true
+	pool.putIdle(entity)
+	return nil
+}
+
+// 放入空闲实体并通知等待方。调用方需持有互斥锁。
+func (pool *myTypedPool[T]) putIdle(entity T) {
+	pool.idle = append(pool.idle, entity)
+	close(pool.avail)
+	pool.avail = make(chan struct{})
+}
+
+func (pool *myTypedPool[T]) Resize(total uint32) error {
+	if total == 0 {
+		errMsg := fmt.Sprintf("The pool can not be resized! (total=%d)\n", total)
+		return errors.New(errMsg)
+	}
+	pool.mutex.Lock()
+	defer pool.mutex.Unlock()
+	current := uint32(len(pool.idContainer)) - pool.retiring
+	if total > current {
+		// 先撤销待淘汰的实体，再生成新的实体。
+		delta := total - current
+		if pool.retiring >= delta {
+			pool.retiring -= delta
+			delta = 0
+		} else {
+			delta -= pool.retiring
+			pool.retiring = 0
+		}
+		for i := uint32(0); i < delta; i++ {
+			newEntity, err := pool.generate()
+			if err != nil {
+				pool.total = uint32(len(pool.idContainer)) - pool.retiring
+				return err
+			}
+			pool.idContainer[newEntity.Id()] = true
+			pool.putIdle(newEntity)
+		}
+	} else if total < current {
+		// 先淘汰空闲的实体，不足的部分会在归还时淘汰。
+		var zero T
+		delta := current - total
+		for delta > 0 && len(pool.idle) > 0 {
+			last := len(pool.idle) - 1
+			delete(pool.idContainer, pool.idle[last].Id())
+			pool.idle[last] = zero
+			pool.idle = pool.idle[:last]
+			delta--
+		}
+		pool.retiring += delta
+	}
+	pool.total = total
+	return nil
+}
+
+func (pool *myTypedPool[T]) SetHealthCheck(check func(entity T) bool) {
+	pool.mutex.Lock()
+	defer pool.mutex.Unlock()
+	pool.check = check
+}
+
+func (pool *myTypedPool[T]) Stats() PoolStats {
+	pool.mutex.Lock()
+	defer pool.mutex.Unlock()
+	return pool.stats
+}
+
+func (pool *myTypedPool[T]) Total() uint32 {
+	pool.mutex.Lock()
+	defer pool.mutex.Unlock()
+	return pool.total
+}
+
+func (pool *myTypedPool[T]) Used() uint32 {
+	pool.mutex.Lock()
+	defer pool.mutex.Unlock()
+	return uint32(len(pool.idContainer) - len(pool.idle))
+}
+
+// 判断实体是否为nil。
+func isNilEntity[T Entity](entity T) bool {
+	return any(entity) == nil
+}
@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"testing"
+)
+
+type namedEntity interface {
+	Entity
+	Name() string
+}
+
+type myNamedEntity struct {
+	id uint32
+}
+
+func (e *myNamedEntity) Id() uint32 {
+	return e.id
+}
+
+func (e *myNamedEntity) Name() string {
+	return "named"
+}
+
+func TestTypedPool(t *testing.T) {
+	idGen := NewIdGenerator()
+	pool, err := NewTypedPool(2, func() namedEntity {
+		return &myNamedEntity{id: idGen.GetUint32()}
+	})
+	if err != nil {
+		t.Fatalf("Typed pool initialization failing: %s\n", err)
+	}
+	e, err := pool.Take()
+	if err != nil {
+		t.Fatalf("Take error: %s\n", err)
+	}
+	if e.Name() != "named" {
+		t.Errorf("Unexpected entity %v!\n", e)
+	}
+	if err := pool.Return(e); err != nil {
+		t.Fatalf("Return error: %s\n", err)
+	}
+	if err := pool.Return(e); err == nil {
+		t.Errorf("The repeated returning should be rejected!\n")
+	}
+	if err := pool.Return(nil); err == nil {
+		t.Errorf("The nil entity should be rejected!\n")
+	}
+	if err := pool.Return(&myNamedEntity{id: 100}); err == nil {
+		t.Errorf("The illegal entity should be rejected!\n")
+	}
+	if _, err := NewTypedPool(1, func() namedEntity { return nil }); err == nil {
+		t.Errorf("The nil generated entity should be rejected!\n")
+	}
+	if _, err := NewTypedPool[namedEntity](0, nil); err == nil {
+		t.Errorf("The zero total should be rejected!\n")
+	}
+}
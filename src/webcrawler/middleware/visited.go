@@ -0,0 +1,76 @@
+package middleware
+
+import (
+	"fmt"
+	"sync"
+)
+
+// 已访问集合的接口类型。调度器用它来记录已请求过的URL。
+// 它的所有方法都是并发安全的。
+type VisitedSet interface {
+	// 添加键。若键在此之前不存在，则结果值为true，否则为false。
+	// 判断与添加是一个原子操作。
+	Add(key string) bool
+	// 判断键是否存在。
+	Contains(key string) bool
+	// 获得已添加的键的数量。
+	Len() uint64
+	// 获得所有的键。若该实现不支持枚举（如布隆过滤器），则结果值为nil。
+	Keys() []string
+	// 关闭集合并释放其占用的资源。
+	Close() error
+	// 获取摘要信息。
+	Summary() string
+}
+
+// 创建基于内存的已访问集合。
+func NewMemoryVisitedSet() VisitedSet {
+	return &memoryVisitedSet{keyMap: make(map[string]struct{})}
+}
+
+// 基于内存的已访问集合的实现类型。
+type memoryVisitedSet struct {
+	keyMap  map[string]struct{} // 键的字典。
+	rwmutex sync.RWMutex        // 读写锁。
+}
+
+func (set *memoryVisitedSet) Add(key string) bool {
+	set.rwmutex.Lock()
+	defer set.rwmutex.Unlock()
+	if _, ok := set.keyMap[key]; ok {
+		return false
+	}
+	set.keyMap[key] = struct{}{}
+	return true
+}
+
+func (set *memoryVisitedSet) Contains(key string) bool {
+	set.rwmutex.RLock()
+	defer set.rwmutex.RUnlock()
+	_, ok := set.keyMap[key]
+	return ok
+}
+
+func (set *memoryVisitedSet) Len() uint64 {
+	set.rwmutex.RLock()
+	defer set.rwmutex.RUnlock()
+	return uint64(len(set.keyMap))
+}
+
+func (set *memoryVisitedSet) Keys() []string {
+	set.rwmutex.RLock()
+	defer set.rwmutex.RUnlock()
+	keys := make([]string, 0, len(set.keyMap))
+	for key := range set.keyMap {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+func (set *memoryVisitedSet) Close() error {
+	return nil
+}
+
+func (set *memoryVisitedSet) Summary() string {
+	return fmt.Sprintf("memory, length: %d", set.Len())
+}
@@ -0,0 +1,86 @@
+package middleware
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+)
+
+func testVisitedSet(t *testing.T, set VisitedSet, number int, exact bool) {
+	for i := 0; i < number; i++ {
+		key := fmt.Sprintf("http://example.com/page/%d", i)
+		if !set.Add(key) && exact {
+			t.Fatalf("The key '%s' is regarded as existing before adding!", key)
+		}
+		if set.Add(key) {
+			t.Fatalf("The key '%s' is added twice!", key)
+		}
+	}
+	for i := 0; i < number; i++ {
+		key := fmt.Sprintf("http://example.com/page/%d", i)
+		if !set.Contains(key) {
+			t.Fatalf("The key '%s' is not found!", key)
+		}
+	}
+	if exact {
+		if set.Len() != uint64(number) {
+			t.Fatalf("Unexpected length %d, expected %d.", set.Len(), number)
+		}
+		for i := 0; i < number; i++ {
+			key := fmt.Sprintf("http://example.com/other/%d", i)
+			if set.Contains(key) {
+				t.Fatalf("The key '%s' is found but never added!", key)
+			}
+		}
+	}
+}
+
+func TestMemoryVisitedSet(t *testing.T) {
+	set := NewMemoryVisitedSet()
+	defer set.Close()
+	testVisitedSet(t, set, 1000, true)
+}
+
+func TestBloomVisitedSet(t *testing.T) {
+	fpRate := 0.01
+	set, err := NewBloomVisitedSet(100, fpRate)
+	if err != nil {
+		t.Fatalf("Create bloom visited set error: %s", err)
+	}
+	defer set.Close()
+	number := 5000
+	testVisitedSet(t, set, number, false)
+	var falsePositive int
+	for i := 0; i < number; i++ {
+		if set.Contains(fmt.Sprintf("http://example.com/other/%d", i)) {
+			falsePositive++
+		}
+	}
+	if rate := float64(falsePositive) / float64(number); rate > fpRate*2 {
+		t.Fatalf("The false positive rate %f is too high! (expected <= %f)", rate, fpRate)
+	}
+	if set.Keys() != nil {
+		t.Fatalf("The bloom visited set should not support enumerating keys!")
+	}
+}
+
+func TestDiskVisitedSet(t *testing.T) {
+	set, err := NewDiskVisitedSet(t.TempDir(), 50)
+	if err != nil {
+		t.Fatalf("Create disk visited set error: %s", err)
+	}
+	defer set.Close()
+	// 足以触发多次溢出和合并。
+	number := 1000
+	testVisitedSet(t, set, number, true)
+	keys := set.Keys()
+	if len(keys) != number {
+		t.Fatalf("Unexpected key number %d, expected %d.", len(keys), number)
+	}
+	sort.Strings(keys)
+	for i := 1; i < len(keys); i++ {
+		if keys[i] == keys[i-1] {
+			t.Fatalf("The key '%s' is repeated!", keys[i])
+		}
+	}
+}
@@ -0,0 +1,274 @@
+package recrawl
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"logging"
+	"net/http"
+	"os"
+	"sort"
+	"sync"
+	"time"
+	base "webcrawler/base"
+)
+
+// 日志记录器。
+var logger logging.Logger = base.NewLogger()
+
+// 网页的访问记录。
+type PageRecord struct {
+	Url          string        `json:"url"`                    // URL。
+	Depth        uint32        `json:"depth"`                  // 请求的深度。
+	ETag         string        `json:"etag,omitempty"`         // 响应头ETag的值。
+	LastModified string        `json:"lastModified,omitempty"` // 响应头Last-Modified的值。
+	ContentHash  string        `json:"contentHash,omitempty"`  // 网页内容的散列值。
+	LastVisit    time.Time     `json:"lastVisit"`              // 最近一次访问的时间。
+	NextVisit    time.Time     `json:"nextVisit"`              // 下一次访问的时间。
+	Interval     time.Duration `json:"interval"`               // 访问的间隔时间。
+	Visits       uint32        `json:"visits"`                 // 访问的次数。
+	Changes      uint32        `json:"changes"`                // 观察到的内容变化的次数。
+}
+
+// 增量爬取存储的接口类型。它记录每个网页（仅限GET请求）的验证信息和内容散列值，
+// 以便在重新爬取时发出条件请求并识别未变化的网页。它的所有方法都是并发安全的。
+type Store interface {
+	// 为请求添加条件请求头（If-None-Match和If-Modified-Since）。
+	Prepare(req *base.Request)
+	// 根据响应更新访问记录。结果值代表网页内容是否有变化。
+	// 对于状态码为304或内容与上次相同的响应，结果值为false。
+	// 首次访问的网页总被视为有变化。
+	Update(resp *base.Response) (changed bool, err error)
+	// 获得访问记录。
+	Get(url string) (PageRecord, bool)
+	// 获得在给定时间已到期需要重新访问的网页的访问记录。结果值按到期时间排序。
+	Due(now time.Time) []PageRecord
+	// 获得访问记录的数量。
+	Len() uint32
+	// 把访问记录保存到文件。
+	Save() error
+	// 获取摘要信息。
+	Summary() string
+}
+
+// 创建增量爬取存储。
+// 参数path代表访问记录文件的路径。若该文件已存在，则会从中加载先前的访问记录。
+// 参数path为空时不会进行持久化。
+// 参数minInterval和maxInterval代表重新访问的间隔时间的下限和上限。
+// 网页内容每变化一次，间隔时间就会减半；反之则会加倍。
+func NewStore(path string, minInterval, maxInterval time.Duration) (Store, error) {
+	if minInterval <= 0 || maxInterval < minInterval {
+		errMsg := fmt.Sprintf("The recrawl store can not be initialized! (minInterval=%s, maxInterval=%s)\n",
+			minInterval, maxInterval)
+		return nil, errors.New(errMsg)
+	}
+	store := &myStore{
+		path:        path,
+		minInterval: minInterval,
+		maxInterval: maxInterval,
+		records:     make(map[string]*PageRecord),
+	}
+	if path != "" {
+		if err := store.load(); err != nil {
+			return nil, err
+		}
+	}
+	return store, nil
+}
+
+// 增量爬取存储的实现类型。
+type myStore struct {
+	path        string                 // 访问记录文件的路径。
+	minInterval time.Duration          // 间隔时间的下限。
+	maxInterval time.Duration          // 间隔时间的上限。
+	records     map[string]*PageRecord // 访问记录的字典。
+	notModified uint64                 // 收到的状态码为304的响应的数量。
+	unchanged   uint64                 // 内容未变化的响应的数量。
+	changed     uint64                 // 内容有变化（包括首次访问）的响应的数量。
+	mutex       sync.Mutex             // 互斥锁。
+}
+
+// 从文件加载访问记录。
+func (store *myStore) load() error {
+	data, err := ioutil.ReadFile(store.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	var records []*PageRecord
+	if err := json.Unmarshal(data, &records); err != nil {
+		return err
+	}
+	for _, record := range records {
+		store.records[record.Url] = record
+	}
+	return nil
+}
+
+func (store *myStore) Prepare(req *base.Request) {
+	httpReq := req.HttpReq()
+	if httpReq == nil || httpReq.URL == nil || !isGet(httpReq) {
+		return
+	}
+	store.mutex.Lock()
+	record, ok := store.records[httpReq.URL.String()]
+	var etag, lastModified string
+	if ok {
+		etag, lastModified = record.ETag, record.LastModified
+	}
+	store.mutex.Unlock()
+	if etag != "" {
+		httpReq.Header.Set("If-None-Match", etag)
+	}
+	if lastModified != "" {
+		httpReq.Header.Set("If-Modified-Since", lastModified)
+	}
+}
+
+func (store *myStore) Update(resp *base.Response) (bool, error) {
+	httpResp := resp.HttpResp()
+	if httpResp == nil || httpResp.Request == nil || httpResp.Request.URL == nil {
+		return true, errors.New("The HTTP response is invalid!")
+	}
+	if !isGet(httpResp.Request) {
+		// 只有GET请求的响应才会被记录。
+		return true, nil
+	}
+	notModified := httpResp.StatusCode == http.StatusNotModified
+	if !notModified && (httpResp.StatusCode < 200 || httpResp.StatusCode >= 300) {
+		// 只有成功的响应才会被记录。
+		return true, nil
+	}
+	var contentHash string
+	if !notModified && httpResp.Body != nil {
+		body, err := ioutil.ReadAll(httpResp.Body)
+		httpResp.Body.Close()
+		httpResp.Body = ioutil.NopCloser(bytes.NewReader(body))
+		if err != nil {
+			return true, err
+		}
+		sum := sha256.Sum256(body)
+		contentHash = hex.EncodeToString(sum[:])
+	}
+	url := httpResp.Request.URL.String()
+	now := time.Now()
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	record, ok := store.records[url]
+	if !ok {
+		record = &PageRecord{Url: url, Interval: store.minInterval}
+		store.records[url] = record
+	}
+	changed := !notModified && (!ok || record.ContentHash != contentHash)
+	if changed {
+		if ok {
+			record.Changes++
+			record.Interval /= 2
+		}
+		store.changed++
+	} else {
+		record.Interval *= 2
+		if notModified {
+			store.notModified++
+		} else {
+			store.unchanged++
+		}
+	}
+	if record.Interval < store.minInterval {
+		record.Interval = store.minInterval
+	}
+	if record.Interval > store.maxInterval {
+		record.Interval = store.maxInterval
+	}
+	if !notModified {
+		record.ContentHash = contentHash
+		record.ETag = httpResp.Header.Get("ETag")
+		record.LastModified = httpResp.Header.Get("Last-Modified")
+	}
+	record.Depth = resp.Depth()
+	record.Visits++
+	record.LastVisit = now
+	record.NextVisit = now.Add(record.Interval)
+	return changed, nil
+}
+
+// 判断HTTP请求是否为GET请求。
+func isGet(httpReq *http.Request) bool {
+	return httpReq.Method == "" || httpReq.Method == "GET"
+}
+
+func (store *myStore) Get(url string) (PageRecord, bool) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	record, ok := store.records[url]
+	if !ok {
+		return PageRecord{}, false
+	}
+	return *record, true
+}
+
+func (store *myStore) Due(now time.Time) []PageRecord {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	var due []PageRecord
+	for _, record := range store.records {
+		if !record.NextVisit.After(now) {
+			due = append(due, *record)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool {
+		return due[i].NextVisit.Before(due[j].NextVisit)
+	})
+	return due
+}
+
+func (store *myStore) Len() uint32 {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	return uint32(len(store.records))
+}
+
+func (store *myStore) Save() error {
+	if store.path == "" {
+		return nil
+	}
+	store.mutex.Lock()
+	records := make([]*PageRecord, 0, len(store.records))
+	for _, record := range store.records {
+		copied := *record
+		records = append(records, &copied)
+	}
+	store.mutex.Unlock()
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].Url < records[j].Url
+	})
+	data, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		return err
+	}
+	// 先写入临时文件再重命名，以免在写入过程中出错时损坏原有的文件。
+	tempPath := store.path + ".tmp"
+	if err := ioutil.WriteFile(tempPath, data, 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tempPath, store.path); err != nil {
+		return err
+	}
+	logger.Infof("Saved %d page records to file '%s'.\n", len(records), store.path)
+	return nil
+}
+
+var storeSummaryTemplate = "records: %d, changed: %d, unchanged: %d, notModified: %d"
+
+func (store *myStore) Summary() string {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	return fmt.Sprintf(storeSummaryTemplate,
+		len(store.records), store.changed, store.unchanged, store.notModified)
+}
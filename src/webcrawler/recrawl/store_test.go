@@ -0,0 +1,94 @@
+package recrawl
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+	base "webcrawler/base"
+)
+
+// 模拟网页。ETag由内容决定，并且支持If-None-Match。
+type testPage struct {
+	content string
+}
+
+func (page *testPage) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	etag := fmt.Sprintf("\"%d\"", len(page.content))
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("ETag", etag)
+	fmt.Fprint(w, page.content)
+}
+
+func visit(t *testing.T, store Store, url string) bool {
+	httpReq, _ := http.NewRequest("GET", url, nil)
+	req := base.NewRequest(httpReq, 1)
+	store.Prepare(req)
+	httpResp, err := http.DefaultClient.Do(httpReq)
+	if err != nil {
+		t.Fatalf("Request error: %s", err)
+	}
+	defer httpResp.Body.Close()
+	changed, err := store.Update(base.NewResponse(httpResp, req.Depth()))
+	if err != nil {
+		t.Fatalf("Update error: %s", err)
+	}
+	return changed
+}
+
+func TestStore(t *testing.T) {
+	page := &testPage{content: "hello"}
+	server := httptest.NewServer(page)
+	defer server.Close()
+	path := filepath.Join(t.TempDir(), "records.json")
+	minInterval, maxInterval := time.Minute, time.Hour
+	store, err := NewStore(path, minInterval, maxInterval)
+	if err != nil {
+		t.Fatalf("Create store error: %s", err)
+	}
+
+	if !visit(t, store, server.URL) {
+		t.Fatalf("The first visit should be regarded as changed!")
+	}
+	if visit(t, store, server.URL) {
+		t.Fatalf("The page should be regarded as unchanged after a 304 response!")
+	}
+	record, _ := store.Get(server.URL)
+	if record.Interval != 2*minInterval || record.Visits != 2 {
+		t.Fatalf("Unexpected record: %+v", record)
+	}
+	page.content = "hello, world"
+	if !visit(t, store, server.URL) {
+		t.Fatalf("The page should be regarded as changed!")
+	}
+	record, _ = store.Get(server.URL)
+	if record.Interval != minInterval || record.Changes != 1 {
+		t.Fatalf("Unexpected record: %+v", record)
+	}
+	if due := store.Due(time.Now()); len(due) != 0 {
+		t.Fatalf("Unexpected due records: %v", due)
+	}
+	if due := store.Due(time.Now().Add(minInterval)); len(due) != 1 {
+		t.Fatalf("Unexpected due records: %v", due)
+	}
+
+	if err := store.Save(); err != nil {
+		t.Fatalf("Save error: %s", err)
+	}
+	loaded, err := NewStore(path, minInterval, maxInterval)
+	if err != nil {
+		t.Fatalf("Load store error: %s", err)
+	}
+	loadedRecord, ok := loaded.Get(server.URL)
+	if !ok || loadedRecord.ETag != record.ETag || loadedRecord.ContentHash != record.ContentHash {
+		t.Fatalf("Unexpected loaded record: %+v", loadedRecord)
+	}
+	if visit(t, loaded, server.URL) {
+		t.Fatalf("The page should be regarded as unchanged after reloading!")
+	}
+}
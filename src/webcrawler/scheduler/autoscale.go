@@ -0,0 +1,108 @@
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+	base "webcrawler/base"
+)
+
+// 网页下载器池的自动伸缩器。
+type autoScaler struct {
+	args         base.AutoScaleArgs // 自动伸缩参数的容器。
+	latencySum   int64              // 当前检查周期内的下载耗时的总和，单位：纳秒。
+	latencyCount int64              // 当前检查周期内的下载次数。
+	lastLatency  int64              // 上一个检查周期的平均下载耗时，单位：纳秒。
+	scaleCount   uint64             // 调整池的尺寸的次数。
+}
+
+// 创建自动伸缩器。
+func newAutoScaler(args base.AutoScaleArgs) *autoScaler {
+	return &autoScaler{args: args}
+}
+
+// 记录一次下载的耗时。
+func (as *autoScaler) record(elapsed time.Duration) {
+	atomic.AddInt64(&as.latencySum, int64(elapsed))
+	atomic.AddInt64(&as.latencyCount, 1)
+}
+
+// 取出当前检查周期内的平均下载耗时，并开始一个新的检查周期。
+func (as *autoScaler) takeAvgLatency() time.Duration {
+	sum := atomic.SwapInt64(&as.latencySum, 0)
+	count := atomic.SwapInt64(&as.latencyCount, 0)
+	var avg int64
+	if count > 0 {
+		avg = sum / count
+	}
+	atomic.StoreInt64(&as.lastLatency, avg)
+	return time.Duration(avg)
+}
+
+// 根据池的当前尺寸、待下载的请求的数量以及平均下载耗时计算池的新尺寸。
+func (as *autoScaler) decide(current uint32, queueLen int, avgLatency time.Duration) uint32 {
+	args := &as.args
+	target := current
+	switch {
+	case avgLatency > args.LatencyThreshold() ||
+		queueLen < int(args.QueueLowWatermark()):
+		if target > args.Step() {
+			target -= args.Step()
+		} else {
+			target = 0
+		}
+	case queueLen > int(args.QueueHighWatermark()):
+		target += args.Step()
+	}
+	if target < args.MinPoolSize() {
+		target = args.MinPoolSize()
+	}
+	if target > args.MaxPoolSize() {
+		target = args.MaxPoolSize()
+	}
+	if target != current {
+		atomic.AddUint64(&as.scaleCount, 1)
+	}
+	return target
+}
+
+// 获取自动伸缩器的摘要信息。
+func (as *autoScaler) summary() string {
+	return fmt.Sprintf("args: %s, lastLatency: %s, scaleCount: %d",
+		as.args.String(),
+		time.Duration(atomic.LoadInt64(&as.lastLatency)),
+		atomic.LoadUint64(&as.scaleCount))
+}
+
+// 自动伸缩网页下载器池。
+func (sched *myScheduler) autoScale() {
+	as := sched.autoScaler
+	go func() {
+		defer func() {
+			if p := recover(); p != nil {
+				errMsg := fmt.Sprintf("Fatal Auto Scaling Error: %s\n", p)
+				logger.Fatal(errMsg)
+			}
+		}()
+		for {
+			time.Sleep(as.args.Interval())
+			if sched.stopSign.Signed() {
+				sched.stopSign.Deal(SCHEDULER_CODE)
+				return
+			}
+			queueLen := sched.reqCache.length() + len(sched.getReqChan())
+			current := sched.dlpool.Total()
+			target := as.decide(current, queueLen, as.takeAvgLatency())
+			if target == current {
+				continue
+			}
+			logger.Infof("Resize the page downloader pool from %d to %d. (queueLength=%d)\n",
+				current, target, queueLen)
+			if err := sched.dlpool.Resize(target); err != nil {
+				errMsg := fmt.Sprintf("Downloader pool error: %s", err)
+				sched.sendError(errors.New(errMsg), SCHEDULER_CODE, "", 0)
+			}
+		}
+	}()
+}
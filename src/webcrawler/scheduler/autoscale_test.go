@@ -0,0 +1,42 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+	base "webcrawler/base"
+)
+
+func TestAutoScalerDecide(t *testing.T) {
+	args := base.NewAutoScaleArgs(2, 8, 2, time.Second, 20, 5, 500*time.Millisecond)
+	if err := args.Check(); err != nil {
+		t.Fatalf("Invalid auto scaling args: %s\n", err)
+	}
+	as := newAutoScaler(args)
+	cases := []struct {
+		current    uint32
+		queueLen   int
+		avgLatency time.Duration
+		expected   uint32
+	}{
+		{4, 30, 100 * time.Millisecond, 6},
+		{8, 30, 100 * time.Millisecond, 8},
+		{4, 30, time.Second, 2},
+		{4, 10, 100 * time.Millisecond, 4},
+		{4, 1, 100 * time.Millisecond, 2},
+		{2, 1, 100 * time.Millisecond, 2},
+		{1, 10, 100 * time.Millisecond, 2},
+	}
+	for i, c := range cases {
+		if target := as.decide(c.current, c.queueLen, c.avgLatency); target != c.expected {
+			t.Errorf("The target size of case [%d] should be %d, but %d!\n", i, c.expected, target)
+		}
+	}
+	as.record(100 * time.Millisecond)
+	as.record(300 * time.Millisecond)
+	if avg := as.takeAvgLatency(); avg != 200*time.Millisecond {
+		t.Errorf("The average latency should be 200ms, but %s!\n", avg)
+	}
+	if avg := as.takeAvgLatency(); avg != 0 {
+		t.Errorf("The average latency should be reset, but %s!\n", avg)
+	}
+}
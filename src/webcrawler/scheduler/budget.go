@@ -0,0 +1,277 @@
+package scheduler
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	base "webcrawler/base"
+)
+
+// 预算耗尽的原因。
+const (
+	BUDGET_PAGES    = "pages"    // 网页数已达上限。
+	BUDGET_BYTES    = "bytes"    // 下载字节数已达上限。
+	BUDGET_DURATION = "duration" // 爬取时间已达上限。
+	BUDGET_ITEMS    = "items"    // 条目数已达上限。
+)
+
+// 域名的预算使用情况。
+type domainUsage struct {
+	quota     base.DomainQuota // 域名配额。
+	pages     uint64           // 已接受的网页数。
+	bytes     uint64           // 已下载的字节数。
+	exhausted string           // 配额耗尽的原因。空字符串表示未耗尽。
+}
+
+// 爬取预算。它会记录全局的以及各个域名的预算使用情况。它的所有方法都是并发安全的。
+type crawlBudget struct {
+	args        base.BudgetArgs         // 爬取预算参数的容器。
+	domains     []string                // 按长度从长到短排序的配额域名的列表。
+	onExhausted func(reason string)     // 全局预算耗尽时被调用的函数。它只会被调用一次。
+	pages       uint64                  // 已接受的网页数。
+	bytes       uint64                  // 已下载的字节数。
+	items       uint64                  // 已接受的条目数。
+	refused     uint64                  // 因预算耗尽而被拒绝的请求的数量。
+	skipped     uint64                  // 因预算耗尽而未被下载的请求的数量。
+	dropped     uint64                  // 因预算耗尽而被丢弃的条目的数量。
+	exhausted   string                  // 全局预算耗尽的原因。空字符串表示未耗尽。
+	usageMap    map[string]*domainUsage // 配额键与域名的预算使用情况的映射。
+	timer       *time.Timer             // 针对最长爬取时间的定时器。
+	mutex       sync.Mutex              // 互斥锁。
+}
+
+// 创建爬取预算。参数onExhausted会在全局预算耗尽时被异步地调用。
+func newCrawlBudget(args base.BudgetArgs, onExhausted func(reason string)) *crawlBudget {
+	budget := &crawlBudget{
+		args:        args,
+		onExhausted: onExhausted,
+		usageMap:    make(map[string]*domainUsage),
+	}
+	for domain := range args.DomainQuotas() {
+		budget.domains = append(budget.domains, domain)
+	}
+	// 先匹配较长的域名，以使更具体的域名的配额优先。
+	sort.Slice(budget.domains, func(i, j int) bool {
+		if len(budget.domains[i]) != len(budget.domains[j]) {
+			return len(budget.domains[i]) > len(budget.domains[j])
+		}
+		return budget.domains[i] < budget.domains[j]
+	})
+	return budget
+}
+
+// 开始计时。
+func (budget *crawlBudget) start() {
+	if budget.args.MaxDuration() <= 0 {
+		return
+	}
+	budget.mutex.Lock()
+	defer budget.mutex.Unlock()
+	budget.timer = time.AfterFunc(budget.args.MaxDuration(), func() {
+		budget.mutex.Lock()
+		defer budget.mutex.Unlock()
+		budget.exhaustLocked(BUDGET_DURATION)
+	})
+}
+
+// 停止计时。
+func (budget *crawlBudget) stop() {
+	budget.mutex.Lock()
+	defer budget.mutex.Unlock()
+	if budget.timer != nil {
+		budget.timer.Stop()
+	}
+}
+
+// 获得URL对应的主机名。
+func budgetHost(u *url.URL) string {
+	return strings.ToLower(u.Hostname())
+}
+
+// 获得主机的预算使用情况。
+// 若有针对该主机或其上级域名的配额，则使用该配额，否则为该主机使用默认的域名配额。
+func (budget *crawlBudget) usageLocked(host string) *domainUsage {
+	key := host
+	quota := budget.args.DomainQuota()
+	for _, domain := range budget.domains {
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			key = domain
+			quota = budget.args.DomainQuotas()[domain]
+			break
+		}
+	}
+	usage, ok := budget.usageMap[key]
+	if !ok {
+		usage = &domainUsage{quota: quota}
+		budget.usageMap[key] = usage
+	}
+	return usage
+}
+
+// 使全局预算耗尽。
+func (budget *crawlBudget) exhaustLocked(reason string) {
+	if budget.exhausted != "" {
+		return
+	}
+	budget.exhausted = reason
+	if budget.onExhausted != nil {
+		go budget.onExhausted(reason)
+	}
+}
+
+// 接受针对给定主机的请求。若全局预算或该主机的配额已耗尽，则结果值为false。
+func (budget *crawlBudget) admit(host string) bool {
+	budget.mutex.Lock()
+	defer budget.mutex.Unlock()
+	usage := budget.usageLocked(host)
+	if budget.exhausted != "" || usage.exhausted != "" {
+		budget.refused++
+		return false
+	}
+	budget.pages++
+	usage.pages++
+	if usage.quota.MaxPages > 0 && usage.pages >= usage.quota.MaxPages {
+		usage.exhausted = BUDGET_PAGES
+	}
+	if max := budget.args.MaxPages(); max > 0 && budget.pages >= max {
+		budget.exhaustLocked(BUDGET_PAGES)
+	}
+	return true
+}
+
+// 判断是否还应该下载针对给定主机的已被接受的请求。
+// 只有在因下载字节数、条目数或爬取时间而耗尽预算时，结果值才会为false。
+func (budget *crawlBudget) allowDownload(host string) bool {
+	budget.mutex.Lock()
+	defer budget.mutex.Unlock()
+	usage := budget.usageLocked(host)
+	if (budget.exhausted != "" && budget.exhausted != BUDGET_PAGES) ||
+		usage.exhausted == BUDGET_BYTES {
+		budget.skipped++
+		return false
+	}
+	return true
+}
+
+// 记录针对给定主机的下载字节数。
+func (budget *crawlBudget) recordBytes(host string, n uint64) {
+	budget.mutex.Lock()
+	defer budget.mutex.Unlock()
+	usage := budget.usageLocked(host)
+	budget.bytes += n
+	usage.bytes += n
+	if usage.quota.MaxBytes > 0 && usage.bytes >= usage.quota.MaxBytes {
+		usage.exhausted = BUDGET_BYTES
+	}
+	if max := budget.args.MaxBytes(); max > 0 && budget.bytes >= max {
+		budget.exhaustLocked(BUDGET_BYTES)
+	}
+}
+
+// 接受条目。若条目数已达上限，则结果值为false。
+func (budget *crawlBudget) takeItem() bool {
+	budget.mutex.Lock()
+	defer budget.mutex.Unlock()
+	max := budget.args.MaxItems()
+	if max == 0 {
+		budget.items++
+		return true
+	}
+	if budget.items >= max {
+		budget.dropped++
+		return false
+	}
+	budget.items++
+	if budget.items >= max {
+		budget.exhaustLocked(BUDGET_ITEMS)
+	}
+	return true
+}
+
+// 获得全局预算耗尽的原因。若未耗尽，则结果值为空字符串。
+func (budget *crawlBudget) exhaustedReason() string {
+	budget.mutex.Lock()
+	defer budget.mutex.Unlock()
+	return budget.exhausted
+}
+
+// 获得用量与上限的字符串表示。上限为0时表示不限制。
+func usageString(used uint64, max uint64) string {
+	if max == 0 {
+		return fmt.Sprintf("%d", used)
+	}
+	return fmt.Sprintf("%d/%d", used, max)
+}
+
+// 获取爬取预算的摘要信息。其中的各个域名会按名称排序。
+func (budget *crawlBudget) summary() string {
+	budget.mutex.Lock()
+	defer budget.mutex.Unlock()
+	exhausted := budget.exhausted
+	if exhausted == "" {
+		exhausted = "<none>"
+	}
+	var buffer bytes.Buffer
+	buffer.WriteString(fmt.Sprintf("pages: %s, bytes: %s, items: %s, maxDuration: %s,"+
+		" refused: %d, skipped: %d, droppedItems: %d, exhausted: %s, domains: [",
+		usageString(budget.pages, budget.args.MaxPages()),
+		usageString(budget.bytes, budget.args.MaxBytes()),
+		usageString(budget.items, budget.args.MaxItems()),
+		budget.args.MaxDuration(),
+		budget.refused, budget.skipped, budget.dropped, exhausted))
+	keys := make([]string, 0, len(budget.usageMap))
+	for key := range budget.usageMap {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for i, key := range keys {
+		usage := budget.usageMap[key]
+		if i > 0 {
+			buffer.WriteString(", ")
+		}
+		buffer.WriteString(fmt.Sprintf("%s: { pages: %s, bytes: %s",
+			key,
+			usageString(usage.pages, usage.quota.MaxPages),
+			usageString(usage.bytes, usage.quota.MaxBytes)))
+		if usage.exhausted != "" {
+			buffer.WriteString(", exhausted: " + usage.exhausted)
+		}
+		buffer.WriteString(" }")
+	}
+	buffer.WriteString("]")
+	return buffer.String()
+}
+
+// 在全局预算耗尽时停止调度器。
+// 若是因爬取时间而耗尽，则立即停止，否则会等到已被接受的工作都处理完毕（即调度器空闲）之后再停止。
+func (sched *myScheduler) onBudgetExhausted(reason string) {
+	logger.Warnf("The crawl budget is exhausted! (reason=%s)\n", reason)
+	if reason != BUDGET_DURATION {
+		events, err := sched.events.subscribe(4)
+		if err != nil {
+			logger.Errorf("Subscribe scheduler events error: %s\n", err)
+			return
+		}
+		defer sched.events.unsubscribe(events)
+		sched.rwmutex.RLock()
+		tracker := sched.tracker
+		sched.rwmutex.RUnlock()
+		if !tracker.idle() {
+			for event := range events {
+				if event.Type == EVENT_STOPPED {
+					return
+				}
+				if event.Type == EVENT_IDLE && tracker.idle() {
+					break
+				}
+			}
+		}
+	}
+	if sched.Stop() {
+		logger.Infof("The scheduler has been stopped because of the crawl budget. (reason=%s)\n", reason)
+	}
+}
@@ -0,0 +1,143 @@
+package scheduler
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+	anlz "webcrawler/analyzer"
+	base "webcrawler/base"
+	ipl "webcrawler/itempipeline"
+	"webcrawler/testhelper"
+)
+
+func TestCrawlBudget(t *testing.T) {
+	args := base.NewBudgetArgs(5, 0, 0, 2,
+		base.DomainQuota{MaxPages: 2},
+		map[string]base.DomainQuota{"Big.com": {MaxPages: 3, MaxBytes: 100}})
+	if err := args.Check(); err != nil {
+		t.Fatalf("Check budget args error: %s", err)
+	}
+	var reasons = make(chan string, 1)
+	budget := newCrawlBudget(args, func(reason string) { reasons <- reason })
+
+	// 默认的域名配额被每个主机单独使用。
+	for i, expected := range []bool{true, true, false} {
+		if budget.admit("a.com") != expected {
+			t.Errorf("Unexpected admission result for the %dth request of 'a.com'!", i)
+		}
+	}
+	// 针对特定域名的配额被该域名及其子域名共用。
+	for i, host := range []string{"big.com", "www.big.com"} {
+		if !budget.admit(host) {
+			t.Errorf("The %dth request of '%s' should be admitted!", i, host)
+		}
+	}
+	budget.recordBytes("img.big.com", 100)
+	if budget.allowDownload("big.com") {
+		t.Errorf("The download for 'big.com' should be refused after its bytes quota is exhausted!")
+	}
+	if budget.admit("big.com") {
+		t.Errorf("The request for 'big.com' should be refused after its bytes quota is exhausted!")
+	}
+	if !budget.admit("b.com") {
+		t.Errorf("The request for 'b.com' should be admitted!")
+	}
+	// 全局的最大网页数已达到。
+	if budget.admit("c.com") {
+		t.Errorf("The request should be refused after the max pages is reached!")
+	}
+	select {
+	case reason := <-reasons:
+		if reason != BUDGET_PAGES {
+			t.Errorf("The exhausted reason should be '%s', but '%s'!", BUDGET_PAGES, reason)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("The exhaustion is not notified!")
+	}
+	if !budget.allowDownload("b.com") {
+		t.Errorf("The admitted request should still be downloaded after the max pages is reached!")
+	}
+	if !budget.takeItem() || !budget.takeItem() || budget.takeItem() {
+		t.Errorf("Only 2 items should be taken!")
+	}
+
+	summary := budget.summary()
+	for _, part := range []string{
+		"pages: 5/5", "bytes: 100,", "items: 2/2", "refused: 3", "skipped: 1", "droppedItems: 1",
+		"exhausted: pages,", "a.com: { pages: 2/2, bytes: 0, exhausted: pages }",
+		"big.com: { pages: 2/3, bytes: 100/100, exhausted: bytes }",
+	} {
+		if !strings.Contains(summary, part) {
+			t.Errorf("The summary should contain '%s'!\n%s", part, summary)
+		}
+	}
+}
+
+func TestBudgetArgsCheck(t *testing.T) {
+	cases := map[string]base.BudgetArgs{
+		"unlimited":        base.NewBudgetArgs(0, 0, 0, 0, base.DomainQuota{}, nil),
+		"negative":         base.NewBudgetArgs(1, 0, -time.Second, 0, base.DomainQuota{}, nil),
+		"empty domain":     base.NewBudgetArgs(1, 0, 0, 0, base.DomainQuota{}, map[string]base.DomainQuota{"": {MaxPages: 1}}),
+		"unlimited domain": base.NewBudgetArgs(0, 0, 0, 0, base.DomainQuota{}, map[string]base.DomainQuota{"a.com": {}}),
+	}
+	for name, args := range cases {
+		if err := NewScheduler().SetCrawlBudget(args); err == nil {
+			t.Errorf("An error should be returned for the budget args '%s'!", name)
+		}
+	}
+}
+
+// 全局的最大网页数耗尽之后，调度器会在处理完已被接受的网页之后自行停止。
+func TestCrawlWithPageBudget(t *testing.T) {
+	site, err := testhelper.NewSiteServer(testhelper.SiteArgs{Depth: 2, FanOut: 3})
+	if err != nil {
+		t.Fatalf("Create site error: %s", err)
+	}
+	defer site.Close()
+
+	result := &crawlResult{}
+	processor := func(item base.Item) (base.Item, error) {
+		result.mutex.Lock()
+		defer result.mutex.Unlock()
+		result.titles = append(result.titles, item["title"].(string))
+		return item, nil
+	}
+	sched := NewScheduler()
+	if err := sched.SetCrawlBudget(base.NewBudgetArgs(5, 0, 0, 0, base.DomainQuota{}, nil)); err != nil {
+		t.Fatalf("Set crawl budget error: %s", err)
+	}
+	firstHttpReq, _ := http.NewRequest("GET", site.URL()+"/", nil)
+	err = sched.Start(
+		base.NewChannelArgs(10, 10, 10, 10),
+		base.NewPoolBaseArgs(3, 3),
+		2,
+		func() *http.Client { return &http.Client{Timeout: 5 * time.Second} },
+		[]anlz.ParseResponse{parseSitePage},
+		[]ipl.ProcessItem{processor},
+		firstHttpReq)
+	if err != nil {
+		t.Fatalf("Start scheduler error: %s", err)
+	}
+	deadline := time.Now().Add(10 * time.Second)
+	for sched.Running() {
+		if time.Now().After(deadline) {
+			sched.Stop()
+			t.Fatalf("The scheduler is not stopped by the crawl budget in time!")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if visited := len(site.VisitedPaths()); visited != 5 {
+		t.Errorf("The number of visited pages should be 5, but %d!", visited)
+	}
+	result.mutex.Lock()
+	if len(result.titles) != 5 {
+		t.Errorf("The number of items should be 5, but %d!", len(result.titles))
+	}
+	result.mutex.Unlock()
+	summary := sched.Summary("").String()
+	if !strings.Contains(summary, "Crawl budget: pages: 5/5") ||
+		!strings.Contains(summary, "exhausted: pages") {
+		t.Errorf("Unexpected summary:\n%s", summary)
+	}
+}
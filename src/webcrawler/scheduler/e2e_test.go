@@ -0,0 +1,272 @@
+package scheduler
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	anlz "webcrawler/analyzer"
+	base "webcrawler/base"
+	ipl "webcrawler/itempipeline"
+	"webcrawler/linkgraph"
+	"webcrawler/session"
+	"webcrawler/testhelper"
+)
+
+// 爬取结果。
+type crawlResult struct {
+	titles []string // 条目中的标题的有序列表。
+	mutex  sync.Mutex
+}
+
+// 响应解析函数。它会为每个状态码为200的网页生成一个带有标题的条目。
+// 其他响应也会被交给链接解析函数，以检查状态错误不会被重复报告。
+func parseSitePage(httpResp *http.Response, respDepth uint32, respMeta base.Meta) ([]base.Data, []error) {
+	dataList, errs := anlz.ParseLinks(httpResp, respDepth, respMeta)
+	if httpResp.StatusCode != 200 {
+		return dataList, errs
+	}
+	pageUrl := httpResp.Request.URL
+	item := base.Item{"url": pageUrl.String(), "title": "Page " + pageUrl.Path}
+	return append(dataList, &item), errs
+}
+
+// 爬取本地站点，并在调度器持续空闲一段时间之后停止它。参数setups会在开启调度器之前被依次调用。
+func crawlSite(t *testing.T, site *testhelper.SiteServer, crawlDepth uint32,
+	setups ...func(sched Scheduler)) (*myScheduler, *crawlResult) {
+	result := &crawlResult{}
+	processor := func(item base.Item) (base.Item, error) {
+		result.mutex.Lock()
+		defer result.mutex.Unlock()
+		result.titles = append(result.titles, item["title"].(string))
+		return item, nil
+	}
+	sched := NewScheduler()
+	for _, setup := range setups {
+		setup(sched)
+	}
+	firstHttpReq, _ := http.NewRequest("GET", site.URL()+"/", nil)
+	err := sched.Start(
+		base.NewChannelArgs(10, 10, 10, 10),
+		base.NewPoolBaseArgs(3, 3),
+		crawlDepth,
+		func() *http.Client { return site.Client(5 * time.Second) },
+		[]anlz.ParseResponse{parseSitePage},
+		[]ipl.ProcessItem{processor},
+		firstHttpReq)
+	if err != nil {
+		t.Fatalf("Start scheduler error: %s", err)
+	}
+	deadline := time.Now().Add(10 * time.Second)
+	idleSince := time.Time{}
+	for {
+		if time.Now().After(deadline) {
+			sched.Stop()
+			t.Fatalf("The crawl is not finished in time!")
+		}
+		if sched.Idle() {
+			if idleSince.IsZero() {
+				idleSince = time.Now()
+			} else if time.Since(idleSince) > 200*time.Millisecond {
+				break
+			}
+		} else {
+			idleSince = time.Time{}
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	sched.Stop()
+	sort.Strings(result.titles)
+	return sched.(*myScheduler), result
+}
+
+// 获得网页的路径和标题的有序列表。
+func expectedPaths(pages []*testhelper.Page) (paths []string, titles []string) {
+	for _, page := range pages {
+		paths = append(paths, page.Path)
+		if page.Kind == testhelper.PAGE_REDIRECT {
+			paths = append(paths, page.ContentPath())
+		}
+		if page.Kind != testhelper.PAGE_ERROR {
+			titles = append(titles, "Page "+page.ContentPath())
+		}
+	}
+	sort.Strings(paths)
+	sort.Strings(titles)
+	return
+}
+
+func assertStrings(t *testing.T, name string, actual []string, expected []string) {
+	if len(actual) != len(expected) {
+		t.Fatalf("Unexpected %s %v, expected %v!", name, actual, expected)
+	}
+	for i := range actual {
+		if actual[i] != expected[i] {
+			t.Fatalf("Unexpected %s %v, expected %v!", name, actual, expected)
+		}
+	}
+}
+
+func TestCrawlSiteTree(t *testing.T) {
+	site, err := testhelper.NewSiteServer(testhelper.SiteArgs{Depth: 2, FanOut: 3})
+	if err != nil {
+		t.Fatalf("Create site error: %s", err)
+	}
+	defer site.Close()
+	_, result := crawlSite(t, site, 2)
+	paths, titles := expectedPaths(site.Pages())
+	if len(paths) != 13 {
+		t.Fatalf("The site should have 13 pages, but %d!", len(paths))
+	}
+	assertStrings(t, "visited paths", site.VisitedPaths(), paths)
+	assertStrings(t, "item titles", result.titles, titles)
+}
+
+func TestCrawlDepthLimit(t *testing.T) {
+	site, err := testhelper.NewSiteServer(testhelper.SiteArgs{Depth: 3, FanOut: 2, Cycles: true})
+	if err != nil {
+		t.Fatalf("Create site error: %s", err)
+	}
+	defer site.Close()
+	_, result := crawlSite(t, site, 1)
+	paths, titles := expectedPaths(site.PagesWithin(1))
+	assertStrings(t, "visited paths", site.VisitedPaths(), paths)
+	assertStrings(t, "item titles", result.titles, titles)
+}
+
+func TestCrawlCyclesRedirectsAndErrors(t *testing.T) {
+	site, err := testhelper.NewSiteServer(testhelper.SiteArgs{
+		Depth:         2,
+		FanOut:        4,
+		Cycles:        true,
+		RedirectEvery: 4,
+		ErrorEvery:    5,
+		ErrorCode:     503,
+		SlowEvery:     3,
+		SlowDelay:     30 * time.Millisecond,
+		Disallow:      []string{"/1/"},
+	})
+	if err != nil {
+		t.Fatalf("Create site error: %s", err)
+	}
+	defer site.Close()
+	sched, result := crawlSite(t, site, 2)
+	paths, titles := expectedPaths(site.Pages())
+	assertStrings(t, "visited paths", site.VisitedPaths(), paths)
+	assertStrings(t, "item titles", result.titles, titles)
+	// 虽然网页之间存在环，但每个地址都只应被访问一次。
+	for path, count := range site.Visits() {
+		if count != 1 {
+			t.Errorf("The path %s should be visited once, but %d times!", path, count)
+		}
+	}
+	// 调度器并不遵守robots.txt，所以它不会被访问。
+	if site.Visits()["/robots.txt"] != 0 {
+		t.Errorf("The robots.txt should not be visited!")
+	}
+	var errorPages uint64
+	for _, page := range site.Pages() {
+		if page.Kind == testhelper.PAGE_ERROR {
+			errorPages++
+		}
+	}
+	if errorPages == 0 {
+		t.Fatalf("The site should have error pages!")
+	}
+	if count := sched.ErrorCounts()[base.HTTP_STATUS_ERROR]; count != errorPages {
+		t.Errorf("The number of HTTP status errors should be %d, but %d!", errorPages, count)
+	}
+}
+
+func TestCrawlLinkGraph(t *testing.T) {
+	site, err := testhelper.NewSiteServer(testhelper.SiteArgs{Depth: 1, FanOut: 3, Cycles: true})
+	if err != nil {
+		t.Fatalf("Create site error: %s", err)
+	}
+	defer site.Close()
+
+	graph := linkgraph.NewLinkGraph()
+	sched, _ := crawlSite(t, site, 1, func(sched Scheduler) { sched.SetLinkGraph(graph) })
+	// 根网页链接到3个子网页以及它自己，每个子网页都链接回根网页（“Home”与“Parent”的链接是重复的）。
+	if graph.NodeNumber() != 4 || graph.EdgeNumber() != 7 {
+		t.Errorf("Unexpected link graph: %s", graph.Summary())
+	}
+	root := site.URL() + "/"
+	if inDegree := graph.InDegree()[root]; inDegree != 4 {
+		t.Errorf("The in-degree of the root page should be 4, but %d!", inDegree)
+	}
+	for _, edge := range graph.Edges() {
+		if edge.From != root {
+			if edge.To != root || edge.Anchor != "Home" || edge.Depth != 2 {
+				t.Errorf("Unexpected edge %+v!", edge)
+			}
+			continue
+		}
+		if edge.Depth != 1 || (edge.To != root && edge.Anchor != strings.TrimPrefix(edge.To, site.URL())) {
+			t.Errorf("Unexpected edge %+v!", edge)
+		}
+	}
+	if summary := sched.Summary("").String(); !strings.Contains(summary, "Link graph: nodes: 4, edges: 7") {
+		t.Errorf("Unexpected summary:\n%s", summary)
+	}
+}
+
+// 在HTTPS站点上，从网页中发现的链接以及额外的种子请求都应该被跟进。
+func TestCrawlTLSSite(t *testing.T) {
+	site, err := testhelper.NewSiteServer(testhelper.SiteArgs{Depth: 2, FanOut: 2, TLS: true})
+	if err != nil {
+		t.Fatalf("Create site error: %s", err)
+	}
+	defer site.Close()
+	if !strings.HasPrefix(site.URL(), "https://") {
+		t.Fatalf("The site should be served over HTTPS! (url=%s)", site.URL())
+	}
+	seed, _ := http.NewRequest("GET", site.URL()+"/1/0", nil)
+	_, result := crawlSite(t, site, 0, func(sched Scheduler) {
+		sched.SetSeeds([]*http.Request{seed})
+	})
+	// 深度为0时只会爬取首页和额外的种子。
+	assertStrings(t, "titles", result.titles, []string{"Page /", "Page /1/0"})
+
+	_, result = crawlSite(t, site, 2)
+	_, titles := expectedPaths(site.PagesWithin(2))
+	assertStrings(t, "titles", result.titles, titles)
+}
+
+func TestCrawlLoginFailure(t *testing.T) {
+	site, err := testhelper.NewSiteServer(testhelper.SiteArgs{Depth: 1, FanOut: 2})
+	if err != nil {
+		t.Fatalf("Create site error: %s", err)
+	}
+	defer site.Close()
+	sess, err := session.NewSession(base.NewSessionArgs(
+		base.COOKIE_JAR_MODE_SHARED, "", site.URL()+"/none", nil, ""))
+	if err != nil {
+		t.Fatalf("Create session error: %s", err)
+	}
+	sched := NewScheduler().(*myScheduler)
+	sched.SetSession(sess)
+	firstHttpReq, _ := http.NewRequest("GET", site.URL()+"/", nil)
+	// 登录失败时，调度器不应开启任何组件，并且可以再次被开启。
+	for i := 0; i < 2; i++ {
+		err := sched.Start(
+			base.NewChannelArgs(10, 10, 10, 10),
+			base.NewPoolBaseArgs(3, 3),
+			1,
+			func() *http.Client { return site.Client(5 * time.Second) },
+			[]anlz.ParseResponse{parseSitePage},
+			[]ipl.ProcessItem{func(item base.Item) (base.Item, error) { return item, nil }},
+			firstHttpReq)
+		if err == nil || !strings.Contains(err.Error(), "Login failed") {
+			t.Fatalf("Expected a login error, but got %v.", err)
+		}
+	}
+	if sched.Running() || sched.chanman != nil || sched.dlpool != nil {
+		t.Errorf("No component should be started after the login failure!")
+	}
+	if visits := site.Visits(); visits["/"] != 0 {
+		t.Errorf("The site should not be crawled after the login failure!")
+	}
+}
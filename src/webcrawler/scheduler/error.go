@@ -0,0 +1,100 @@
+package scheduler
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"sync"
+	base "webcrawler/base"
+)
+
+// 根据错误值以及组件代号的前缀判断错误类型。
+// 注意，HTTP客户端返回的所有错误都是*url.Error类型的，而它实现了net.Error接口，
+// 所以只有真正的网络操作错误和域名解析错误才会被视为网络错误。
+func classifyError(err error, codePrefix string) base.ErrorType {
+	var statusErr *base.HttpStatusError
+	if errors.As(err, &statusErr) {
+		return base.HTTP_STATUS_ERROR
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return base.TIMEOUT_ERROR
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return base.TIMEOUT_ERROR
+	}
+	var opErr *net.OpError
+	var dnsErr *net.DNSError
+	if errors.As(err, &opErr) || errors.As(err, &dnsErr) {
+		return base.NETWORK_ERROR
+	}
+	switch codePrefix {
+	case DOWNLOADER_CODE:
+		return base.DOWNLOADER_ERROR
+	case ANALYZER_CODE:
+		return base.ANALYZER_ERROR
+	case ITEMPIPELINE_CODE:
+		return base.ITEM_PROCESSOR_ERROR
+	default:
+		return base.SCHEDULER_ERROR
+	}
+}
+
+// 错误计数器。它会按照错误类型分别计数。
+type errorCounter struct {
+	countMap map[base.ErrorType]uint64 // 计数的字典。
+	mutex    sync.Mutex                // 互斥锁。
+}
+
+// 创建错误计数器。
+func newErrorCounter() *errorCounter {
+	return &errorCounter{countMap: make(map[base.ErrorType]uint64)}
+}
+
+// 为给定的错误类型增加计数。
+func (counter *errorCounter) add(errorType base.ErrorType) {
+	counter.mutex.Lock()
+	defer counter.mutex.Unlock()
+	counter.countMap[errorType]++
+}
+
+// 获得给定错误类型的计数。
+func (counter *errorCounter) count(errorType base.ErrorType) uint64 {
+	counter.mutex.Lock()
+	defer counter.mutex.Unlock()
+	return counter.countMap[errorType]
+}
+
+// 获得所有错误类型的计数。结果值是一个副本。
+func (counter *errorCounter) counts() map[base.ErrorType]uint64 {
+	counter.mutex.Lock()
+	defer counter.mutex.Unlock()
+	countMap := make(map[base.ErrorType]uint64, len(counter.countMap))
+	for errorType, count := range counter.countMap {
+		countMap[errorType] = count
+	}
+	return countMap
+}
+
+// 获取错误计数器的摘要信息。其中的各项会按照错误类型排序。
+func (counter *errorCounter) summary() string {
+	counter.mutex.Lock()
+	defer counter.mutex.Unlock()
+	errorTypes := make([]string, 0, len(counter.countMap))
+	var total uint64
+	for errorType, count := range counter.countMap {
+		errorTypes = append(errorTypes, string(errorType))
+		total += count
+	}
+	sort.Strings(errorTypes)
+	var buffer bytes.Buffer
+	buffer.WriteString(fmt.Sprintf("total: %d", total))
+	for _, errorType := range errorTypes {
+		buffer.WriteString(fmt.Sprintf(", %s: %d",
+			errorType, counter.countMap[base.ErrorType(errorType)]))
+	}
+	return buffer.String()
+}
@@ -0,0 +1,83 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strings"
+	"testing"
+	base "webcrawler/base"
+)
+
+type timeoutError struct{}
+
+func (e timeoutError) Error() string   { return "i/o timeout" }
+func (e timeoutError) Timeout() bool   { return true }
+func (e timeoutError) Temporary() bool { return true }
+
+func TestClassifyError(t *testing.T) {
+	cases := []struct {
+		err        error
+		codePrefix string
+		expected   base.ErrorType
+	}{
+		{errors.New("x"), DOWNLOADER_CODE, base.DOWNLOADER_ERROR},
+		{errors.New("x"), ANALYZER_CODE, base.ANALYZER_ERROR},
+		{errors.New("x"), ITEMPIPELINE_CODE, base.ITEM_PROCESSOR_ERROR},
+		{errors.New("x"), SCHEDULER_CODE, base.SCHEDULER_ERROR},
+		{base.NewHttpStatusError(404, "http://a.com"), DOWNLOADER_CODE, base.HTTP_STATUS_ERROR},
+		{fmt.Errorf("wrapped: %w", context.DeadlineExceeded), DOWNLOADER_CODE, base.TIMEOUT_ERROR},
+		{&url.Error{Op: "Get", URL: "http://a.com", Err: timeoutError{}}, DOWNLOADER_CODE, base.TIMEOUT_ERROR},
+		{&net.OpError{Op: "dial", Err: errors.New("refused")}, DOWNLOADER_CODE, base.NETWORK_ERROR},
+		{&url.Error{Op: "Get", URL: "http://a.com", Err: &net.OpError{Op: "dial", Err: errors.New("refused")}},
+			DOWNLOADER_CODE, base.NETWORK_ERROR},
+		{&url.Error{Op: "Get", URL: "http://a.com", Err: &net.DNSError{Err: "no such host", Name: "a.com"}},
+			DOWNLOADER_CODE, base.NETWORK_ERROR},
+		// HTTP客户端返回的其他错误都不是网络错误。
+		{&url.Error{Op: "Get", URL: "ftp://a.com", Err: errors.New("unsupported protocol scheme \"ftp\"")},
+			DOWNLOADER_CODE, base.DOWNLOADER_ERROR},
+		{&url.Error{Op: "Get", URL: "http://a.com/r", Err: errors.New("stopped after 10 redirects")},
+			DOWNLOADER_CODE, base.DOWNLOADER_ERROR},
+		{&url.Error{Op: "Get", URL: "http://a.com", Err: io.ErrUnexpectedEOF}, DOWNLOADER_CODE, base.DOWNLOADER_ERROR},
+	}
+	for i, c := range cases {
+		if errorType := classifyError(c.err, c.codePrefix); errorType != c.expected {
+			t.Errorf("The type of error [%d] should be %q, but %q!\n", i, c.expected, errorType)
+		}
+	}
+}
+
+func TestWrappedCrawlerError(t *testing.T) {
+	cause := base.NewHttpStatusError(500, "http://a.com/b")
+	cError := base.WrapCrawlerError(base.HTTP_STATUS_ERROR, cause, "http://a.com/b", 2, "downloader-1")
+	if errors.Unwrap(cError) != cause {
+		t.Errorf("The cause should be accessible via errors.Unwrap!\n")
+	}
+	var statusErr *base.HttpStatusError
+	if !errors.As(cError, &statusErr) || statusErr.StatusCode() != 500 {
+		t.Errorf("The cause should be accessible via errors.As!\n")
+	}
+	if cError.Url() != "http://a.com/b" || cError.Depth() != 2 || cError.ComponentId() != "downloader-1" {
+		t.Errorf("Unexpected error context! (%s)\n", cError)
+	}
+	if !strings.Contains(cError.Error(), "component=downloader-1") {
+		t.Errorf("The error message should contain the context! (%s)\n", cError)
+	}
+}
+
+func TestErrorCounter(t *testing.T) {
+	counter := newErrorCounter()
+	counter.add(base.DOWNLOADER_ERROR)
+	counter.add(base.ANALYZER_ERROR)
+	counter.add(base.DOWNLOADER_ERROR)
+	if count := counter.count(base.DOWNLOADER_ERROR); count != 2 {
+		t.Errorf("The count should be 2, but %d!\n", count)
+	}
+	expected := "total: 3, Analyzer Error: 1, Downloader Error: 2"
+	if summary := counter.summary(); summary != expected {
+		t.Errorf("The summary should be %q, but %q!\n", expected, summary)
+	}
+}
@@ -0,0 +1,201 @@
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+	base "webcrawler/base"
+)
+
+// 调度器事件的类型。
+type EventType uint8
+
+const (
+	EVENT_STARTED          EventType = iota + 1 // 调度器已开启。
+	EVENT_BUSY                                  // 调度器由空闲转为忙碌。
+	EVENT_IDLE                                  // 调度器由忙碌转为空闲，即没有任何待处理或处理中的请求、响应和条目。
+	EVENT_QUEUE_EMPTY                           // 请求缓存中的最后一个请求已被取走。
+	EVENT_PIPELINE_DRAINED                      // 条目处理管道中已没有待处理或处理中的条目。
+	EVENT_STOPPED                               // 调度器已停止。
+)
+
+// 调度器事件类型与名称的映射。
+var eventTypeNameMap = map[EventType]string{
+	EVENT_STARTED:          "started",
+	EVENT_BUSY:             "busy",
+	EVENT_IDLE:             "idle",
+	EVENT_QUEUE_EMPTY:      "queue empty",
+	EVENT_PIPELINE_DRAINED: "pipeline drained",
+	EVENT_STOPPED:          "stopped",
+}
+
+// 获得调度器事件类型的名称。
+func EventTypeName(eventType EventType) (string, bool) {
+	name, ok := eventTypeNameMap[eventType]
+	return name, ok
+}
+
+// 调度器事件。
+type Event struct {
+	Type EventType // 事件类型。
+	Time time.Time // 事件发生的时间。
+}
+
+func (event Event) String() string {
+	name, ok := EventTypeName(event.Type)
+	if !ok {
+		name = fmt.Sprintf("unknown(%d)", event.Type)
+	}
+	return fmt.Sprintf("%s at %s", name, event.Time.Format(time.RFC3339Nano))
+}
+
+// 事件总线。发布事件的操作不会阻塞：
+// 若某个订阅通道已满，则其中最早的事件会被丢弃，以便让订阅方总能看到最新的状态。
+type eventBus struct {
+	subscribers []chan Event // 订阅通道的列表。
+	dropped     uint64       // 已被丢弃的事件的数量。
+	mutex       sync.Mutex   // 互斥锁。它同时保证了事件的发布顺序。
+}
+
+// 创建事件总线。
+func newEventBus() *eventBus {
+	return &eventBus{}
+}
+
+// 订阅事件。参数bufferLen代表作为结果值的通道的长度，它不能为0。
+func (bus *eventBus) subscribe(bufferLen uint) (<-chan Event, error) {
+	if bufferLen == 0 {
+		return nil, errors.New("The buffer length of event channel can not be 0!")
+	}
+	bus.mutex.Lock()
+	defer bus.mutex.Unlock()
+	ch := make(chan Event, bufferLen)
+	bus.subscribers = append(bus.subscribers, ch)
+	return ch, nil
+}
+
+// 取消订阅。被取消订阅的通道会被关闭。
+func (bus *eventBus) unsubscribe(ch <-chan Event) {
+	bus.mutex.Lock()
+	defer bus.mutex.Unlock()
+	for i, subscriber := range bus.subscribers {
+		if subscriber == ch {
+			bus.subscribers = append(bus.subscribers[:i], bus.subscribers[i+1:]...)
+			close(subscriber)
+			return
+		}
+	}
+}
+
+// 发布事件。
+func (bus *eventBus) publish(eventType EventType) {
+	event := Event{Type: eventType, Time: time.Now()}
+	bus.mutex.Lock()
+	defer bus.mutex.Unlock()
+	for _, ch := range bus.subscribers {
+		for sent := false; !sent; {
+			select {
+			case ch <- event:
+				sent = true
+			default:
+				select {
+				case <-ch:
+					atomic.AddUint64(&bus.dropped, 1)
+				default:
+				}
+			}
+		}
+	}
+}
+
+// 活动跟踪器。它以工作单元的方式跟踪调度器中的活动：
+// 从请求缓存中取出的请求、被发送到响应通道的响应以及被发送到条目通道的条目各自对应一个工作单元。
+// 每个工作单元都会在其下游的工作单元开始之后才结束，所以当工作单元的数量为0且请求缓存为空时，
+// 调度器就确实空闲了。忙碌与空闲之间的转换会被发布为事件。
+type activityTracker struct {
+	bus      *eventBus  // 事件总线。
+	queueLen func() int // 获得请求缓存长度的函数。
+	active   int64      // 进行中的工作单元的数量。
+	items    int64      // 进行中的条目工作单元的数量。
+	busy     bool       // 是否处于忙碌状态。
+	mutex    sync.Mutex // 互斥锁。
+}
+
+// 创建活动跟踪器。
+func newActivityTracker(bus *eventBus, queueLen func() int) *activityTracker {
+	return &activityTracker{bus: bus, queueLen: queueLen}
+}
+
+// 从请求缓存中取出请求。若取到了请求，则开始一个工作单元。
+// 取出请求与开始工作单元是原子的，以免在两者之间被误判为空闲。
+func (tracker *activityTracker) take(get func() *base.Request) *base.Request {
+	tracker.mutex.Lock()
+	defer tracker.mutex.Unlock()
+	req := get()
+	if req == nil {
+		return nil
+	}
+	tracker.beginLocked()
+	if tracker.queueLen() == 0 {
+		tracker.bus.publish(EVENT_QUEUE_EMPTY)
+	}
+	return req
+}
+
+// 开始一个工作单元。
+func (tracker *activityTracker) begin() {
+	tracker.mutex.Lock()
+	defer tracker.mutex.Unlock()
+	tracker.beginLocked()
+}
+
+// 结束一个工作单元。
+func (tracker *activityTracker) end() {
+	tracker.mutex.Lock()
+	defer tracker.mutex.Unlock()
+	tracker.endLocked()
+}
+
+// 开始一个条目工作单元。
+func (tracker *activityTracker) beginItem() {
+	tracker.mutex.Lock()
+	defer tracker.mutex.Unlock()
+	tracker.items++
+	tracker.beginLocked()
+}
+
+// 结束一个条目工作单元。
+func (tracker *activityTracker) endItem() {
+	tracker.mutex.Lock()
+	defer tracker.mutex.Unlock()
+	tracker.items--
+	if tracker.items == 0 {
+		tracker.bus.publish(EVENT_PIPELINE_DRAINED)
+	}
+	tracker.endLocked()
+}
+
+func (tracker *activityTracker) beginLocked() {
+	tracker.active++
+	if !tracker.busy {
+		tracker.busy = true
+		tracker.bus.publish(EVENT_BUSY)
+	}
+}
+
+func (tracker *activityTracker) endLocked() {
+	tracker.active--
+	if tracker.busy && tracker.active == 0 && tracker.queueLen() == 0 {
+		tracker.busy = false
+		tracker.bus.publish(EVENT_IDLE)
+	}
+}
+
+// 判断是否空闲。
+func (tracker *activityTracker) idle() bool {
+	tracker.mutex.Lock()
+	defer tracker.mutex.Unlock()
+	return tracker.active == 0 && tracker.queueLen() == 0
+}
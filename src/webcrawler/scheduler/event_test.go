@@ -0,0 +1,106 @@
+package scheduler
+
+import (
+	"net/http"
+	"testing"
+	"time"
+	anlz "webcrawler/analyzer"
+	base "webcrawler/base"
+	ipl "webcrawler/itempipeline"
+	"webcrawler/testhelper"
+)
+
+func TestEventBusDropOldest(t *testing.T) {
+	bus := newEventBus()
+	if _, err := bus.subscribe(0); err == nil {
+		t.Fatalf("An error should be returned for the buffer length 0!")
+	}
+	ch, err := bus.subscribe(2)
+	if err != nil {
+		t.Fatalf("Subscribe error: %s", err)
+	}
+	bus.publish(EVENT_STARTED)
+	bus.publish(EVENT_BUSY)
+	bus.publish(EVENT_IDLE)
+	if event := <-ch; event.Type != EVENT_BUSY {
+		t.Errorf("The oldest event should be dropped, but got %s!", event)
+	}
+	if event := <-ch; event.Type != EVENT_IDLE {
+		t.Errorf("The latest event should be kept, but got %s!", event)
+	}
+	if bus.dropped != 1 {
+		t.Errorf("The dropped number should be 1, but %d!", bus.dropped)
+	}
+}
+
+// 在调度器发布空闲事件的时刻，所有网页都应该已经被处理完毕。
+func TestSchedulerEvents(t *testing.T) {
+	site, err := testhelper.NewSiteServer(testhelper.SiteArgs{Depth: 2, FanOut: 3, Cycles: true})
+	if err != nil {
+		t.Fatalf("Create site error: %s", err)
+	}
+	defer site.Close()
+
+	sched := NewScheduler()
+	events, err := sched.SubscribeEvents(128)
+	if err != nil {
+		t.Fatalf("Subscribe events error: %s", err)
+	}
+	result := &crawlResult{}
+	processor := func(item base.Item) (base.Item, error) {
+		result.mutex.Lock()
+		defer result.mutex.Unlock()
+		result.titles = append(result.titles, item["title"].(string))
+		return item, nil
+	}
+	firstHttpReq, _ := http.NewRequest("GET", site.URL()+"/", nil)
+	err = sched.Start(
+		base.NewChannelArgs(10, 10, 10, 10),
+		base.NewPoolBaseArgs(3, 3),
+		2,
+		func() *http.Client { return &http.Client{Timeout: 5 * time.Second} },
+		[]anlz.ParseResponse{parseSitePage},
+		[]ipl.ProcessItem{processor},
+		firstHttpReq)
+	if err != nil {
+		t.Fatalf("Start scheduler error: %s", err)
+	}
+	defer sched.Stop()
+
+	seen := make(map[EventType]bool)
+	timeout := time.After(10 * time.Second)
+	for !seen[EVENT_IDLE] {
+		select {
+		case event := <-events:
+			seen[event.Type] = true
+		case <-timeout:
+			t.Fatalf("The idle event is not received in time! (seen=%v)", seen)
+		}
+	}
+	if !sched.Idle() {
+		t.Errorf("The scheduler should be idle after the idle event!")
+	}
+	result.mutex.Lock()
+	if len(result.titles) != len(site.Pages()) {
+		t.Errorf("The number of items should be %d, but %d!", len(site.Pages()), len(result.titles))
+	}
+	result.mutex.Unlock()
+	for _, eventType := range []EventType{EVENT_BUSY, EVENT_QUEUE_EMPTY, EVENT_PIPELINE_DRAINED} {
+		if !seen[eventType] {
+			name, _ := EventTypeName(eventType)
+			t.Errorf("The event '%s' should be received before the idle event!", name)
+		}
+	}
+
+	sched.Stop()
+	for {
+		select {
+		case event := <-events:
+			if event.Type == EVENT_STOPPED {
+				return
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("The stopped event is not received in time!")
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package scheduler
+
+import (
+	"fmt"
+	"sync"
+	base "webcrawler/base"
+	"webcrawler/frontier"
+)
+
+// 每次从爬取边界取出的请求的最大数量。
+const frontierPullBatch = 16
+
+// 创建基于爬取边界的请求缓存。
+// 放入的请求会被推送到爬取边界，获取请求时则会从爬取边界成批地取出属于当前工作方的请求。
+// 缓存的长度包含了爬取边界中待取出的请求，以免调度器在爬取边界仍有工作时被判定为空闲。
+func newFrontierRequestCache(client frontier.Client) requestCache {
+	return &reqCacheByFrontier{
+		client: client,
+		buffer: make([]*base.Request, 0, frontierPullBatch),
+	}
+}
+
+// 基于爬取边界的请求缓存的实现类型。
+type reqCacheByFrontier struct {
+	client frontier.Client // 爬取边界客户端。
+	buffer []*base.Request // 已从爬取边界取出但尚未被获取的请求。
+	mutex  sync.Mutex      // 互斥锁。
+	status byte            // 缓存状态。0表示正在运行，1表示已关闭。
+}
+
+func (rcache *reqCacheByFrontier) put(req *base.Request) bool {
+	if req == nil {
+		return false
+	}
+	rcache.mutex.Lock()
+	closed := rcache.status == 1
+	rcache.mutex.Unlock()
+	if closed {
+		return false
+	}
+	if _, err := rcache.client.Push([]*base.Request{req}); err != nil {
+		logger.Warnf("Push request to frontier error: %s\n", err)
+		return false
+	}
+	return true
+}
+
+func (rcache *reqCacheByFrontier) get() *base.Request {
+	rcache.mutex.Lock()
+	defer rcache.mutex.Unlock()
+	if rcache.status == 1 {
+		return nil
+	}
+	if len(rcache.buffer) == 0 {
+		reqs, err := rcache.client.Pull(frontierPullBatch)
+		if err != nil {
+			logger.Warnf("Pull requests from frontier error: %s\n", err)
+		}
+		rcache.buffer = append(rcache.buffer, reqs...)
+	}
+	if len(rcache.buffer) == 0 {
+		return nil
+	}
+	req := rcache.buffer[0]
+	rcache.buffer = rcache.buffer[1:]
+	return req
+}
+
+func (rcache *reqCacheByFrontier) capacity() int {
+	rcache.mutex.Lock()
+	defer rcache.mutex.Unlock()
+	return cap(rcache.buffer)
+}
+
+// 获得缓存的长度，即本地缓冲的请求与爬取边界中待取出的请求的数量之和。
+// 后者是最近一次与爬取边界交互时得到的。正在进行的取出操作会持有互斥锁，
+// 所以本方法会等到它完成之后再以最新的结果计算。
+func (rcache *reqCacheByFrontier) length() int {
+	rcache.mutex.Lock()
+	defer rcache.mutex.Unlock()
+	if rcache.status == 1 {
+		return len(rcache.buffer)
+	}
+	return len(rcache.buffer) + int(rcache.client.Pending())
+}
+
+func (rcache *reqCacheByFrontier) close() {
+	rcache.mutex.Lock()
+	defer rcache.mutex.Unlock()
+	if rcache.status == 1 {
+		return
+	}
+	rcache.status = 1
+	rcache.client.Close()
+}
+
+func (rcache *reqCacheByFrontier) summary() string {
+	rcache.mutex.Lock()
+	status := rcache.status
+	length := len(rcache.buffer)
+	capacity := cap(rcache.buffer)
+	rcache.mutex.Unlock()
+	summary := fmt.Sprintf(summaryTemplate, statusMap[status], length, capacity)
+	return fmt.Sprintf("%s, frontier: worker %d/%d, pending %d", summary,
+		rcache.client.WorkerIndex(), rcache.client.WorkerNumber(), rcache.client.Pending())
+}
@@ -1,125 +1,193 @@
-package scheduler
-
-import (
-	"errors"
-	"fmt"
-	"regexp"
-	"strings"
-	anlz "webcrawler/analyzer"
-	base "webcrawler/base"
-	dl "webcrawler/downloader"
-	ipl "webcrawler/itempipeline"
-	mdw "webcrawler/middleware"
-)
-
-func generateChannelManager(channelArgs base.ChannelArgs) mdw.ChannelManager {
-	return mdw.NewChannelManager(channelArgs)
-}
-
-func generatePageDownloaderPool(
-	poolSize uint32,
-	httpClientGenerator GenHttpClient) (dl.PageDownloaderPool, error) {
-	dlPool, err := dl.NewPageDownloaderPool(
-		poolSize,
-		func() dl.PageDownloader {
-			return dl.NewPageDownloader(httpClientGenerator())
-		},
-	)
-	if err != nil {
-		return nil, err
-	}
-	return dlPool, nil
-}
-
-func generateAnalyzerPool(poolSize uint32) (anlz.AnalyzerPool, error) {
-	analyzerPool, err := anlz.NewAnalyzerPool(
-		poolSize,
-		func() anlz.Analyzer {
-			return anlz.NewAnalyzer()
-		},
-	)
-	if err != nil {
-		return nil, err
-	}
-	return analyzerPool, nil
-}
-
-func generateItemPipeline(itemProcessors []ipl.ProcessItem) ipl.ItemPipeline {
-	return ipl.NewItemPipeline(itemProcessors)
-}
-
-// 生成组件实例代号。
-func generateCode(prefix string, id uint32) string {
-	return fmt.Sprintf("%s-%d", prefix, id)
-}
-
-// 解析组件实例代号。
-func parseCode(code string) []string {
-	result := make([]string, 2)
-	var codePrefix string
-	var id string
-	index := strings.Index(code, "-")
-	if index > 0 {
-		codePrefix = code[:index]
-		id = code[index+1:]
-	} else {
-		codePrefix = code
-	}
-	result[0] = codePrefix
-	result[1] = id
-	return result
-}
-
-var regexpForIp = regexp.MustCompile(`((?:(?:25[0-5]|2[0-4]\d|[01]?\d?\d)\.){3}(?:25[0-5]|2[0-4]\d|[01]?\d?\d))`)
-
-var regexpForDomains = []*regexp.Regexp{
-	// *.xx or *.xxx.xx
-	regexp.MustCompile(`\.(com|com\.\w{2})$`),
-	regexp.MustCompile(`\.(gov|gov\.\w{2})$`),
-	regexp.MustCompile(`\.(net|net\.\w{2})$`),
-	regexp.MustCompile(`\.(org|org\.\w{2})$`),
-	// *.xx
-	regexp.MustCompile(`\.me$`),
-	regexp.MustCompile(`\.biz$`),
-	regexp.MustCompile(`\.info$`),
-	regexp.MustCompile(`\.name$`),
-	regexp.MustCompile(`\.mobi$`),
-	regexp.MustCompile(`\.so$`),
-	regexp.MustCompile(`\.asia$`),
-	regexp.MustCompile(`\.tel$`),
-	regexp.MustCompile(`\.tv$`),
-	regexp.MustCompile(`\.cc$`),
-	regexp.MustCompile(`\.co$`),
-	regexp.MustCompile(`\.\w{2}$`),
-}
-
-func getPrimaryDomain(host string) (string, error) {
-	host = strings.TrimSpace(host)
-	if host == "" {
-		return "", errors.New("The host is empty!")
-	}
-	if regexpForIp.MatchString(host) {
-		return host, nil
-	}
-	var suffixIndex int
-	for _, re := range regexpForDomains {
-		pos := re.FindStringIndex(host)
-		if pos != nil {
-			suffixIndex = pos[0]
-			break
-		}
-	}
-	if suffixIndex > 0 {
-		var pdIndex int
-		firstPart := host[:suffixIndex]
-		index := strings.LastIndex(firstPart, ".")
-		if index < 0 {
-			pdIndex = 0
-		} else {
-			pdIndex = index + 1
-		}
-		return host[pdIndex:], nil
-	} else {
-		return "", errors.New("Unrecognized host!")
-	}
-}
+package scheduler
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"strings"
+	anlz "webcrawler/analyzer"
+	base "webcrawler/base"
+	dl "webcrawler/downloader"
+	ipl "webcrawler/itempipeline"
+	mdw "webcrawler/middleware"
+	"webcrawler/session"
+	"webcrawler/warc"
+)
+
+func generateChannelManager(channelArgs base.ChannelArgs) mdw.ChannelManager {
+	return mdw.NewChannelManager(channelArgs)
+}
+
+func generatePageDownloaderPool(
+	poolSize uint32,
+	httpClientGenerator GenHttpClient,
+	httpCache dl.HttpCache,
+	warcWriter warc.Writer) (dl.PageDownloaderPool, error) {
+	dlPool, err := dl.NewPageDownloaderPool(
+		poolSize,
+		func() dl.PageDownloader {
+			downloader := dl.NewPageDownloader(httpClientGenerator())
+			if warcWriter != nil {
+				downloader = warc.NewArchivingPageDownloader(downloader, warcWriter)
+			}
+			if httpCache != nil {
+				downloader = dl.NewCachingPageDownloader(downloader, httpCache)
+			}
+			return downloader
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+	return dlPool, nil
+}
+
+// 包装HTTP客户端的生成函数，使其生成的HTTP客户端都使用会话的Cookie罐。
+func withSessionJar(httpClientGenerator GenHttpClient, sess session.Session) GenHttpClient {
+	return func() *http.Client {
+		client := httpClientGenerator()
+		if client == nil {
+			client = &http.Client{}
+		}
+		client.Jar = sess.Jar()
+		return client
+	}
+}
+
+// 包装HTTP客户端的生成函数，使其生成的HTTP客户端都使用请求的上下文中的代理。
+func withContextProxy(httpClientGenerator GenHttpClient) GenHttpClient {
+	return func() *http.Client {
+		client := httpClientGenerator()
+		if client == nil {
+			client = &http.Client{}
+		}
+		dl.UseContextProxy(client)
+		return client
+	}
+}
+
+func generateAnalyzerPool(poolSize uint32) (anlz.AnalyzerPool, error) {
+	analyzerPool, err := anlz.NewAnalyzerPool(
+		poolSize,
+		func() anlz.Analyzer {
+			return anlz.NewAnalyzer()
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+	return analyzerPool, nil
+}
+
+func generateItemPipeline(itemProcessors []ipl.ProcessItem) ipl.ItemPipeline {
+	return ipl.NewItemPipeline(itemProcessors)
+}
+
+// 获得请求的URL。
+func getRequestUrl(req base.Request) string {
+	httpReq := req.HttpReq()
+	if httpReq == nil || httpReq.URL == nil {
+		return ""
+	}
+	return httpReq.URL.String()
+}
+
+// 读取响应体并把它替换为可重复读取的副本。结果值为响应体的字节数。
+// 若读取出错，则响应体会被替换为已读取的部分。
+func readResponseBody(httpResp *http.Response) (uint64, error) {
+	if httpResp == nil || httpResp.Body == nil {
+		return 0, nil
+	}
+	body, err := ioutil.ReadAll(httpResp.Body)
+	httpResp.Body.Close()
+	httpResp.Body = ioutil.NopCloser(bytes.NewReader(body))
+	return uint64(len(body)), err
+}
+
+// 获得响应所对应的请求的URL。
+func getResponseUrl(resp base.Response) string {
+	httpResp := resp.HttpResp()
+	if httpResp == nil || httpResp.Request == nil || httpResp.Request.URL == nil {
+		return ""
+	}
+	return httpResp.Request.URL.String()
+}
+
+// 生成组件实例代号。
+func generateCode(prefix string, id uint32) string {
+	return fmt.Sprintf("%s-%d", prefix, id)
+}
+
+// 解析组件实例代号。
+func parseCode(code string) []string {
+	result := make([]string, 2)
+	var codePrefix string
+	var id string
+	index := strings.Index(code, "-")
+	if index > 0 {
+		codePrefix = code[:index]
+		id = code[index+1:]
+	} else {
+		codePrefix = code
+	}
+	result[0] = codePrefix
+	result[1] = id
+	return result
+}
+
+var regexpForIp = regexp.MustCompile(`((?:(?:25[0-5]|2[0-4]\d|[01]?\d?\d)\.){3}(?:25[0-5]|2[0-4]\d|[01]?\d?\d))`)
+
+var regexpForDomains = []*regexp.Regexp{
+	// *.xx or *.xxx.xx
+	regexp.MustCompile(`\.(com|com\.\w{2})$`),
+	regexp.MustCompile(`\.(gov|gov\.\w{2})$`),
+	regexp.MustCompile(`\.(net|net\.\w{2})$`),
+	regexp.MustCompile(`\.(org|org\.\w{2})$`),
+	// *.xx
+	regexp.MustCompile(`\.me$`),
+	regexp.MustCompile(`\.biz$`),
+	regexp.MustCompile(`\.info$`),
+	regexp.MustCompile(`\.name$`),
+	regexp.MustCompile(`\.mobi$`),
+	regexp.MustCompile(`\.so$`),
+	regexp.MustCompile(`\.asia$`),
+	regexp.MustCompile(`\.tel$`),
+	regexp.MustCompile(`\.tv$`),
+	regexp.MustCompile(`\.cc$`),
+	regexp.MustCompile(`\.co$`),
+	regexp.MustCompile(`\.\w{2}$`),
+}
+
+func getPrimaryDomain(host string) (string, error) {
+	host = strings.TrimSpace(host)
+	if host == "" {
+		return "", errors.New("The host is empty!")
+	}
+	if regexpForIp.MatchString(host) {
+		return host, nil
+	}
+	var suffixIndex int
+	for _, re := range regexpForDomains {
+		pos := re.FindStringIndex(host)
+		if pos != nil {
+			suffixIndex = pos[0]
+			break
+		}
+	}
+	if suffixIndex > 0 {
+		var pdIndex int
+		firstPart := host[:suffixIndex]
+		index := strings.LastIndex(firstPart, ".")
+		if index < 0 {
+			pdIndex = 0
+		} else {
+			pdIndex = index + 1
+		}
+		return host[pdIndex:], nil
+	} else {
+		return "", errors.New("Unrecognized host!")
+	}
+}
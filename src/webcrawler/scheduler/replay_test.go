@@ -0,0 +1,85 @@
+package scheduler
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+	anlz "webcrawler/analyzer"
+	base "webcrawler/base"
+	dl "webcrawler/downloader"
+	ipl "webcrawler/itempipeline"
+)
+
+func TestReplayMode(t *testing.T) {
+	dir := t.TempDir()
+	pages := map[string]string{
+		"example.com/index.html": `<a href="/a.html">a</a>`,
+		"example.com/a.html":     `<a href="/b.html">b</a>`,
+	}
+	for path, content := range pages {
+		fullPath := filepath.Join(dir, filepath.FromSlash(path))
+		os.MkdirAll(filepath.Dir(fullPath), 0755)
+		if err := ioutil.WriteFile(fullPath, []byte(content), 0644); err != nil {
+			t.Fatalf("Write file error: %s", err)
+		}
+	}
+	source, err := dl.NewDirResponseSource(dir)
+	if err != nil {
+		t.Fatalf("Create response source error: %s", err)
+	}
+
+	parser := func(httpResp *http.Response, respDepth uint32, respMeta base.Meta) ([]base.Data, []error) {
+		body, _ := ioutil.ReadAll(httpResp.Body)
+		item := base.Item{"url": httpResp.Request.URL.String(), "body": string(body)}
+		// 在重放模式下，该请求应被忽略。
+		childReq, _ := http.NewRequest("GET", "http://example.com/b.html", nil)
+		return []base.Data{&item, base.NewRequest(childReq, respDepth+1)}, nil
+	}
+	var mutex sync.Mutex
+	var urls []string
+	processor := func(item base.Item) (base.Item, error) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		urls = append(urls, item["url"].(string))
+		return item, nil
+	}
+
+	sched := NewScheduler()
+	sched.SetResponseSource(source)
+	firstHttpReq, _ := http.NewRequest("GET", "http://example.com/index.html", nil)
+	err = sched.Start(
+		base.NewChannelArgs(10, 10, 10, 10),
+		base.NewPoolBaseArgs(2, 2),
+		3,
+		func() *http.Client { return &http.Client{} },
+		[]anlz.ParseResponse{parser},
+		[]ipl.ProcessItem{processor},
+		firstHttpReq)
+	if err != nil {
+		t.Fatalf("Start scheduler error: %s", err)
+	}
+	defer sched.Stop()
+	deadline := time.Now().Add(5 * time.Second)
+	for !sched.Idle() || len(sched.(*myScheduler).getRespChan()) > 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("The replay is not finished in time!")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	time.Sleep(50 * time.Millisecond)
+	mutex.Lock()
+	defer mutex.Unlock()
+	sort.Strings(urls)
+	expected := []string{"http://example.com/a.html", "http://example.com/index.html"}
+	if len(urls) != len(expected) || urls[0] != expected[0] || urls[1] != expected[1] {
+		t.Fatalf("Unexpected replayed urls %v, expected %v.", urls, expected)
+	}
+	if sched.(*myScheduler).dlpool.Stats().TakeCount != 0 {
+		t.Fatalf("The downloaders should not be used in replay mode!")
+	}
+}
@@ -1,484 +1,935 @@
-package scheduler
-
-import (
-	"errors"
-	"fmt"
-	"logging"
-	"net/http"
-	"strings"
-	"sync/atomic"
-	"time"
-	anlz "webcrawler/analyzer"
-	base "webcrawler/base"
-	dl "webcrawler/downloader"
-	ipl "webcrawler/itempipeline"
-	mdw "webcrawler/middleware"
-)
-
-// 组件的统一代号。
-const (
-	DOWNLOADER_CODE   = "downloader"
-	ANALYZER_CODE     = "analyzer"
-	ITEMPIPELINE_CODE = "item_pipeline"
-	SCHEDULER_CODE    = "scheduler"
-)
-
-// 日志记录器。
-var logger logging.Logger = base.NewLogger()
-
-// 被用来生成HTTP客户端的函数类型。
-type GenHttpClient func() *http.Client
-
-// 调度器的接口类型。
-type Scheduler interface {
-	// 开启调度器。
-	// 调用该方法会使调度器创建和初始化各个组件。在此之后，调度器会激活爬取流程的执行。
-	// 参数channelArgs代表通道参数的容器。
-	// 参数poolBaseArgs代表池基本参数的容器。
-	// 参数crawlDepth代表了需要被爬取的网页的最大深度值。深度大于此值的网页会被忽略。
-	// 参数httpClientGenerator代表的是被用来生成HTTP客户端的函数。
-	// 参数respParsers的值应为分析器所需的被用来解析HTTP响应的函数的序列。
-	// 参数itemProcessors的值应为需要被置入条目处理管道中的条目处理器的序列。
-	// 参数firstHttpReq即代表首次请求。调度器会以此为起始点开始执行爬取流程。
-	Start(channelArgs base.ChannelArgs,
-		poolBaseArgs base.PoolBaseArgs,
-		crawlDepth uint32,
-		httpClientGenerator GenHttpClient,
-		respParsers []anlz.ParseResponse,
-		itemProcessors []ipl.ProcessItem,
-		firstHttpReq *http.Request) (err error)
-	// 调用该方法会停止调度器的运行。所有处理模块执行的流程都会被中止。
-	Stop() bool
-	// 判断调度器是否正在运行。
-	Running() bool
-	// 获得错误通道。调度器以及各个处理模块运行过程中出现的所有错误都会被发送到该通道。
-	// 若该方法的结果值为nil，则说明错误通道不可用或调度器已被停止。
-	ErrorChan() <-chan error
-	// 判断所有处理模块是否都处于空闲状态。
-	Idle() bool
-	// 获取摘要信息。
-	Summary(prefix string) SchedSummary
-}
-
-// 创建调度器。
-func NewScheduler() Scheduler {
-	return &myScheduler{}
-}
-
-// 调度器的实现类型。
-type myScheduler struct {
-	channelArgs   base.ChannelArgs      // 通道参数的容器。
-	poolBaseArgs  base.PoolBaseArgs     // 池基本参数的容器。
-	crawlDepth    uint32                // 爬取的最大深度。首次请求的深度为0。
-	primaryDomain string                // 主域名。
-	chanman       mdw.ChannelManager    // 通道管理器。
-	stopSign      mdw.StopSign          // 停止信号。
-	dlpool        dl.PageDownloaderPool // 网页下载器池。
-	analyzerPool  anlz.AnalyzerPool     // 分析器池。
-	itemPipeline  ipl.ItemPipeline      // 条目处理管道。
-	reqCache      requestCache          // 请求缓存。
-	urlMap        map[string]bool       // 已请求的URL的字典。
-	running       uint32                // 运行标记。0表示未运行，1表示已运行，2表示已停止。
-}
-
-func (sched *myScheduler) Start(
-	channelArgs base.ChannelArgs,
-	poolBaseArgs base.PoolBaseArgs,
-	crawlDepth uint32,
-	httpClientGenerator GenHttpClient,
-	respParsers []anlz.ParseResponse,
-	itemProcessors []ipl.ProcessItem,
-	firstHttpReq *http.Request) (err error) {
-	defer func() {
-		if p := recover(); p != nil {
-			errMsg := fmt.Sprintf("Fatal Scheduler Error: %s\n", p)
-			logger.Fatal(errMsg)
-			err = errors.New(errMsg)
-		}
-	}()
-	if atomic.LoadUint32(&sched.running) == 1 {
-		return errors.New("The scheduler has been started!\n")
-	}
-	atomic.StoreUint32(&sched.running, 1)
-
-	if err := channelArgs.Check(); err != nil {
-		return err
-	}
-	sched.channelArgs = channelArgs
-	if err := poolBaseArgs.Check(); err != nil {
-		return err
-	}
-	sched.poolBaseArgs = poolBaseArgs
-	sched.crawlDepth = crawlDepth
-
-	sched.chanman = generateChannelManager(sched.channelArgs)
-	if httpClientGenerator == nil {
-		return errors.New("The HTTP client generator list is invalid!")
-	}
-	dlpool, err :=
-		generatePageDownloaderPool(
-			sched.poolBaseArgs.PageDownloaderPoolSize(),
-			httpClientGenerator)
-	if err != nil {
-		errMsg :=
-			fmt.Sprintf("Occur error when get page downloader pool: %s\n", err)
-		return errors.New(errMsg)
-	}
-	sched.dlpool = dlpool
-	analyzerPool, err := generateAnalyzerPool(sched.poolBaseArgs.AnalyzerPoolSize())
-	if err != nil {
-		errMsg :=
-			fmt.Sprintf("Occur error when get analyzer pool: %s\n", err)
-		return errors.New(errMsg)
-	}
-	sched.analyzerPool = analyzerPool
-
-	if itemProcessors == nil {
-		return errors.New("The item processor list is invalid!")
-	}
-	for i, ip := range itemProcessors {
-		if ip == nil {
-			return errors.New(fmt.Sprintf("The %dth item processor is invalid!", i))
-		}
-	}
-	sched.itemPipeline = generateItemPipeline(itemProcessors)
-
-	if sched.stopSign == nil {
-		sched.stopSign = mdw.NewStopSign()
-	} else {
-		sched.stopSign.Reset()
-	}
-
-	sched.reqCache = newRequestCache()
-	sched.urlMap = make(map[string]bool)
-
-	sched.startDownloading()
-	sched.activateAnalyzers(respParsers)
-	sched.openItemPipeline()
-	sched.schedule(10 * time.Millisecond)
-
-	if firstHttpReq == nil {
-		return errors.New("The first HTTP request is invalid!")
-	}
-	pd, err := getPrimaryDomain(firstHttpReq.Host)
-	if err != nil {
-		return err
-	}
-	sched.primaryDomain = pd
-
-	firstReq := base.NewRequest(firstHttpReq, 0)
-	sched.reqCache.put(firstReq)
-
-	return nil
-}
-
-func (sched *myScheduler) Stop() bool {
-	if atomic.LoadUint32(&sched.running) != 1 {
-		return false
-	}
-	sched.stopSign.Sign()
-	sched.chanman.Close()
-	sched.reqCache.close()
-	atomic.StoreUint32(&sched.running, 2)
-	return true
-}
-
-func (sched *myScheduler) Running() bool {
-	return atomic.LoadUint32(&sched.running) == 1
-}
-
-func (sched *myScheduler) ErrorChan() <-chan error {
-	if sched.chanman.Status() != mdw.CHANNEL_MANAGER_STATUS_INITIALIZED {
-		return nil
-	}
-	return sched.getErrorChan()
-}
-
-func (sched *myScheduler) Idle() bool {
-	idleDlPool := sched.dlpool.Used() == 0
-	idleAnalyzerPool := sched.analyzerPool.Used() == 0
-	idleItemPipeline := sched.itemPipeline.ProcessingNumber() == 0
-	if idleDlPool && idleAnalyzerPool && idleItemPipeline {
-		return true
-	}
-	return false
-}
-
-func (sched *myScheduler) Summary(prefix string) SchedSummary {
-	return NewSchedSummary(sched, prefix)
-}
-
-// 开始下载。
-func (sched *myScheduler) startDownloading() {
-	go func() {
-		for {
-			req, ok := <-sched.getReqChan()
-			if !ok {
-				break
-			}
-			go sched.download(req)
-		}
-	}()
-}
-
-// 下载。
-func (sched *myScheduler) download(req base.Request) {
-	defer func() {
-		if p := recover(); p != nil {
-			errMsg := fmt.Sprintf("Fatal Download Error: %s\n", p)
-			logger.Fatal(errMsg)
-		}
-	}()
-	downloader, err := sched.dlpool.Take()
-	if err != nil {
-		errMsg := fmt.Sprintf("Downloader pool error: %s", err)
-		sched.sendError(errors.New(errMsg), SCHEDULER_CODE)
-		return
-	}
-	defer func() {
-		err := sched.dlpool.Return(downloader)
-		if err != nil {
-			errMsg := fmt.Sprintf("Downloader pool error: %s", err)
-			sched.sendError(errors.New(errMsg), SCHEDULER_CODE)
-		}
-	}()
-	code := generateCode(DOWNLOADER_CODE, downloader.Id())
-	respp, err := downloader.Download(req)
-	if respp != nil {
-		sched.sendResp(*respp, code)
-	}
-	if err != nil {
-		sched.sendError(err, code)
-	}
-}
-
-// 激活分析器。
-func (sched *myScheduler) activateAnalyzers(respParsers []anlz.ParseResponse) {
-	go func() {
-		for {
-			resp, ok := <-sched.getRespChan()
-			if !ok {
-				break
-			}
-			go sched.analyze(respParsers, resp)
-		}
-	}()
-}
-
-// 分析。
-func (sched *myScheduler) analyze(respParsers []anlz.ParseResponse, resp base.Response) {
-	defer func() {
-		if p := recover(); p != nil {
-			errMsg := fmt.Sprintf("Fatal Analysis Error: %s\n", p)
-			logger.Fatal(errMsg)
-		}
-	}()
-	analyzer, err := sched.analyzerPool.Take()
-	if err != nil {
-		errMsg := fmt.Sprintf("Analyzer pool error: %s", err)
-		sched.sendError(errors.New(errMsg), SCHEDULER_CODE)
-		return
-	}
-	defer func() {
-		err := sched.analyzerPool.Return(analyzer)
-		if err != nil {
-			errMsg := fmt.Sprintf("Analyzer pool error: %s", err)
-			sched.sendError(errors.New(errMsg), SCHEDULER_CODE)
-		}
-	}()
-	code := generateCode(ANALYZER_CODE, analyzer.Id())
-	dataList, errs := analyzer.Analyze(respParsers, resp)
-	if dataList != nil {
-		for _, data := range dataList {
-			if data == nil {
-				continue
-			}
-			switch d := data.(type) {
-			case *base.Request:
-				sched.saveReqToCache(*d, code)
-			case *base.Item:
-				sched.sendItem(*d, code)
-			default:
-				errMsg := fmt.Sprintf("Unsupported data type '%T'! (value=%v)\n", d, d)
-				sched.sendError(errors.New(errMsg), code)
-			}
-		}
-	}
-	if errs != nil {
-		for _, err := range errs {
-			sched.sendError(err, code)
-		}
-	}
-}
-
-// 打开条目处理管道。
-func (sched *myScheduler) openItemPipeline() {
-	go func() {
-		sched.itemPipeline.SetFailFast(true)
-		code := ITEMPIPELINE_CODE
-		for item := range sched.getItemChan() {
-			go func(item base.Item) {
-				defer func() {
-					if p := recover(); p != nil {
-						errMsg := fmt.Sprintf("Fatal Item Processing Error: %s\n", p)
-						logger.Fatal(errMsg)
-					}
-				}()
-				errs := sched.itemPipeline.Send(item)
-				if errs != nil {
-					for _, err := range errs {
-						sched.sendError(err, code)
-					}
-				}
-			}(item)
-		}
-	}()
-}
-
-// 把请求存放到请求缓存。
-func (sched *myScheduler) saveReqToCache(req base.Request, code string) bool {
-	httpReq := req.HttpReq()
-	if httpReq == nil {
-		logger.Warnln("Ignore the request! It's HTTP request is invalid!")
-		return false
-	}
-	reqUrl := httpReq.URL
-	if reqUrl == nil {
-		logger.Warnln("Ignore the request! It's url is is invalid!")
-		return false
-	}
-	if strings.ToLower(reqUrl.Scheme) != "http" {
-		logger.Warnf("Ignore the request! It's url scheme '%s', but should be 'http'!\n", reqUrl.Scheme)
-		return false
-	}
-	if _, ok := sched.urlMap[reqUrl.String()]; ok {
-		logger.Warnf("Ignore the request! It's url is repeated. (requestUrl=%s)\n", reqUrl)
-		return false
-	}
-	if pd, _ := getPrimaryDomain(httpReq.Host); pd != sched.primaryDomain {
-		logger.Warnf("Ignore the request! It's host '%s' not in primary domain '%s'. (requestUrl=%s)\n",
-			httpReq.Host, sched.primaryDomain, reqUrl)
-		return false
-	}
-	if req.Depth() > sched.crawlDepth {
-		logger.Warnf("Ignore the request! It's depth %d greater than %d. (requestUrl=%s)\n",
-			req.Depth(), sched.crawlDepth, reqUrl)
-		return false
-	}
-	if sched.stopSign.Signed() {
-		sched.stopSign.Deal(code)
-		return false
-	}
-	sched.reqCache.put(&req)
-	sched.urlMap[reqUrl.String()] = true
-	return true
-}
-
-// 发送响应。
-func (sched *myScheduler) sendResp(resp base.Response, code string) bool {
-	if sched.stopSign.Signed() {
-		sched.stopSign.Deal(code)
-		return false
-	}
-	sched.getRespChan() <- resp
-	return true
-}
-
-// 发送条目。
-func (sched *myScheduler) sendItem(item base.Item, code string) bool {
-	if sched.stopSign.Signed() {
-		sched.stopSign.Deal(code)
-		return false
-	}
-	sched.getItemChan() <- item
-	return true
-}
-
-// 发送错误。
-func (sched *myScheduler) sendError(err error, code string) bool {
-	if err == nil {
-		return false
-	}
-	codePrefix := parseCode(code)[0]
-	var errorType base.ErrorType
-	switch codePrefix {
-	case DOWNLOADER_CODE:
-		errorType = base.DOWNLOADER_ERROR
-	case ANALYZER_CODE:
-		errorType = base.ANALYZER_ERROR
-	case ITEMPIPELINE_CODE:
-		errorType = base.ITEM_PROCESSOR_ERROR
-	}
-	cError := base.NewCrawlerError(errorType, err.Error())
-	if sched.stopSign.Signed() {
-		sched.stopSign.Deal(code)
-		return false
-	}
-	go func() {
-		sched.getErrorChan() <- cError
-	}()
-	return true
-}
-
-// 调度。适当的搬运请求缓存中的请求到请求通道。
-func (sched *myScheduler) schedule(interval time.Duration) {
-	go func() {
-		for {
-			if sched.stopSign.Signed() {
-				sched.stopSign.Deal(SCHEDULER_CODE)
-				return
-			}
-			remainder := cap(sched.getReqChan()) - len(sched.getReqChan())
-			var temp *base.Request
-			for remainder > 0 {
-				temp = sched.reqCache.get()
-				if temp == nil {
-					break
-				}
-				if sched.stopSign.Signed() {
-					sched.stopSign.Deal(SCHEDULER_CODE)
-					return
-				}
-				sched.getReqChan() <- *temp
-				remainder--
-			}
-			time.Sleep(interval)
-		}
-	}()
-}
-
-// 获取通道管理器持有的请求通道。
-func (sched *myScheduler) getReqChan() chan base.Request {
-	reqChan, err := sched.chanman.ReqChan()
-	if err != nil {
-		panic(err)
-	}
-	return reqChan
-}
-
-// 获取通道管理器持有的响应通道。
-func (sched *myScheduler) getRespChan() chan base.Response {
-	respChan, err := sched.chanman.RespChan()
-	if err != nil {
-		panic(err)
-	}
-	return respChan
-}
-
-// 获取通道管理器持有的条目通道。
-func (sched *myScheduler) getItemChan() chan base.Item {
-	itemChan, err := sched.chanman.ItemChan()
-	if err != nil {
-		panic(err)
-	}
-	return itemChan
-}
-
-// 获取通道管理器持有的错误通道。
-func (sched *myScheduler) getErrorChan() chan error {
-	errorChan, err := sched.chanman.ErrorChan()
-	if err != nil {
-		panic(err)
-	}
-	return errorChan
-}
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"logging"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	anlz "webcrawler/analyzer"
+	base "webcrawler/base"
+	dl "webcrawler/downloader"
+	"webcrawler/frontier"
+	ipl "webcrawler/itempipeline"
+	"webcrawler/linkgraph"
+	mdw "webcrawler/middleware"
+	"webcrawler/recrawl"
+	"webcrawler/session"
+	"webcrawler/warc"
+)
+
+// 组件的统一代号。
+const (
+	DOWNLOADER_CODE   = "downloader"
+	ANALYZER_CODE     = "analyzer"
+	ITEMPIPELINE_CODE = "item_pipeline"
+	SCHEDULER_CODE    = "scheduler"
+)
+
+// 日志记录器。
+var logger logging.Logger = base.NewLogger()
+
+// 被用来生成HTTP客户端的函数类型。
+type GenHttpClient func() *http.Client
+
+// 调度器的接口类型。
+type Scheduler interface {
+	// 开启调度器。
+	// 调用该方法会使调度器创建和初始化各个组件。在此之后，调度器会激活爬取流程的执行。
+	// 参数channelArgs代表通道参数的容器。
+	// 参数poolBaseArgs代表池基本参数的容器。
+	// 参数crawlDepth代表了需要被爬取的网页的最大深度值。深度大于此值的网页会被忽略。
+	// 参数httpClientGenerator代表的是被用来生成HTTP客户端的函数。
+	// 参数respParsers的值应为分析器所需的被用来解析HTTP响应的函数的序列。
+	// 参数itemProcessors的值应为需要被置入条目处理管道中的条目处理器的序列。
+	// 参数firstHttpReq即代表首次请求。调度器会以此为起始点开始执行爬取流程。
+	Start(channelArgs base.ChannelArgs,
+		poolBaseArgs base.PoolBaseArgs,
+		crawlDepth uint32,
+		httpClientGenerator GenHttpClient,
+		respParsers []anlz.ParseResponse,
+		itemProcessors []ipl.ProcessItem,
+		firstHttpReq *http.Request) (err error)
+	// 调用该方法会停止调度器的运行。所有处理模块执行的流程都会被中止。
+	Stop() bool
+	// 判断调度器是否正在运行。
+	Running() bool
+	// 获得错误通道。调度器以及各个处理模块运行过程中出现的所有错误都会被发送到该通道。
+	// 若该方法的结果值为nil，则说明错误通道不可用或调度器已被停止。
+	ErrorChan() <-chan error
+	// 订阅错误。参数bufferLen代表作为结果值的通道的长度，它不能为0。
+	// 与ErrorChan方法不同，每次调用该方法都会得到一个新的订阅通道。
+	// 每个订阅通道都会收到在其订阅之后发生的所有未被丢弃的错误。
+	// 错误的分发不会阻塞，订阅通道已满时新的错误会被丢弃，所以订阅方应及时读取。
+	SubscribeErrors(bufferLen uint) (<-chan error, error)
+	// 获得各类错误的数量。调度器发送过的每个错误都会被计入，即使它在分发时被丢弃了。
+	// 调度器被再次开启时，计数会被清零。
+	ErrorCounts() map[base.ErrorType]uint64
+	// 判断所有处理模块是否都处于空闲状态。
+	Idle() bool
+	// 订阅调度器事件。参数bufferLen代表作为结果值的通道的长度，它不能为0。
+	// 调度器会在开启、停止、忙碌与空闲之间的转换、请求缓存变空以及条目处理管道排空时发布事件。
+	// 发布事件的操作不会阻塞。若订阅通道已满，则其中最早的事件会被丢弃。
+	// 该方法可以在开启调度器之前被调用，订阅在调度器被停止和再次开启之后依然有效。
+	SubscribeEvents(bufferLen uint) (<-chan Event, error)
+	// 设置网页去重器。网页在被下载之后、被分析之前会先经过它的检查。
+	// 对于被判定为近似重复的网页，调度器不会再从中提取链接。
+	// 该方法应在开启调度器之前被调用。参数pageDedup为nil时会禁用该功能。
+	SetPageDeduplicator(pageDedup anlz.PageDeduplicator)
+	// 设置条目模式。条目处理管道会用它来校验每个条目。
+	// 未通过校验的条目会被丢弃，相应的字段错误会被发送到错误通道。
+	// 该方法应在开启调度器之前被调用。参数schema为nil时会禁用该功能。
+	SetItemSchema(schema ipl.ItemSchema)
+	// 设置网页下载器池的自动伸缩参数。
+	// 该方法应在开启调度器之前被调用。
+	SetDownloaderAutoScaling(autoScaleArgs base.AutoScaleArgs) error
+	// 设置爬取预算。调度器会拒绝超出全局预算或域名配额的请求，并丢弃超出上限的条目。
+	// 全局预算耗尽后，调度器会在已被接受的工作都处理完毕之后停止；若是因爬取时间而耗尽，则会立即停止。
+	// 预算的使用情况会被包含在摘要信息中。
+	// 该方法应在开启调度器之前被调用。
+	SetCrawlBudget(budgetArgs base.BudgetArgs) error
+	// 调整网页下载器池和分析器池的尺寸。该方法可以在调度器运行期间被调用。
+	ResizePools(poolBaseArgs base.PoolBaseArgs) error
+	// 设置爬取边界客户端。设置后，调度器会通过共享的爬取边界与其他调度器协同爬取：
+	// 新的请求会被推送到爬取边界，而调度器只会处理被分配给自己的请求。
+	// 该方法应在开启调度器之前被调用。参数client为nil时会使用本地的请求缓存。
+	SetFrontier(client frontier.Client)
+	// 设置已访问集合。调度器会用它来记录已请求过的URL并过滤重复的请求。
+	// 该方法应在开启调度器之前被调用。参数visited为nil时会使用基于内存的已访问集合。
+	SetVisitedSet(visited mdw.VisitedSet)
+	// 设置增量爬取存储。设置后，调度器会为请求添加条件请求头，
+	// 并跳过对未变化的网页（状态码为304或内容与上次相同）的分析。
+	// 开启调度器时，已到期的网页会被重新放入请求缓存。停止调度器时，访问记录会被保存。
+	// 该方法应在开启调度器之前被调用。参数store为nil时会禁用该功能。
+	SetRecrawlStore(store recrawl.Store)
+	// 设置HTTP缓存。设置后，每个网页下载器都会先在缓存中查找响应。
+	// 该方法应在开启调度器之前被调用。参数cache为nil时会禁用该功能。
+	SetHttpCache(cache dl.HttpCache)
+	// 设置WARC写入器。设置后，所有被实际下载的请求和响应都会被写入到WARC文件中。
+	// 停止调度器时，该写入器会被关闭。
+	// 该方法应在开启调度器之前被调用。参数writer为nil时会禁用该功能。
+	SetWarcWriter(writer warc.Writer)
+	// 设置响应源并开启重放模式。在重放模式下，调度器不会下载任何网页，
+	// 而是把响应源提供的响应依次交给分析器，分析得到的请求会被忽略，条目则会照常被处理。
+	// 首次请求仅被用来确定主域名。响应源耗尽之后，它会被关闭。
+	// 该方法应在开启调度器之前被调用。参数source为nil时会关闭重放模式。
+	SetResponseSource(source dl.ResponseSource)
+	// 设置会话。设置后，所有网页下载器的HTTP客户端都会共用会话的Cookie罐，
+	// 并且调度器会在放入首次请求之前登录。若登录失败，则开启调度器的操作也会失败。
+	// 停止调度器时，Cookie会被持久化。
+	// 该方法应在开启调度器之前被调用。参数sess为nil时会禁用该功能。
+	SetSession(sess session.Session)
+	// 设置请求装饰器。设置后，每个请求在被下载之前都会先被它装饰，
+	// 即被添加默认的请求头、轮换的User-Agent以及针对域名的请求头，并被分配代理。
+	// 该方法应在开启调度器之前被调用。参数decorator为nil时会禁用该功能。
+	SetRequestDecorator(decorator dl.RequestDecorator)
+	// 设置额外的种子请求。开启调度器时，它们会与首次请求一起被放入请求缓存，其深度都为0。
+	// 与首次请求不属于同一个主域名的种子请求会被忽略。
+	// 该方法应在开启调度器之前被调用。参数seeds为nil时不会有额外的种子请求。
+	SetSeeds(seeds []*http.Request)
+	// 设置链接图。设置后，分析器从每个网页中发现的链接（包括被过滤掉的链接）都会被记录到链接图中。
+	// 该方法应在开启调度器之前被调用。参数graph为nil时会禁用该功能。
+	SetLinkGraph(graph linkgraph.LinkGraph)
+	// 获取摘要信息。
+	Summary(prefix string) SchedSummary
+}
+
+// 创建调度器。
+func NewScheduler() Scheduler {
+	return &myScheduler{events: newEventBus()}
+}
+
+// 调度器的实现类型。
+type myScheduler struct {
+	channelArgs   base.ChannelArgs      // 通道参数的容器。
+	poolBaseArgs  base.PoolBaseArgs     // 池基本参数的容器。
+	crawlDepth    uint32                // 爬取的最大深度。首次请求的深度为0。
+	primaryDomain string                // 主域名。
+	chanman       mdw.ChannelManager    // 通道管理器。
+	stopSign      mdw.StopSign          // 停止信号。
+	dlpool        dl.PageDownloaderPool // 网页下载器池。
+	analyzerPool  anlz.AnalyzerPool     // 分析器池。
+	pageDedup     anlz.PageDeduplicator // 网页去重器。
+	itemSchema    ipl.ItemSchema        // 条目模式。
+	autoScaler    *autoScaler           // 网页下载器池的自动伸缩器。
+	budgetArgs    *base.BudgetArgs      // 爬取预算参数的容器。
+	budget        *crawlBudget          // 爬取预算。
+	itemPipeline  ipl.ItemPipeline      // 条目处理管道。
+	reqCache      requestCache          // 请求缓存。
+	frontier      frontier.Client       // 爬取边界客户端。
+	errorCounter  *errorCounter         // 错误计数器。
+	events        *eventBus             // 事件总线。
+	tracker       *activityTracker      // 活动跟踪器。
+	visitedSet    mdw.VisitedSet        // 被设置的已访问集合。
+	visited       mdw.VisitedSet        // 已请求的URL的集合。
+	recrawlStore  recrawl.Store         // 增量爬取存储。
+	httpCache     dl.HttpCache          // HTTP缓存。
+	warcWriter    warc.Writer           // WARC写入器。
+	respSource    dl.ResponseSource     // 重放模式下的响应源。
+	session       session.Session       // 会话。
+	decorator     dl.RequestDecorator   // 请求装饰器。
+	seeds         []*http.Request       // 额外的种子请求。
+	linkGraph     linkgraph.LinkGraph   // 链接图。
+	replayCount   uint64                // 已重放的响应的数量。
+	replayDone    uint32                // 重放完成标记。0表示未完成，1表示已完成。
+	running       uint32                // 运行标记。0表示未运行，1表示已运行，2表示已停止。
+	// 针对各个组件以及参数容器的读写锁。
+	// 开启、停止调度器以及调整池尺寸的操作会持有写锁，而其他的公开方法会持有读锁，
+	// 以保证它们不会看到初始化到一半的调度器。
+	rwmutex sync.RWMutex
+}
+
+func (sched *myScheduler) Start(
+	channelArgs base.ChannelArgs,
+	poolBaseArgs base.PoolBaseArgs,
+	crawlDepth uint32,
+	httpClientGenerator GenHttpClient,
+	respParsers []anlz.ParseResponse,
+	itemProcessors []ipl.ProcessItem,
+	firstHttpReq *http.Request) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			errMsg := fmt.Sprintf("Fatal Scheduler Error: %s\n", p)
+			logger.Fatal(errMsg)
+			err = errors.New(errMsg)
+		}
+	}()
+	sched.rwmutex.Lock()
+	defer sched.rwmutex.Unlock()
+	if atomic.LoadUint32(&sched.running) == 1 {
+		return errors.New("The scheduler has been started!\n")
+	}
+	atomic.StoreUint32(&sched.running, 1)
+
+	if err := channelArgs.Check(); err != nil {
+		return err
+	}
+	sched.channelArgs = channelArgs
+	if err := poolBaseArgs.Check(); err != nil {
+		return err
+	}
+	sched.poolBaseArgs = poolBaseArgs
+	sched.crawlDepth = crawlDepth
+
+	if httpClientGenerator == nil {
+		return errors.New("The HTTP client generator list is invalid!")
+	}
+	if sched.session != nil {
+		httpClientGenerator = withSessionJar(httpClientGenerator, sched.session)
+	}
+	if sched.decorator != nil && sched.decorator.HasProxies() {
+		httpClientGenerator = withContextProxy(httpClientGenerator)
+	}
+	// 登录须在各个组件开启之前完成，以免登录失败时留下仍在运行的goroutine。
+	if sched.session != nil {
+		if err := sched.session.Login(httpClientGenerator()); err != nil {
+			atomic.StoreUint32(&sched.running, 0)
+			return err
+		}
+	}
+
+	sched.chanman = generateChannelManager(sched.channelArgs)
+	dlpool, err :=
+		generatePageDownloaderPool(
+			sched.poolBaseArgs.PageDownloaderPoolSize(),
+			httpClientGenerator,
+			sched.httpCache,
+			sched.warcWriter)
+	if err != nil {
+		errMsg :=
+			fmt.Sprintf("Occur error when get page downloader pool: %s\n", err)
+		return errors.New(errMsg)
+	}
+	sched.dlpool = dlpool
+	analyzerPool, err := generateAnalyzerPool(sched.poolBaseArgs.AnalyzerPoolSize())
+	if err != nil {
+		errMsg :=
+			fmt.Sprintf("Occur error when get analyzer pool: %s\n", err)
+		return errors.New(errMsg)
+	}
+	sched.analyzerPool = analyzerPool
+
+	if itemProcessors == nil {
+		return errors.New("The item processor list is invalid!")
+	}
+	for i, ip := range itemProcessors {
+		if ip == nil {
+			return errors.New(fmt.Sprintf("The %dth item processor is invalid!", i))
+		}
+	}
+	sched.itemPipeline = generateItemPipeline(itemProcessors)
+	sched.itemPipeline.SetSchema(sched.itemSchema)
+
+	if sched.stopSign == nil {
+		sched.stopSign = mdw.NewStopSign()
+	} else {
+		sched.stopSign.Reset()
+	}
+
+	if sched.frontier != nil {
+		sched.reqCache = newFrontierRequestCache(sched.frontier)
+	} else {
+		sched.reqCache = newRequestCache()
+	}
+	sched.tracker = newActivityTracker(sched.events, sched.reqCache.length)
+	sched.budget = nil
+	if sched.budgetArgs != nil {
+		sched.budget = newCrawlBudget(*sched.budgetArgs, sched.onBudgetExhausted)
+		sched.budget.start()
+	}
+	sched.errorCounter = newErrorCounter()
+	if sched.visitedSet != nil {
+		sched.visited = sched.visitedSet
+	} else {
+		sched.visited = mdw.NewMemoryVisitedSet()
+	}
+
+	if sched.respSource != nil {
+		atomic.StoreUint64(&sched.replayCount, 0)
+		atomic.StoreUint32(&sched.replayDone, 0)
+		sched.startReplaying()
+	} else {
+		sched.startDownloading()
+	}
+	sched.activateAnalyzers(respParsers)
+	sched.openItemPipeline()
+	sched.schedule(10 * time.Millisecond)
+	if sched.autoScaler != nil {
+		sched.autoScale()
+	}
+
+	if firstHttpReq == nil {
+		return errors.New("The first HTTP request is invalid!")
+	}
+	pd, err := getPrimaryDomain(firstHttpReq.Host)
+	if err != nil {
+		return err
+	}
+	sched.primaryDomain = pd
+
+	if sched.respSource != nil {
+		sched.events.publish(EVENT_STARTED)
+		return nil
+	}
+	firstReq := base.NewRequest(firstHttpReq, 0)
+	firstReq.Meta()[base.META_KEY_SEED_URL] = firstHttpReq.URL.String()
+	if sched.budget != nil {
+		sched.budget.admit(budgetHost(firstHttpReq.URL))
+	}
+	sched.reqCache.put(firstReq)
+	sched.visited.Add(firstReq.DedupeKey())
+	for _, seed := range sched.seeds {
+		if seed == nil || seed.URL == nil {
+			continue
+		}
+		req := base.NewRequest(seed, 0)
+		req.Meta()[base.META_KEY_SEED_URL] = seed.URL.String()
+		sched.saveReqToCache(*req, SCHEDULER_CODE)
+	}
+	sched.scheduleRevisits(firstHttpReq.URL.String())
+	sched.events.publish(EVENT_STARTED)
+
+	return nil
+}
+
+func (sched *myScheduler) Stop() bool {
+	sched.rwmutex.Lock()
+	defer sched.rwmutex.Unlock()
+	if atomic.LoadUint32(&sched.running) != 1 {
+		return false
+	}
+	sched.stopSign.Sign()
+	sched.chanman.Close()
+	sched.reqCache.close()
+	if sched.budget != nil {
+		sched.budget.stop()
+	}
+	if sched.recrawlStore != nil {
+		if err := sched.recrawlStore.Save(); err != nil {
+			logger.Errorf("Save recrawl store error: %s\n", err)
+		}
+	}
+	if sched.session != nil {
+		if err := sched.session.Save(); err != nil {
+			logger.Errorf("Save session error: %s\n", err)
+		}
+	}
+	if sched.warcWriter != nil {
+		if err := sched.warcWriter.Close(); err != nil {
+			logger.Errorf("Close WARC writer error: %s\n", err)
+		}
+	}
+	atomic.StoreUint32(&sched.running, 2)
+	sched.events.publish(EVENT_STOPPED)
+	return true
+}
+
+func (sched *myScheduler) Running() bool {
+	return atomic.LoadUint32(&sched.running) == 1
+}
+
+func (sched *myScheduler) ErrorChan() <-chan error {
+	sched.rwmutex.RLock()
+	defer sched.rwmutex.RUnlock()
+	if sched.chanman.Status() != mdw.CHANNEL_MANAGER_STATUS_INITIALIZED {
+		return nil
+	}
+	return sched.getErrorChan()
+}
+
+func (sched *myScheduler) SubscribeErrors(bufferLen uint) (<-chan error, error) {
+	sched.rwmutex.RLock()
+	defer sched.rwmutex.RUnlock()
+	if sched.chanman == nil {
+		return nil, errors.New("The scheduler has not been started!")
+	}
+	errorDisp, err := sched.chanman.ErrorDispatcher()
+	if err != nil {
+		return nil, err
+	}
+	return errorDisp.Subscribe(bufferLen)
+}
+
+func (sched *myScheduler) ErrorCounts() map[base.ErrorType]uint64 {
+	sched.rwmutex.RLock()
+	defer sched.rwmutex.RUnlock()
+	if sched.errorCounter == nil {
+		return make(map[base.ErrorType]uint64)
+	}
+	return sched.errorCounter.counts()
+}
+
+func (sched *myScheduler) Idle() bool {
+	sched.rwmutex.RLock()
+	defer sched.rwmutex.RUnlock()
+	idleDlPool := sched.dlpool.Used() == 0
+	idleAnalyzerPool := sched.analyzerPool.Used() == 0
+	idleItemPipeline := sched.itemPipeline.ProcessingNumber() == 0
+	if sched.respSource != nil && atomic.LoadUint32(&sched.replayDone) == 0 {
+		return false
+	}
+	if idleDlPool && idleAnalyzerPool && idleItemPipeline && sched.tracker.idle() {
+		return true
+	}
+	return false
+}
+
+func (sched *myScheduler) SubscribeEvents(bufferLen uint) (<-chan Event, error) {
+	return sched.events.subscribe(bufferLen)
+}
+
+func (sched *myScheduler) SetPageDeduplicator(pageDedup anlz.PageDeduplicator) {
+	sched.pageDedup = pageDedup
+}
+
+func (sched *myScheduler) SetItemSchema(schema ipl.ItemSchema) {
+	sched.itemSchema = schema
+}
+
+func (sched *myScheduler) SetDownloaderAutoScaling(autoScaleArgs base.AutoScaleArgs) error {
+	if err := autoScaleArgs.Check(); err != nil {
+		return err
+	}
+	sched.autoScaler = newAutoScaler(autoScaleArgs)
+	return nil
+}
+
+func (sched *myScheduler) SetCrawlBudget(budgetArgs base.BudgetArgs) error {
+	if err := budgetArgs.Check(); err != nil {
+		return err
+	}
+	sched.budgetArgs = &budgetArgs
+	return nil
+}
+
+func (sched *myScheduler) ResizePools(poolBaseArgs base.PoolBaseArgs) error {
+	sched.rwmutex.Lock()
+	defer sched.rwmutex.Unlock()
+	if !sched.Running() {
+		return errors.New("The scheduler is not running!")
+	}
+	if err := poolBaseArgs.Check(); err != nil {
+		return err
+	}
+	if err := sched.dlpool.Resize(poolBaseArgs.PageDownloaderPoolSize()); err != nil {
+		return err
+	}
+	if err := sched.analyzerPool.Resize(poolBaseArgs.AnalyzerPoolSize()); err != nil {
+		return err
+	}
+	sched.poolBaseArgs = poolBaseArgs
+	return nil
+}
+
+func (sched *myScheduler) SetFrontier(client frontier.Client) {
+	sched.frontier = client
+}
+
+func (sched *myScheduler) SetVisitedSet(visited mdw.VisitedSet) {
+	sched.visitedSet = visited
+}
+
+func (sched *myScheduler) SetRecrawlStore(store recrawl.Store) {
+	sched.recrawlStore = store
+}
+
+func (sched *myScheduler) SetHttpCache(cache dl.HttpCache) {
+	sched.httpCache = cache
+}
+
+func (sched *myScheduler) SetWarcWriter(writer warc.Writer) {
+	sched.warcWriter = writer
+}
+
+func (sched *myScheduler) SetResponseSource(source dl.ResponseSource) {
+	sched.respSource = source
+}
+
+func (sched *myScheduler) SetSession(sess session.Session) {
+	sched.session = sess
+}
+
+func (sched *myScheduler) SetRequestDecorator(decorator dl.RequestDecorator) {
+	sched.decorator = decorator
+}
+
+func (sched *myScheduler) SetSeeds(seeds []*http.Request) {
+	sched.seeds = seeds
+}
+
+func (sched *myScheduler) SetLinkGraph(graph linkgraph.LinkGraph) {
+	sched.linkGraph = graph
+}
+
+func (sched *myScheduler) Summary(prefix string) SchedSummary {
+	sched.rwmutex.RLock()
+	defer sched.rwmutex.RUnlock()
+	return NewSchedSummary(sched, prefix)
+}
+
+// 开始下载。
+func (sched *myScheduler) startDownloading() {
+	go func() {
+		for {
+			req, ok := <-sched.getReqChan()
+			if !ok {
+				break
+			}
+			go func(req base.Request) {
+				defer sched.tracker.end()
+				sched.download(req)
+			}(req)
+		}
+	}()
+}
+
+// 开始重放。依次把响应源提供的响应发送到响应通道。
+func (sched *myScheduler) startReplaying() {
+	// 重放期间始终持有一个工作单元，以免在两次重放之间被判定为空闲。
+	sched.tracker.begin()
+	go func() {
+		defer func() {
+			sched.respSource.Close()
+			atomic.StoreUint32(&sched.replayDone, 1)
+			sched.tracker.end()
+		}()
+		for {
+			resp, err := sched.respSource.Next()
+			if err == io.EOF {
+				logger.Infof("The replay is finished. (responses=%d)\n",
+					atomic.LoadUint64(&sched.replayCount))
+				return
+			}
+			if err != nil {
+				sched.sendError(err, SCHEDULER_CODE, "", 0)
+				continue
+			}
+			if !sched.sendResp(*resp, SCHEDULER_CODE) {
+				return
+			}
+			atomic.AddUint64(&sched.replayCount, 1)
+		}
+	}()
+}
+
+// 下载。
+func (sched *myScheduler) download(req base.Request) {
+	defer func() {
+		if p := recover(); p != nil {
+			errMsg := fmt.Sprintf("Fatal Download Error: %s\n", p)
+			logger.Fatal(errMsg)
+		}
+	}()
+	reqUrl := getRequestUrl(req)
+	host := budgetHost(req.HttpReq().URL)
+	if sched.budget != nil && !sched.budget.allowDownload(host) {
+		logger.Warnf("Skip the download! The crawl budget is exhausted. (requestUrl=%s)\n", reqUrl)
+		return
+	}
+	downloader, err := sched.dlpool.Take()
+	if err != nil {
+		errMsg := fmt.Sprintf("Downloader pool error: %s", err)
+		sched.sendError(errors.New(errMsg), SCHEDULER_CODE, reqUrl, req.Depth())
+		return
+	}
+	defer func() {
+		err := sched.dlpool.Return(downloader)
+		if err != nil {
+			errMsg := fmt.Sprintf("Downloader pool error: %s", err)
+			sched.sendError(errors.New(errMsg), SCHEDULER_CODE, reqUrl, req.Depth())
+		}
+	}()
+	code := generateCode(DOWNLOADER_CODE, downloader.Id())
+	if sched.decorator != nil {
+		req, err = sched.decorator.Decorate(req)
+		if err != nil {
+			sched.sendError(err, code, reqUrl, req.Depth())
+			return
+		}
+	}
+	if sched.recrawlStore != nil {
+		sched.recrawlStore.Prepare(&req)
+	}
+	startTime := time.Now()
+	respp, err := downloader.Download(req)
+	if sched.autoScaler != nil {
+		sched.autoScaler.record(time.Since(startTime))
+	}
+	if respp != nil && sched.budget != nil {
+		n, readErr := readResponseBody(respp.HttpResp())
+		if readErr != nil {
+			sched.sendError(readErr, code, reqUrl, req.Depth())
+		}
+		sched.budget.recordBytes(host, n)
+	}
+	if respp != nil && sched.recrawlStore != nil {
+		changed, err := sched.recrawlStore.Update(respp)
+		if err != nil {
+			sched.sendError(err, code, reqUrl, req.Depth())
+		}
+		if !changed {
+			logger.Infof("Skip the analysis! The page is unchanged. (requestUrl=%s)\n", reqUrl)
+			respp = nil
+		}
+	}
+	if respp != nil {
+		sched.sendResp(*respp, code)
+	}
+	if err != nil {
+		sched.sendError(err, code, reqUrl, req.Depth())
+	}
+}
+
+// 激活分析器。
+func (sched *myScheduler) activateAnalyzers(respParsers []anlz.ParseResponse) {
+	go func() {
+		for {
+			resp, ok := <-sched.getRespChan()
+			if !ok {
+				break
+			}
+			go func(resp base.Response) {
+				defer sched.tracker.end()
+				sched.analyze(respParsers, resp)
+			}(resp)
+		}
+	}()
+}
+
+// 分析。
+func (sched *myScheduler) analyze(respParsers []anlz.ParseResponse, resp base.Response) {
+	defer func() {
+		if p := recover(); p != nil {
+			errMsg := fmt.Sprintf("Fatal Analysis Error: %s\n", p)
+			logger.Fatal(errMsg)
+		}
+	}()
+	respUrl := getResponseUrl(resp)
+	analyzer, err := sched.analyzerPool.Take()
+	if err != nil {
+		errMsg := fmt.Sprintf("Analyzer pool error: %s", err)
+		sched.sendError(errors.New(errMsg), SCHEDULER_CODE, respUrl, resp.Depth())
+		return
+	}
+	defer func() {
+		err := sched.analyzerPool.Return(analyzer)
+		if err != nil {
+			errMsg := fmt.Sprintf("Analyzer pool error: %s", err)
+			sched.sendError(errors.New(errMsg), SCHEDULER_CODE, respUrl, resp.Depth())
+		}
+	}()
+	code := generateCode(ANALYZER_CODE, analyzer.Id())
+	var nearDup bool
+	if sched.pageDedup != nil {
+		// 先把响应体转换为UTF-8，以免同一内容因编码不同而得到不同的指纹。
+		// 转换时发生的错误会由分析器再次转换时报告，这里忽略即可。
+		anlz.DecodeToUtf8(resp.HttpResp())
+		nearDup, err = sched.pageDedup.Check(resp.HttpResp())
+		if err != nil {
+			sched.sendError(err, code, respUrl, resp.Depth())
+		}
+	}
+	dataList, errs := analyzer.Analyze(respParsers, resp)
+	if dataList != nil {
+		for _, data := range dataList {
+			if data == nil {
+				continue
+			}
+			switch d := data.(type) {
+			case *base.Request:
+				sched.recordLink(respUrl, d)
+				if sched.respSource != nil {
+					// 在重放模式下，不会跟进任何链接。
+					continue
+				}
+				if nearDup {
+					logger.Warnf("Ignore the request! It's parent page is a near-duplicate. (requestUrl=%s)\n",
+						d.HttpReq().URL)
+					continue
+				}
+				sched.saveReqToCache(*d, code)
+			case *base.Item:
+				sched.sendItem(*d, code)
+			default:
+				errMsg := fmt.Sprintf("Unsupported data type '%T'! (value=%v)\n", d, d)
+				sched.sendError(errors.New(errMsg), code, respUrl, resp.Depth())
+			}
+		}
+	}
+	if errs != nil {
+		for _, err := range errs {
+			sched.sendError(err, code, respUrl, resp.Depth())
+		}
+	}
+}
+
+// 把从网页中发现的链接记录到链接图中。参数parentUrl代表该网页的URL。
+func (sched *myScheduler) recordLink(parentUrl string, req *base.Request) {
+	if sched.linkGraph == nil || parentUrl == "" {
+		return
+	}
+	httpReq := req.HttpReq()
+	if httpReq == nil || httpReq.URL == nil {
+		return
+	}
+	anchor, _ := req.Meta()[base.META_KEY_ANCHOR_TEXT].(string)
+	sched.linkGraph.AddEdge(linkgraph.Edge{
+		From:   parentUrl,
+		To:     httpReq.URL.String(),
+		Anchor: anchor,
+		Depth:  req.Depth(),
+	})
+}
+
+// 打开条目处理管道。
+func (sched *myScheduler) openItemPipeline() {
+	sched.itemPipeline.SetFailFast(true)
+	go func() {
+		code := ITEMPIPELINE_CODE
+		for item := range sched.getItemChan() {
+			go func(item base.Item) {
+				defer sched.tracker.endItem()
+				defer func() {
+					if p := recover(); p != nil {
+						errMsg := fmt.Sprintf("Fatal Item Processing Error: %s\n", p)
+						logger.Fatal(errMsg)
+					}
+				}()
+				errs := sched.itemPipeline.Send(item)
+				if errs != nil {
+					for _, err := range errs {
+						sched.sendError(err, code, "", 0)
+					}
+				}
+			}(item)
+		}
+	}()
+}
+
+// 把增量爬取存储中已到期的网页重新放入请求缓存。参数firstUrl代表首次请求的URL。
+func (sched *myScheduler) scheduleRevisits(firstUrl string) {
+	if sched.recrawlStore == nil {
+		return
+	}
+	for _, record := range sched.recrawlStore.Due(time.Now()) {
+		if record.Url == firstUrl {
+			continue
+		}
+		httpReq, err := http.NewRequest("GET", record.Url, nil)
+		if err != nil {
+			sched.sendError(err, SCHEDULER_CODE, record.Url, record.Depth)
+			continue
+		}
+		req := base.NewRequest(httpReq, record.Depth)
+		req.Meta()[base.META_KEY_SEED_URL] = firstUrl
+		sched.saveReqToCache(*req, SCHEDULER_CODE)
+	}
+}
+
+// 把请求存放到请求缓存。
+func (sched *myScheduler) saveReqToCache(req base.Request, code string) bool {
+	httpReq := req.HttpReq()
+	if httpReq == nil {
+		logger.Warnln("Ignore the request! It's HTTP request is invalid!")
+		sched.sendError(base.WrapCrawlerError(base.FILTER_ERROR,
+			errors.New("The HTTP request is invalid!"), "", req.Depth(), code), code, "", req.Depth())
+		return false
+	}
+	reqUrl := httpReq.URL
+	if reqUrl == nil {
+		logger.Warnln("Ignore the request! It's url is is invalid!")
+		sched.sendError(base.WrapCrawlerError(base.FILTER_ERROR,
+			errors.New("The url of HTTP request is invalid!"), "", req.Depth(), code), code, "", req.Depth())
+		return false
+	}
+	if scheme := strings.ToLower(reqUrl.Scheme); scheme != "http" && scheme != "https" {
+		logger.Warnf("Ignore the request! It's url scheme '%s', but should be 'http' or 'https'!\n", reqUrl.Scheme)
+		return false
+	}
+	dedupeKey := req.DedupeKey()
+	if sched.visited.Contains(dedupeKey) {
+		logger.Warnf("Ignore the request! It's url is repeated. (requestUrl=%s)\n", reqUrl)
+		return false
+	}
+	if pd, _ := getPrimaryDomain(httpReq.Host); pd != sched.primaryDomain {
+		logger.Warnf("Ignore the request! It's host '%s' not in primary domain '%s'. (requestUrl=%s)\n",
+			httpReq.Host, sched.primaryDomain, reqUrl)
+		return false
+	}
+	if req.Depth() > sched.crawlDepth {
+		logger.Warnf("Ignore the request! It's depth %d greater than %d. (requestUrl=%s)\n",
+			req.Depth(), sched.crawlDepth, reqUrl)
+		return false
+	}
+	if sched.stopSign.Signed() {
+		sched.stopSign.Deal(code)
+		return false
+	}
+	if !sched.visited.Add(dedupeKey) {
+		logger.Warnf("Ignore the request! It's url is repeated. (requestUrl=%s)\n", reqUrl)
+		return false
+	}
+	if sched.budget != nil && !sched.budget.admit(budgetHost(reqUrl)) {
+		logger.Warnf("Ignore the request! The crawl budget is exhausted. (requestUrl=%s)\n", reqUrl)
+		return false
+	}
+	sched.reqCache.put(&req)
+	return true
+}
+
+// 发送响应。
+func (sched *myScheduler) sendResp(resp base.Response, code string) bool {
+	if sched.stopSign.Signed() {
+		sched.stopSign.Deal(code)
+		return false
+	}
+	// 先开始响应的工作单元，再结束请求的工作单元。
+	sched.tracker.begin()
+	sched.getRespChan() <- resp
+	return true
+}
+
+// 发送条目。
+func (sched *myScheduler) sendItem(item base.Item, code string) bool {
+	if sched.stopSign.Signed() {
+		sched.stopSign.Deal(code)
+		return false
+	}
+	if sched.budget != nil && !sched.budget.takeItem() {
+		logger.Warnln("Ignore the item! The crawl budget is exhausted.")
+		return false
+	}
+	sched.tracker.beginItem()
+	sched.getItemChan() <- item
+	return true
+}
+
+// 发送错误。
+// 参数reqUrl和depth代表与错误相关的请求的URL和深度。
+func (sched *myScheduler) sendError(err error, code string, reqUrl string, depth uint32) bool {
+	if err == nil {
+		return false
+	}
+	cError, ok := err.(base.CrawlerError)
+	if !ok {
+		errorType := classifyError(err, parseCode(code)[0])
+		cError = base.WrapCrawlerError(errorType, err, reqUrl, depth, code)
+	}
+	sched.errorCounter.add(cError.Type())
+	if sched.stopSign.Signed() {
+		sched.stopSign.Deal(code)
+		return false
+	}
+	errorDisp, err := sched.chanman.ErrorDispatcher()
+	if err != nil {
+		return false
+	}
+	return errorDisp.Put(cError)
+}
+
+// 调度。适当的搬运请求缓存中的请求到请求通道。
+func (sched *myScheduler) schedule(interval time.Duration) {
+	go func() {
+		for {
+			if sched.stopSign.Signed() {
+				sched.stopSign.Deal(SCHEDULER_CODE)
+				return
+			}
+			remainder := cap(sched.getReqChan()) - len(sched.getReqChan())
+			var temp *base.Request
+			for remainder > 0 {
+				temp = sched.tracker.take(sched.reqCache.get)
+				if temp == nil {
+					break
+				}
+				if sched.stopSign.Signed() {
+					sched.stopSign.Deal(SCHEDULER_CODE)
+					return
+				}
+				sched.getReqChan() <- *temp
+				remainder--
+			}
+			time.Sleep(interval)
+		}
+	}()
+}
+
+// 获取通道管理器持有的请求通道。
+func (sched *myScheduler) getReqChan() chan base.Request {
+	reqChan, err := sched.chanman.ReqChan()
+	if err != nil {
+		panic(err)
+	}
+	return reqChan
+}
+
+// 获取通道管理器持有的响应通道。
+func (sched *myScheduler) getRespChan() chan base.Response {
+	respChan, err := sched.chanman.RespChan()
+	if err != nil {
+		panic(err)
+	}
+	return respChan
+}
+
+// 获取通道管理器持有的条目通道。
+func (sched *myScheduler) getItemChan() chan base.Item {
+	itemChan, err := sched.chanman.ItemChan()
+	if err != nil {
+		panic(err)
+	}
+	return itemChan
+}
+
+// 获取通道管理器持有的默认的错误订阅通道。
+func (sched *myScheduler) getErrorChan() <-chan error {
+	errorChan, err := sched.chanman.ErrorChan()
+	if err != nil {
+		panic(err)
+	}
+	return errorChan
+}
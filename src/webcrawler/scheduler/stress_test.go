@@ -0,0 +1,160 @@
+package scheduler
+
+import (
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+	anlz "webcrawler/analyzer"
+	base "webcrawler/base"
+	ipl "webcrawler/itempipeline"
+	"webcrawler/testhelper"
+)
+
+func TestRequestCacheConcurrently(t *testing.T) {
+	rcache := newRequestCache()
+	var wg sync.WaitGroup
+	var mutex sync.Mutex
+	got := 0
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 200; j++ {
+				httpReq, _ := http.NewRequest("GET", "http://example.com/", nil)
+				rcache.put(base.NewRequest(httpReq, 0))
+				rcache.summary()
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 200; j++ {
+				if rcache.get() != nil {
+					mutex.Lock()
+					got++
+					mutex.Unlock()
+				}
+				rcache.length()
+				rcache.capacity()
+			}
+		}()
+	}
+	wg.Wait()
+	if got+rcache.length() != 8*200 {
+		t.Errorf("The number of requests should be %d, but %d!", 8*200, got+rcache.length())
+	}
+	rcache.close()
+	httpReq, _ := http.NewRequest("GET", "http://example.com/", nil)
+	if rcache.put(base.NewRequest(httpReq, 0)) || rcache.get() != nil {
+		t.Errorf("The closed request cache should not be usable!")
+	}
+}
+
+// 在爬取一个较大的站点的同时并发地调用调度器的各个公开方法。
+// 该测试主要用于在开启竞态检测时发现数据竞争。
+func TestSchedulerStress(t *testing.T) {
+	site, err := testhelper.NewSiteServer(testhelper.SiteArgs{
+		Depth:         3,
+		FanOut:        5,
+		Cycles:        true,
+		RedirectEvery: 7,
+		ErrorEvery:    11,
+		SlowEvery:     13,
+		SlowDelay:     5 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("Create site error: %s", err)
+	}
+	defer site.Close()
+
+	sched := NewScheduler()
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	// 在调度器开启之前就开始观察它。
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				if sched.Running() {
+					summary := sched.Summary("  ")
+					summary.Same(sched.Summary("  "))
+					_ = summary.Detail()
+					sched.Idle()
+					if i == 0 {
+						sched.ResizePools(base.NewPoolBaseArgs(uint32(2+time.Now().Nanosecond()%3), 3))
+					}
+				}
+				time.Sleep(time.Millisecond)
+			}
+		}(i)
+	}
+	errorDrained := make(chan struct{})
+	var items uint64
+	var itemMutex sync.Mutex
+	processor := func(item base.Item) (base.Item, error) {
+		itemMutex.Lock()
+		items++
+		itemMutex.Unlock()
+		return item, nil
+	}
+	firstHttpReq, _ := http.NewRequest("GET", site.URL()+"/", nil)
+	err = sched.Start(
+		base.NewChannelArgs(5, 5, 5, 5),
+		base.NewPoolBaseArgs(3, 3),
+		3,
+		func() *http.Client { return &http.Client{Timeout: 5 * time.Second} },
+		[]anlz.ParseResponse{parseSitePage},
+		[]ipl.ProcessItem{processor},
+		firstHttpReq)
+	if err != nil {
+		t.Fatalf("Start scheduler error: %s", err)
+	}
+	errChan, err := sched.SubscribeErrors(10)
+	if err != nil {
+		t.Fatalf("Subscribe errors error: %s", err)
+	}
+	go func() {
+		defer close(errorDrained)
+		for range errChan {
+		}
+	}()
+
+	deadline := time.Now().Add(20 * time.Second)
+	idleSince := time.Time{}
+	for {
+		if time.Now().After(deadline) {
+			t.Errorf("The crawl is not finished in time!")
+			break
+		}
+		if sched.Idle() {
+			if idleSince.IsZero() {
+				idleSince = time.Now()
+			} else if time.Since(idleSince) > 200*time.Millisecond {
+				break
+			}
+		} else {
+			idleSince = time.Time{}
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if !sched.Stop() {
+		t.Errorf("The scheduler should be stopped!")
+	}
+	close(stop)
+	wg.Wait()
+	<-errorDrained
+
+	paths, titles := expectedPaths(site.Pages())
+	assertStrings(t, "visited paths", site.VisitedPaths(), paths)
+	itemMutex.Lock()
+	defer itemMutex.Unlock()
+	if items != uint64(len(titles)) {
+		t.Errorf("The number of items should be %d, but %d!", len(titles), items)
+	}
+}
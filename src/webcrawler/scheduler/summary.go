@@ -1,144 +1,298 @@
-package scheduler
-
-import (
-	"bytes"
-	"fmt"
-	base "webcrawler/base"
-)
-
-// 调度器摘要信息的接口类型。
-type SchedSummary interface {
-	String() string               // 获得摘要信息的一般表示。
-	Detail() string               // 获取摘要信息的详细表示。
-	Same(other SchedSummary) bool // 判断是否与另一份摘要信息相同。
-}
-
-// 创建调度器摘要信息。
-func NewSchedSummary(sched *myScheduler, prefix string) SchedSummary {
-	if sched == nil {
-		return nil
-	}
-	urlCount := len(sched.urlMap)
-	var urlDetail string
-	if urlCount > 0 {
-		var buffer bytes.Buffer
-		buffer.WriteByte('\n')
-		for k, _ := range sched.urlMap {
-			buffer.WriteString(prefix)
-			buffer.WriteString(prefix)
-			buffer.WriteString(k)
-			buffer.WriteByte('\n')
-		}
-		urlDetail = buffer.String()
-	} else {
-		urlDetail = "\n"
-	}
-	return &mySchedSummary{
-		prefix:              prefix,
-		running:             sched.running,
-		channelArgs:         sched.channelArgs,
-		poolBaseArgs:        sched.poolBaseArgs,
-		crawlDepth:          sched.crawlDepth,
-		chanmanSummary:      sched.chanman.Summary(),
-		reqCacheSummary:     sched.reqCache.summary(),
-		dlPoolLen:           sched.dlpool.Used(),
-		dlPoolCap:           sched.dlpool.Total(),
-		analyzerPoolLen:     sched.analyzerPool.Used(),
-		analyzerPoolCap:     sched.analyzerPool.Total(),
-		itemPipelineSummary: sched.itemPipeline.Summary(),
-		urlCount:            urlCount,
-		urlDetail:           urlDetail,
-		stopSignSummary:     sched.stopSign.Summary(),
-	}
-}
-
-// 调度器摘要信息的实现类型。
-type mySchedSummary struct {
-	prefix              string            // 前缀。
-	running             uint32            // 运行标记。
-	channelArgs         base.ChannelArgs  // 通道参数的容器。
-	poolBaseArgs        base.PoolBaseArgs // 池基本参数的容器。
-	crawlDepth          uint32            // 爬取的最大深度。
-	chanmanSummary      string            // 通道管理器的摘要信息。
-	reqCacheSummary     string            // 请求缓存的摘要信息。
-	dlPoolLen           uint32            // 网页下载器池的长度。
-	dlPoolCap           uint32            // 网页下载器池的容量。
-	analyzerPoolLen     uint32            // 分析器池的长度。
-	analyzerPoolCap     uint32            // 分析器池的容量。
-	itemPipelineSummary string            // 条目处理管道的摘要信息。
-	urlCount            int               // 已请求的URL的计数。
-	urlDetail           string            // 已请求的URL的详细信息。
-	stopSignSummary     string            // 停止信号的摘要信息。
-}
-
-func (ss *mySchedSummary) String() string {
-	return ss.getSummary(false)
-}
-
-func (ss *mySchedSummary) Detail() string {
-	return ss.getSummary(true)
-}
-
-// 获取摘要信息。
-func (ss *mySchedSummary) getSummary(detail bool) string {
-	prefix := ss.prefix
-	template := prefix + "Running: %v \n" +
-		prefix + "Channel args: %s \n" +
-		prefix + "Pool base args: %s \n" +
-		prefix + "Crawl depth: %d \n" +
-		prefix + "Channels manager: %s \n" +
-		prefix + "Request cache: %s\n" +
-		prefix + "Downloader pool: %d/%d\n" +
-		prefix + "Analyzer pool: %d/%d\n" +
-		prefix + "Item pipeline: %s\n" +
-		prefix + "Urls(%d): %s" +
-		prefix + "Stop sign: %s\n"
-	return fmt.Sprintf(template,
-		func() bool {
-			return ss.running == 1
-		}(),
-		ss.channelArgs.String(),
-		ss.poolBaseArgs.String(),
-		ss.crawlDepth,
-		ss.chanmanSummary,
-		ss.reqCacheSummary,
-		ss.dlPoolLen, ss.dlPoolCap,
-		ss.analyzerPoolLen, ss.analyzerPoolCap,
-		ss.itemPipelineSummary,
-		ss.urlCount,
-		func() string {
-			if detail {
-				return ss.urlDetail
-			} else {
-				return "<concealed>\n"
-			}
-		}(),
-		ss.stopSignSummary)
-}
-
-func (ss *mySchedSummary) Same(other SchedSummary) bool {
-	if other == nil {
-		return false
-	}
-	otherSs, ok := interface{}(other).(*mySchedSummary)
-	if !ok {
-		return false
-	}
-	if ss.running != otherSs.running ||
-		ss.crawlDepth != otherSs.crawlDepth ||
-		ss.dlPoolLen != otherSs.dlPoolLen ||
-		ss.dlPoolCap != otherSs.dlPoolCap ||
-		ss.analyzerPoolLen != otherSs.analyzerPoolLen ||
-		ss.analyzerPoolCap != otherSs.analyzerPoolCap ||
-		ss.urlCount != otherSs.urlCount ||
-		ss.stopSignSummary != otherSs.stopSignSummary ||
-		ss.reqCacheSummary != otherSs.reqCacheSummary ||
-		ss.poolBaseArgs.String() != otherSs.poolBaseArgs.String() ||
-		ss.channelArgs.String() != otherSs.channelArgs.String() ||
-		ss.itemPipelineSummary != otherSs.itemPipelineSummary ||
-		ss.chanmanSummary != otherSs.chanmanSummary {
-		return false
-	} else {
-		return true
-	}
-}
+package scheduler
+
+import (
+	"bytes"
+	"fmt"
+	"sync/atomic"
+	base "webcrawler/base"
+	mdw "webcrawler/middleware"
+)
+
+// 调度器摘要信息的接口类型。
+type SchedSummary interface {
+	String() string               // 获得摘要信息的一般表示。
+	Detail() string               // 获取摘要信息的详细表示。
+	Same(other SchedSummary) bool // 判断是否与另一份摘要信息相同。
+}
+
+// 创建调度器摘要信息。
+func NewSchedSummary(sched *myScheduler, prefix string) SchedSummary {
+	if sched == nil {
+		return nil
+	}
+	return &mySchedSummary{
+		prefix:              prefix,
+		running:             atomic.LoadUint32(&sched.running),
+		channelArgs:         sched.channelArgs,
+		poolBaseArgs:        sched.poolBaseArgs,
+		crawlDepth:          sched.crawlDepth,
+		chanmanSummary:      sched.chanman.Summary(),
+		reqCacheSummary:     sched.reqCache.summary(),
+		dlPoolLen:           sched.dlpool.Used(),
+		dlPoolCap:           sched.dlpool.Total(),
+		dlPoolStats:         sched.dlpool.Stats().String(),
+		analyzerPoolLen:     sched.analyzerPool.Used(),
+		analyzerPoolCap:     sched.analyzerPool.Total(),
+		analyzerPoolStats:   sched.analyzerPool.Stats().String(),
+		itemPipelineSummary: sched.itemPipeline.Summary(),
+		pageDedupSummary:    getPageDedupSummary(sched),
+		errorSummary:        sched.errorCounter.summary(),
+		autoScaleSummary:    getAutoScaleSummary(sched),
+		budgetSummary:       getBudgetSummary(sched),
+		linkGraphSummary:    getLinkGraphSummary(sched),
+		visitedSummary:      sched.visited.Summary(),
+		recrawlSummary:      getRecrawlSummary(sched),
+		httpCacheSummary:    getHttpCacheSummary(sched),
+		warcSummary:         getWarcSummary(sched),
+		replaySummary:       getReplaySummary(sched),
+		sessionSummary:      getSessionSummary(sched),
+		decoratorSummary:    getDecoratorSummary(sched),
+		urlCount:            sched.visited.Len(),
+		visited:             sched.visited,
+		stopSignSummary:     sched.stopSign.Summary(),
+	}
+}
+
+// 获取网页去重器的摘要信息。
+func getPageDedupSummary(sched *myScheduler) string {
+	if sched.pageDedup == nil {
+		return "<disabled>"
+	}
+	return sched.pageDedup.Summary()
+}
+
+// 获取网页下载器池的自动伸缩器的摘要信息。
+func getAutoScaleSummary(sched *myScheduler) string {
+	if sched.autoScaler == nil {
+		return "<disabled>"
+	}
+	return sched.autoScaler.summary()
+}
+
+// 获取爬取预算的摘要信息。
+func getBudgetSummary(sched *myScheduler) string {
+	if sched.budget == nil {
+		return "<disabled>"
+	}
+	return sched.budget.summary()
+}
+
+// 获取链接图的摘要信息。
+func getLinkGraphSummary(sched *myScheduler) string {
+	if sched.linkGraph == nil {
+		return "<disabled>"
+	}
+	return sched.linkGraph.Summary()
+}
+
+// 获取增量爬取存储的摘要信息。
+func getRecrawlSummary(sched *myScheduler) string {
+	if sched.recrawlStore == nil {
+		return "<disabled>"
+	}
+	return sched.recrawlStore.Summary()
+}
+
+// 获取HTTP缓存的摘要信息。
+func getHttpCacheSummary(sched *myScheduler) string {
+	if sched.httpCache == nil {
+		return "<disabled>"
+	}
+	return sched.httpCache.Summary()
+}
+
+// 获取WARC写入器的摘要信息。
+func getWarcSummary(sched *myScheduler) string {
+	if sched.warcWriter == nil {
+		return "<disabled>"
+	}
+	return sched.warcWriter.Summary()
+}
+
+// 获取重放模式的摘要信息。
+func getReplaySummary(sched *myScheduler) string {
+	if sched.respSource == nil {
+		return "<disabled>"
+	}
+	return fmt.Sprintf("replayed: %d, done: %v, source: %s",
+		atomic.LoadUint64(&sched.replayCount),
+		atomic.LoadUint32(&sched.replayDone) == 1,
+		sched.respSource.Summary())
+}
+
+// 获取会话的摘要信息。
+func getSessionSummary(sched *myScheduler) string {
+	if sched.session == nil {
+		return "<disabled>"
+	}
+	return sched.session.Summary()
+}
+
+// 获取请求装饰器的摘要信息。
+func getDecoratorSummary(sched *myScheduler) string {
+	if sched.decorator == nil {
+		return "<disabled>"
+	}
+	return sched.decorator.Summary()
+}
+
+// 调度器摘要信息的实现类型。
+type mySchedSummary struct {
+	prefix              string            // 前缀。
+	running             uint32            // 运行标记。
+	channelArgs         base.ChannelArgs  // 通道参数的容器。
+	poolBaseArgs        base.PoolBaseArgs // 池基本参数的容器。
+	crawlDepth          uint32            // 爬取的最大深度。
+	chanmanSummary      string            // 通道管理器的摘要信息。
+	reqCacheSummary     string            // 请求缓存的摘要信息。
+	dlPoolLen           uint32            // 网页下载器池的长度。
+	dlPoolCap           uint32            // 网页下载器池的容量。
+	dlPoolStats         string            // 网页下载器池的统计信息。
+	analyzerPoolLen     uint32            // 分析器池的长度。
+	analyzerPoolCap     uint32            // 分析器池的容量。
+	analyzerPoolStats   string            // 分析器池的统计信息。
+	itemPipelineSummary string            // 条目处理管道的摘要信息。
+	pageDedupSummary    string            // 网页去重器的摘要信息。
+	errorSummary        string            // 错误计数的摘要信息。
+	autoScaleSummary    string            // 自动伸缩器的摘要信息。
+	budgetSummary       string            // 爬取预算的摘要信息。
+	linkGraphSummary    string            // 链接图的摘要信息。
+	visitedSummary      string            // 已访问集合的摘要信息。
+	recrawlSummary      string            // 增量爬取存储的摘要信息。
+	httpCacheSummary    string            // HTTP缓存的摘要信息。
+	warcSummary         string            // WARC写入器的摘要信息。
+	replaySummary       string            // 重放模式的摘要信息。
+	sessionSummary      string            // 会话的摘要信息。
+	decoratorSummary    string            // 请求装饰器的摘要信息。
+	urlCount            uint64            // 已请求的URL的计数。
+	visited             mdw.VisitedSet    // 已访问集合。仅在获取详细表示时才会用它枚举已请求的URL。
+	stopSignSummary     string            // 停止信号的摘要信息。
+}
+
+func (ss *mySchedSummary) String() string {
+	return ss.getSummary(false)
+}
+
+func (ss *mySchedSummary) Detail() string {
+	return ss.getSummary(true)
+}
+
+// 获取摘要信息。
+func (ss *mySchedSummary) getSummary(detail bool) string {
+	prefix := ss.prefix
+	template := prefix + "Running: %v \n" +
+		prefix + "Channel args: %s \n" +
+		prefix + "Pool base args: %s \n" +
+		prefix + "Crawl depth: %d \n" +
+		prefix + "Channels manager: %s \n" +
+		prefix + "Request cache: %s\n" +
+		prefix + "Downloader pool: %d/%d (%s)\n" +
+		prefix + "Analyzer pool: %d/%d (%s)\n" +
+		prefix + "Downloader autoscaling: %s\n" +
+		prefix + "Item pipeline: %s\n" +
+		prefix + "Page deduplicator: %s\n" +
+		prefix + "Errors: %s\n" +
+		prefix + "Crawl budget: %s\n" +
+		prefix + "Visited set: %s\n" +
+		prefix + "Recrawl store: %s\n" +
+		prefix + "HTTP cache: %s\n" +
+		prefix + "WARC writer: %s\n" +
+		prefix + "Replay: %s\n" +
+		prefix + "Session: %s\n" +
+		prefix + "Request decorator: %s\n" +
+		prefix + "Link graph: %s\n" +
+		prefix + "Urls(%d): %s" +
+		prefix + "Stop sign: %s\n"
+	return fmt.Sprintf(template,
+		func() bool {
+			return ss.running == 1
+		}(),
+		ss.channelArgs.String(),
+		ss.poolBaseArgs.String(),
+		ss.crawlDepth,
+		ss.chanmanSummary,
+		ss.reqCacheSummary,
+		ss.dlPoolLen, ss.dlPoolCap, ss.dlPoolStats,
+		ss.analyzerPoolLen, ss.analyzerPoolCap, ss.analyzerPoolStats,
+		ss.autoScaleSummary,
+		ss.itemPipelineSummary,
+		ss.pageDedupSummary,
+		ss.errorSummary,
+		ss.budgetSummary,
+		ss.visitedSummary,
+		ss.recrawlSummary,
+		ss.httpCacheSummary,
+		ss.warcSummary,
+		ss.replaySummary,
+		ss.sessionSummary,
+		ss.decoratorSummary,
+		ss.linkGraphSummary,
+		ss.urlCount,
+		func() string {
+			if detail {
+				return genUrlDetail(ss.visited, prefix)
+			} else {
+				return "<concealed>\n"
+			}
+		}(),
+		ss.stopSignSummary)
+}
+
+// 生成已请求的URL的详细信息。
+// 枚举已访问集合中的键可能代价很高（如基于磁盘的实现），所以只应在需要时调用。
+func genUrlDetail(visited mdw.VisitedSet, prefix string) string {
+	keys := visited.Keys()
+	if len(keys) == 0 {
+		return "\n"
+	}
+	var buffer bytes.Buffer
+	buffer.WriteByte('\n')
+	for _, k := range keys {
+		buffer.WriteString(prefix)
+		buffer.WriteString(prefix)
+		buffer.WriteString(k)
+		buffer.WriteByte('\n')
+	}
+	return buffer.String()
+}
+
+func (ss *mySchedSummary) Same(other SchedSummary) bool {
+	if other == nil {
+		return false
+	}
+	otherSs, ok := interface{}(other).(*mySchedSummary)
+	if !ok {
+		return false
+	}
+	if ss.running != otherSs.running ||
+		ss.crawlDepth != otherSs.crawlDepth ||
+		ss.dlPoolLen != otherSs.dlPoolLen ||
+		ss.dlPoolCap != otherSs.dlPoolCap ||
+		ss.analyzerPoolLen != otherSs.analyzerPoolLen ||
+		ss.analyzerPoolCap != otherSs.analyzerPoolCap ||
+		ss.dlPoolStats != otherSs.dlPoolStats ||
+		ss.analyzerPoolStats != otherSs.analyzerPoolStats ||
+		ss.urlCount != otherSs.urlCount ||
+		ss.visitedSummary != otherSs.visitedSummary ||
+		ss.recrawlSummary != otherSs.recrawlSummary ||
+		ss.httpCacheSummary != otherSs.httpCacheSummary ||
+		ss.warcSummary != otherSs.warcSummary ||
+		ss.replaySummary != otherSs.replaySummary ||
+		ss.sessionSummary != otherSs.sessionSummary ||
+		ss.decoratorSummary != otherSs.decoratorSummary ||
+		ss.stopSignSummary != otherSs.stopSignSummary ||
+		ss.reqCacheSummary != otherSs.reqCacheSummary ||
+		ss.poolBaseArgs.String() != otherSs.poolBaseArgs.String() ||
+		ss.channelArgs.String() != otherSs.channelArgs.String() ||
+		ss.itemPipelineSummary != otherSs.itemPipelineSummary ||
+		ss.pageDedupSummary != otherSs.pageDedupSummary ||
+		ss.errorSummary != otherSs.errorSummary ||
+		ss.autoScaleSummary != otherSs.autoScaleSummary ||
+		ss.budgetSummary != otherSs.budgetSummary ||
+		ss.linkGraphSummary != otherSs.linkGraphSummary ||
+		ss.chanmanSummary != otherSs.chanmanSummary {
+		return false
+	} else {
+		return true
+	}
+}
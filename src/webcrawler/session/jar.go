@@ -0,0 +1,320 @@
+package session
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 可持久化的Cookie罐的接口类型。它的所有方法都是并发安全的。
+type Jar interface {
+	http.CookieJar
+	// 获得Cookie的数量。
+	Len() int
+	// 把所有未过期的Cookie保存到文件。
+	Save(path string) error
+	// 从文件加载Cookie。已过期的Cookie会被忽略。
+	Load(path string) error
+}
+
+// 被存储的Cookie。
+type entry struct {
+	Name     string    `json:"name"`              // 名称。
+	Value    string    `json:"value"`             // 值。
+	Domain   string    `json:"domain"`            // 域名。不包含开头的点号。
+	Path     string    `json:"path"`              // 路径。
+	HostOnly bool      `json:"hostOnly"`          // 是否只发送给与域名完全一致的主机。
+	Secure   bool      `json:"secure"`            // 是否只通过HTTPS发送。
+	HttpOnly bool      `json:"httpOnly"`          // 是否仅限HTTP使用。
+	Expires  time.Time `json:"expires,omitempty"` // 过期时间。为零值时代表会话Cookie。
+}
+
+// 判断Cookie是否已过期。
+func (e *entry) expired(now time.Time) bool {
+	return !e.Expires.IsZero() && !e.Expires.After(now)
+}
+
+// 判断Cookie是否应被发送给给定的主机。
+func (e *entry) domainMatch(host string) bool {
+	if e.HostOnly {
+		return host == e.Domain
+	}
+	return host == e.Domain || strings.HasSuffix(host, "."+e.Domain)
+}
+
+// 判断Cookie是否应被发送给给定的路径。
+func (e *entry) pathMatch(path string) bool {
+	if path == e.Path {
+		return true
+	}
+	if strings.HasPrefix(path, e.Path) {
+		return strings.HasSuffix(e.Path, "/") || path[len(e.Path)] == '/'
+	}
+	return false
+}
+
+// 创建Cookie罐。
+// 与net/http/cookiejar不同，它不依赖公共后缀列表，而只会拒绝不含点号的域名属性（如“com”）。
+// 因此，它只应被用于爬取可信的网站。
+func NewJar() Jar {
+	return &myJar{entries: make(map[string]*entry)}
+}
+
+// Cookie罐的实现类型。
+type myJar struct {
+	entries map[string]*entry // Cookie的字典。键由域名、路径和名称组成。
+	mutex   sync.Mutex        // 互斥锁。
+}
+
+// 生成Cookie的键。
+func entryKey(domain, path, name string) string {
+	return domain + ";" + path + ";" + name
+}
+
+// 获得URL中的主机名。
+func canonicalHost(u *url.URL) string {
+	host := u.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.ToLower(strings.TrimSuffix(host, "."))
+}
+
+// 获得URL的默认的Cookie路径。
+func defaultPath(path string) string {
+	if path == "" || path[0] != '/' {
+		return "/"
+	}
+	i := strings.LastIndex(path, "/")
+	if i == 0 {
+		return "/"
+	}
+	return path[:i]
+}
+
+func (jar *myJar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	host := canonicalHost(u)
+	now := time.Now()
+	jar.mutex.Lock()
+	defer jar.mutex.Unlock()
+	for _, cookie := range cookies {
+		e := &entry{
+			Name:     cookie.Name,
+			Value:    cookie.Value,
+			Path:     cookie.Path,
+			Secure:   cookie.Secure,
+			HttpOnly: cookie.HttpOnly,
+		}
+		domain := strings.ToLower(strings.TrimPrefix(cookie.Domain, "."))
+		if domain == "" {
+			e.Domain, e.HostOnly = host, true
+		} else {
+			if !strings.Contains(domain, ".") || !(host == domain || strings.HasSuffix(host, "."+domain)) {
+				// 域名属性无效或与主机名不符。
+				continue
+			}
+			e.Domain = domain
+		}
+		if e.Path == "" || e.Path[0] != '/' {
+			e.Path = defaultPath(u.Path)
+		}
+		key := entryKey(e.Domain, e.Path, e.Name)
+		switch {
+		case cookie.MaxAge < 0:
+			delete(jar.entries, key)
+			continue
+		case cookie.MaxAge > 0:
+			e.Expires = now.Add(time.Duration(cookie.MaxAge) * time.Second)
+		case !cookie.Expires.IsZero():
+			e.Expires = cookie.Expires
+		}
+		if e.expired(now) {
+			delete(jar.entries, key)
+			continue
+		}
+		jar.entries[key] = e
+	}
+}
+
+func (jar *myJar) Cookies(u *url.URL) []*http.Cookie {
+	host := canonicalHost(u)
+	path := u.Path
+	if path == "" {
+		path = "/"
+	}
+	secure := u.Scheme == "https"
+	now := time.Now()
+	jar.mutex.Lock()
+	var selected []*entry
+	for key, e := range jar.entries {
+		if e.expired(now) {
+			delete(jar.entries, key)
+			continue
+		}
+		if (e.Secure && !secure) || !e.domainMatch(host) || !e.pathMatch(path) {
+			continue
+		}
+		selected = append(selected, e)
+	}
+	jar.mutex.Unlock()
+	// 路径更长的Cookie排在前面。
+	sort.Slice(selected, func(i, j int) bool {
+		if len(selected[i].Path) != len(selected[j].Path) {
+			return len(selected[i].Path) > len(selected[j].Path)
+		}
+		return selected[i].Name < selected[j].Name
+	})
+	cookies := make([]*http.Cookie, 0, len(selected))
+	for _, e := range selected {
+		cookies = append(cookies, &http.Cookie{Name: e.Name, Value: e.Value})
+	}
+	return cookies
+}
+
+func (jar *myJar) Len() int {
+	jar.mutex.Lock()
+	defer jar.mutex.Unlock()
+	return len(jar.entries)
+}
+
+// 获得所有未过期的Cookie的副本。
+func (jar *myJar) snapshot() []entry {
+	now := time.Now()
+	jar.mutex.Lock()
+	entries := make([]entry, 0, len(jar.entries))
+	for _, e := range jar.entries {
+		if !e.expired(now) {
+			entries = append(entries, *e)
+		}
+	}
+	jar.mutex.Unlock()
+	sort.Slice(entries, func(i, j int) bool {
+		return entryKey(entries[i].Domain, entries[i].Path, entries[i].Name) <
+			entryKey(entries[j].Domain, entries[j].Path, entries[j].Name)
+	})
+	return entries
+}
+
+// 放入Cookie。已过期的Cookie会被忽略。
+func (jar *myJar) restore(entries []entry) {
+	now := time.Now()
+	jar.mutex.Lock()
+	defer jar.mutex.Unlock()
+	for i := range entries {
+		e := entries[i]
+		if e.expired(now) {
+			continue
+		}
+		jar.entries[entryKey(e.Domain, e.Path, e.Name)] = &e
+	}
+}
+
+func (jar *myJar) Save(path string) error {
+	return writeJson(path, jar.snapshot())
+}
+
+func (jar *myJar) Load(path string) error {
+	var entries []entry
+	if err := readJson(path, &entries); err != nil {
+		return err
+	}
+	jar.restore(entries)
+	return nil
+}
+
+// 创建按域名隔离的Cookie罐。每个域名（即请求的主机名）都有各自的Cookie罐，
+// 所以Cookie永远不会被发送给设置它的主机以外的主机。
+func NewPerDomainJar() Jar {
+	return &perDomainJar{jars: make(map[string]*myJar)}
+}
+
+// 按域名隔离的Cookie罐的实现类型。
+type perDomainJar struct {
+	jars  map[string]*myJar // Cookie罐的字典。键为主机名。
+	mutex sync.Mutex        // 互斥锁。
+}
+
+// 获得与主机名对应的Cookie罐。若参数create为false且该Cookie罐不存在，则结果值为nil。
+func (pdj *perDomainJar) jar(host string, create bool) *myJar {
+	pdj.mutex.Lock()
+	defer pdj.mutex.Unlock()
+	jar, ok := pdj.jars[host]
+	if !ok && create {
+		jar = NewJar().(*myJar)
+		pdj.jars[host] = jar
+	}
+	return jar
+}
+
+func (pdj *perDomainJar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	pdj.jar(canonicalHost(u), true).SetCookies(u, cookies)
+}
+
+func (pdj *perDomainJar) Cookies(u *url.URL) []*http.Cookie {
+	jar := pdj.jar(canonicalHost(u), false)
+	if jar == nil {
+		return nil
+	}
+	return jar.Cookies(u)
+}
+
+func (pdj *perDomainJar) Len() int {
+	pdj.mutex.Lock()
+	defer pdj.mutex.Unlock()
+	var total int
+	for _, jar := range pdj.jars {
+		total += jar.Len()
+	}
+	return total
+}
+
+func (pdj *perDomainJar) Save(path string) error {
+	pdj.mutex.Lock()
+	hostEntries := make(map[string][]entry, len(pdj.jars))
+	for host, jar := range pdj.jars {
+		hostEntries[host] = jar.snapshot()
+	}
+	pdj.mutex.Unlock()
+	return writeJson(path, hostEntries)
+}
+
+func (pdj *perDomainJar) Load(path string) error {
+	var hostEntries map[string][]entry
+	if err := readJson(path, &hostEntries); err != nil {
+		return err
+	}
+	for host, entries := range hostEntries {
+		pdj.jar(host, true).restore(entries)
+	}
+	return nil
+}
+
+// 把值以JSON格式写入文件。
+// Cookie可能包含凭证，所以文件只允许所有者读写。
+func writeJson(path string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	tempPath := path + ".tmp"
+	if err := ioutil.WriteFile(tempPath, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tempPath, path)
+}
+
+// 从文件读取JSON格式的值。
+func readJson(path string, v interface{}) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
@@ -0,0 +1,122 @@
+package session
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"logging"
+	"net/http"
+	"net/url"
+	"os"
+	"sync/atomic"
+	base "webcrawler/base"
+)
+
+// 日志记录器。
+var logger logging.Logger = base.NewLogger()
+
+// 会话的接口类型。它持有一个被所有网页下载器共享的Cookie罐。
+type Session interface {
+	// 获得Cookie罐。
+	Jar() Jar
+	// 登录。若会话参数中未设置登录URL，则什么也不做。
+	// 参数client的Cookie罐会被忽略，登录过程总会使用会话的Cookie罐。
+	Login(client *http.Client) error
+	// 把Cookie保存到持久化文件。若会话参数中未设置持久化文件，则什么也不做。
+	Save() error
+	// 获取摘要信息。
+	Summary() string
+}
+
+// 创建会话。若持久化文件已存在，则会先从中加载Cookie。
+func NewSession(args base.SessionArgs) (Session, error) {
+	if err := args.Check(); err != nil {
+		return nil, err
+	}
+	var jar Jar
+	if args.JarMode() == base.COOKIE_JAR_MODE_PER_DOMAIN {
+		jar = NewPerDomainJar()
+	} else {
+		jar = NewJar()
+	}
+	if path := args.CookieFile(); path != "" {
+		err := jar.Load(path)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		if err == nil {
+			logger.Infof("Loaded %d cookies from file '%s'.\n", jar.Len(), path)
+		}
+	}
+	return &mySession{args: args, jar: jar}, nil
+}
+
+// 会话的实现类型。
+type mySession struct {
+	args     base.SessionArgs // 会话参数。
+	jar      Jar              // Cookie罐。
+	loggedIn uint32           // 登录标记。0表示未登录，1表示已登录。
+}
+
+func (session *mySession) Jar() Jar {
+	return session.jar
+}
+
+func (session *mySession) Login(client *http.Client) error {
+	loginUrl := session.args.LoginUrl()
+	if loginUrl == "" {
+		return nil
+	}
+	loginClient := http.Client{}
+	if client != nil {
+		loginClient = *client
+	}
+	loginClient.Jar = session.jar
+	form := url.Values{}
+	for name, value := range session.args.LoginFields() {
+		form.Set(name, value)
+	}
+	logger.Infof("Log in (url=%s)... \n", loginUrl)
+	httpResp, err := loginClient.PostForm(loginUrl, form)
+	if err != nil {
+		return err
+	}
+	io.Copy(ioutil.Discard, httpResp.Body)
+	httpResp.Body.Close()
+	if httpResp.StatusCode >= 400 {
+		errMsg := fmt.Sprintf("Login failed! (url=%s, statusCode=%d)", loginUrl, httpResp.StatusCode)
+		return errors.New(errMsg)
+	}
+	if name := session.args.SuccessCookie(); name != "" {
+		u, _ := url.Parse(loginUrl)
+		var found bool
+		for _, cookie := range session.jar.Cookies(u) {
+			if cookie.Name == name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			errMsg := fmt.Sprintf("Login failed! The cookie '%s' is absent. (url=%s)", name, loginUrl)
+			return errors.New(errMsg)
+		}
+	}
+	atomic.StoreUint32(&session.loggedIn, 1)
+	return nil
+}
+
+func (session *mySession) Save() error {
+	path := session.args.CookieFile()
+	if path == "" {
+		return nil
+	}
+	return session.jar.Save(path)
+}
+
+var sessionSummaryTemplate = "args: %s, cookies: %d, loggedIn: %v"
+
+func (session *mySession) Summary() string {
+	return fmt.Sprintf(sessionSummaryTemplate,
+		session.args.String(), session.jar.Len(), atomic.LoadUint32(&session.loggedIn) == 1)
+}
@@ -0,0 +1,111 @@
+package session
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"testing"
+	base "webcrawler/base"
+)
+
+func TestJarScope(t *testing.T) {
+	jar := NewJar()
+	setUrl, _ := url.Parse("http://www.example.com/a/b.html")
+	jar.SetCookies(setUrl, []*http.Cookie{
+		{Name: "host", Value: "1"},
+		{Name: "domain", Value: "2", Domain: ".example.com", Path: "/"},
+		{Name: "tld", Value: "3", Domain: "com"},
+		{Name: "other", Value: "4", Domain: "other.com"},
+	})
+	cases := map[string]string{
+		"http://www.example.com/a/c.html": "[host=1 domain=2]",
+		"http://www.example.com/x.html":   "[domain=2]",
+		"http://img.example.com/a/c.html": "[domain=2]",
+		"http://www.other.com/":           "[]",
+	}
+	for rawUrl, expected := range cases {
+		u, _ := url.Parse(rawUrl)
+		var actual []string
+		for _, cookie := range jar.Cookies(u) {
+			actual = append(actual, cookie.String())
+		}
+		if got := "[" + joinStrings(actual) + "]"; got != expected {
+			t.Errorf("Unexpected cookies %s for %s, expected %s.", got, rawUrl, expected)
+		}
+	}
+
+	perDomain := NewPerDomainJar()
+	perDomain.SetCookies(setUrl, []*http.Cookie{{Name: "domain", Value: "2", Domain: "example.com"}})
+	imgUrl, _ := url.Parse("http://img.example.com/")
+	if cookies := perDomain.Cookies(imgUrl); len(cookies) != 0 {
+		t.Errorf("The cookies should not be shared between hosts in per-domain mode: %v", cookies)
+	}
+}
+
+func joinStrings(strs []string) string {
+	var result string
+	for i, str := range strs {
+		if i > 0 {
+			result += " "
+		}
+		result += str
+	}
+	return result
+}
+
+func TestSessionLogin(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/login":
+			if r.PostFormValue("user") == "alice" && r.PostFormValue("password") == "secret" {
+				http.SetCookie(w, &http.Cookie{Name: "sid", Value: "42", Path: "/", MaxAge: 3600})
+			}
+		case "/private":
+			if cookie, err := r.Cookie("sid"); err != nil || cookie.Value != "42" {
+				w.WriteHeader(http.StatusForbidden)
+			}
+		}
+	}))
+	defer server.Close()
+	cookieFile := filepath.Join(t.TempDir(), "cookies.json")
+
+	badArgs := base.NewSessionArgs(base.COOKIE_JAR_MODE_SHARED, "", server.URL+"/login",
+		map[string]string{"user": "alice", "password": "wrong"}, "sid")
+	badSession, err := NewSession(badArgs)
+	if err != nil {
+		t.Fatalf("Create session error: %s", err)
+	}
+	if err := badSession.Login(nil); err == nil {
+		t.Fatalf("Expected a login error for wrong credentials, but got nil.")
+	}
+
+	args := base.NewSessionArgs(base.COOKIE_JAR_MODE_PER_DOMAIN, cookieFile, server.URL+"/login",
+		map[string]string{"user": "alice", "password": "secret"}, "sid")
+	sess, err := NewSession(args)
+	if err != nil {
+		t.Fatalf("Create session error: %s", err)
+	}
+	if err := sess.Login(nil); err != nil {
+		t.Fatalf("Login error: %s", err)
+	}
+	if err := sess.Save(); err != nil {
+		t.Fatalf("Save session error: %s", err)
+	}
+
+	// 新的会话应能从持久化文件中恢复登录状态。
+	restoredArgs := base.NewSessionArgs(base.COOKIE_JAR_MODE_PER_DOMAIN, cookieFile, "", nil, "")
+	restored, err := NewSession(restoredArgs)
+	if err != nil {
+		t.Fatalf("Restore session error: %s", err)
+	}
+	client := &http.Client{Jar: restored.Jar()}
+	resp, err := client.Get(server.URL + "/private")
+	if err != nil {
+		t.Fatalf("Request error: %s", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Unexpected status code %d with restored cookies.", resp.StatusCode)
+	}
+}
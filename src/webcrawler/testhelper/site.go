@@ -0,0 +1,261 @@
+// 该包提供了用于端到端测试的本地站点。
+// 站点中的网页会按照给定的参数生成，并构成一棵树（也可以带有环）。
+package testhelper
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 站点参数。
+type SiteArgs struct {
+	Depth         uint32        // 网页的最大深度。首页的深度为0。
+	FanOut        uint32        // 每个非叶子网页的子网页的数量。
+	Cycles        bool          // 是否让每个网页都链接到首页和父网页，从而形成环。
+	RedirectEvery uint32        // 每隔多少个网页就有一个网页被重定向。为0时不重定向。
+	ErrorEvery    uint32        // 每隔多少个网页就有一个网页返回错误状态码。为0时不返回错误。
+	ErrorCode     int           // 错误状态码。为0时使用500。
+	SlowEvery     uint32        // 每隔多少个网页就有一个网页被延迟响应。为0时不延迟。
+	SlowDelay     time.Duration // 延迟响应的时长。
+	Disallow      []string      // robots.txt中的Disallow规则的列表。
+	TLS           bool          // 是否使用HTTPS。此时应使用Client方法获得的HTTP客户端。
+}
+
+func (args SiteArgs) Check() error {
+	if args.FanOut == 0 && args.Depth > 0 {
+		return errors.New("The fan-out can not be 0 when the depth is greater than 0!\n")
+	}
+	if args.ErrorCode != 0 && (args.ErrorCode < 400 || args.ErrorCode > 599) {
+		return errors.New(fmt.Sprintf("Illegal error code %d!\n", args.ErrorCode))
+	}
+	return nil
+}
+
+// 网页的种类。
+type PageKind uint8
+
+// 网页的种类的常量。
+const (
+	PAGE_NORMAL   PageKind = iota // 普通网页。
+	PAGE_REDIRECT                 // 被重定向的网页。其内容位于重定向的目标地址。
+	PAGE_ERROR                    // 返回错误状态码的网页。它不包含任何链接。
+	PAGE_SLOW                     // 被延迟响应的网页。
+)
+
+// 网页。
+type Page struct {
+	Index    uint32   // 序号。它是网页在广度优先遍历中的次序，首页的序号为0。
+	Path     string   // 路径。
+	Depth    uint32   // 深度。
+	Kind     PageKind // 种类。
+	Title    string   // 标题。
+	Children []string // 子网页的路径的列表。
+	Parent   string   // 父网页的路径。首页没有父网页。
+}
+
+// 获得网页的内容的路径。对于被重定向的网页，它是重定向的目标地址的路径。
+func (page *Page) ContentPath() string {
+	if page.Kind == PAGE_REDIRECT {
+		return "/moved" + page.Path
+	}
+	return page.Path
+}
+
+// 本地站点。
+type SiteServer struct {
+	args   SiteArgs          // 站点参数。
+	pages  []*Page           // 按序号排列的网页的列表。
+	server *httptest.Server  // HTTP服务器。
+	visits map[string]uint32 // 路径与其被访问的次数的映射。
+	mutex  sync.Mutex        // 针对访问计数的互斥锁。
+}
+
+// 生成站点并开始在本地监听。使用完毕后应调用Close方法。
+func NewSiteServer(args SiteArgs) (*SiteServer, error) {
+	if err := args.Check(); err != nil {
+		return nil, err
+	}
+	site := &SiteServer{args: args, visits: make(map[string]uint32)}
+	site.genPages()
+	if args.TLS {
+		site.server = httptest.NewTLSServer(http.HandlerFunc(site.serve))
+	} else {
+		site.server = httptest.NewServer(http.HandlerFunc(site.serve))
+	}
+	return site, nil
+}
+
+// 按照广度优先的顺序生成网页。
+func (site *SiteServer) genPages() {
+	root := &Page{Path: "/", Title: "Page /"}
+	site.pages = []*Page{root}
+	for i := 0; i < len(site.pages); i++ {
+		page := site.pages[i]
+		// 错误网页不包含任何链接，所以它不会有子网页。
+		if page.Depth >= site.args.Depth || page.Kind == PAGE_ERROR {
+			continue
+		}
+		for j := uint32(0); j < site.args.FanOut; j++ {
+			child := &Page{
+				Index:  uint32(len(site.pages)),
+				Path:   strings.TrimSuffix(page.Path, "/") + "/" + strconv.Itoa(int(j)),
+				Depth:  page.Depth + 1,
+				Parent: page.Path,
+			}
+			child.Title = "Page " + child.Path
+			child.Kind = site.pageKind(child.Index)
+			page.Children = append(page.Children, child.Path)
+			site.pages = append(site.pages, child)
+		}
+	}
+}
+
+// 根据序号决定网页的种类。首页总是普通网页。
+func (site *SiteServer) pageKind(index uint32) PageKind {
+	switch {
+	case index == 0:
+		return PAGE_NORMAL
+	case site.args.ErrorEvery > 0 && index%site.args.ErrorEvery == 0:
+		return PAGE_ERROR
+	case site.args.RedirectEvery > 0 && index%site.args.RedirectEvery == 0:
+		return PAGE_REDIRECT
+	case site.args.SlowEvery > 0 && index%site.args.SlowEvery == 0:
+		return PAGE_SLOW
+	}
+	return PAGE_NORMAL
+}
+
+// 获得站点的根URL，如“http://127.0.0.1:12345”。
+func (site *SiteServer) URL() string {
+	return site.server.URL
+}
+
+// 获得信任该站点证书的HTTP客户端。每次调用都会得到一个新的客户端。参数timeout为0时不超时。
+func (site *SiteServer) Client(timeout time.Duration) *http.Client {
+	client := *site.server.Client()
+	client.Timeout = timeout
+	return &client
+}
+
+// 获得所有的网页。
+func (site *SiteServer) Pages() []*Page {
+	return site.pages
+}
+
+// 根据路径获得网页。被重定向的网页也可以通过其内容的路径获得。
+func (site *SiteServer) Page(path string) *Page {
+	for _, page := range site.pages {
+		if page.Path == path || page.ContentPath() == path {
+			return page
+		}
+	}
+	return nil
+}
+
+// 获得深度不大于maxDepth的网页的列表。错误网页会被包含在内。
+func (site *SiteServer) PagesWithin(maxDepth uint32) []*Page {
+	var pages []*Page
+	for _, page := range site.pages {
+		if page.Depth <= maxDepth {
+			pages = append(pages, page)
+		}
+	}
+	return pages
+}
+
+// 获得所有被访问过的路径及其访问次数。
+func (site *SiteServer) Visits() map[string]uint32 {
+	site.mutex.Lock()
+	defer site.mutex.Unlock()
+	visits := make(map[string]uint32, len(site.visits))
+	for path, count := range site.visits {
+		visits[path] = count
+	}
+	return visits
+}
+
+// 获得所有被访问过的路径的有序列表。
+func (site *SiteServer) VisitedPaths() []string {
+	visits := site.Visits()
+	paths := make([]string, 0, len(visits))
+	for path := range visits {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// 获得robots.txt的内容。
+func (site *SiteServer) RobotsTxt() string {
+	var buffer bytes.Buffer
+	buffer.WriteString("User-agent: *\n")
+	for _, rule := range site.args.Disallow {
+		buffer.WriteString("Disallow: " + rule + "\n")
+	}
+	return buffer.String()
+}
+
+// 关闭站点。
+func (site *SiteServer) Close() {
+	site.server.Close()
+}
+
+func (site *SiteServer) serve(w http.ResponseWriter, r *http.Request) {
+	path := r.URL.Path
+	site.mutex.Lock()
+	site.visits[path]++
+	site.mutex.Unlock()
+	if path == "/robots.txt" {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		fmt.Fprint(w, site.RobotsTxt())
+		return
+	}
+	page := site.Page(path)
+	if page == nil {
+		http.NotFound(w, r)
+		return
+	}
+	switch page.Kind {
+	case PAGE_REDIRECT:
+		if path == page.Path {
+			http.Redirect(w, r, page.ContentPath(), http.StatusFound)
+			return
+		}
+	case PAGE_ERROR:
+		code := site.args.ErrorCode
+		if code == 0 {
+			code = http.StatusInternalServerError
+		}
+		http.Error(w, http.StatusText(code), code)
+		return
+	case PAGE_SLOW:
+		time.Sleep(site.args.SlowDelay)
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	fmt.Fprint(w, site.render(page))
+}
+
+// 生成网页的HTML内容。
+func (site *SiteServer) render(page *Page) string {
+	var buffer bytes.Buffer
+	buffer.WriteString("<html><head><title>" + page.Title + "</title></head><body>\n")
+	for _, child := range page.Children {
+		buffer.WriteString(fmt.Sprintf("<a href=\"%s\">%s</a>\n", child, child))
+	}
+	if site.args.Cycles {
+		buffer.WriteString("<a href=\"/\">Home</a>\n")
+		if page.Parent != "" {
+			buffer.WriteString(fmt.Sprintf("<a href=\"%s\">Parent</a>\n", page.Parent))
+		}
+	}
+	buffer.WriteString("</body></html>\n")
+	return buffer.String()
+}
@@ -0,0 +1,61 @@
+package testhelper
+
+import (
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestSiteServer(t *testing.T) {
+	site, err := NewSiteServer(SiteArgs{
+		Depth:         2,
+		FanOut:        2,
+		RedirectEvery: 2,
+		ErrorEvery:    3,
+		ErrorCode:     404,
+		SlowEvery:     5,
+		SlowDelay:     time.Millisecond,
+		Disallow:      []string{"/private/"},
+	})
+	if err != nil {
+		t.Fatalf("Create site error: %s", err)
+	}
+	defer site.Close()
+	if n := len(site.Pages()); n != 7 {
+		t.Fatalf("The number of pages should be 7, but %d!", n)
+	}
+	// 序号为1、2和3的网页分别是普通网页、被重定向的网页和错误网页。
+	if site.Page("/1").Kind != PAGE_REDIRECT || site.Page("/0/0").Kind != PAGE_ERROR ||
+		site.Page("/1/0").Kind != PAGE_SLOW {
+		t.Fatalf("Unexpected page kinds!")
+	}
+	get := func(path string) (*http.Response, string) {
+		resp, err := http.Get(site.URL() + path)
+		if err != nil {
+			t.Fatalf("Get %s error: %s", path, err)
+		}
+		defer resp.Body.Close()
+		body, _ := ioutil.ReadAll(resp.Body)
+		return resp, string(body)
+	}
+	if resp, _ := get("/0"); resp.StatusCode != 200 || resp.Request.URL.Path != "/0" {
+		t.Errorf("Unexpected response of normal page: %d %s", resp.StatusCode, resp.Request.URL)
+	}
+	if resp, _ := get("/1"); resp.StatusCode != 200 || resp.Request.URL.Path != "/moved/1" {
+		t.Errorf("Unexpected response of redirected page: %d %s", resp.StatusCode, resp.Request.URL)
+	}
+	if resp, _ := get("/0/0"); resp.StatusCode != 404 {
+		t.Errorf("Unexpected status code %d of error page!", resp.StatusCode)
+	}
+	if resp, _ := get("/none"); resp.StatusCode != 404 {
+		t.Errorf("Unexpected status code %d of unknown page!", resp.StatusCode)
+	}
+	if _, body := get("/robots.txt"); body != "User-agent: *\nDisallow: /private/\n" {
+		t.Errorf("Unexpected robots.txt %q!", body)
+	}
+	visits := site.Visits()
+	if visits["/1"] != 1 || visits["/moved/1"] != 1 || visits["/robots.txt"] != 1 {
+		t.Errorf("Unexpected visits %v!", visits)
+	}
+}
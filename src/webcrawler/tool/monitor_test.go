@@ -0,0 +1,92 @@
+package tool
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	anlz "webcrawler/analyzer"
+	base "webcrawler/base"
+	ipl "webcrawler/itempipeline"
+	sched "webcrawler/scheduler"
+	"webcrawler/testhelper"
+)
+
+// 在监控运行的同时执行一次完整的并发爬取，并由监控在调度器空闲之后停止它。
+func TestMonitoringCrawl(t *testing.T) {
+	site, err := testhelper.NewSiteServer(testhelper.SiteArgs{
+		Depth:      2,
+		FanOut:     4,
+		Cycles:     true,
+		ErrorEvery: 6,
+	})
+	if err != nil {
+		t.Fatalf("Create site error: %s", err)
+	}
+	defer site.Close()
+
+	scheduler := sched.NewScheduler()
+	var mutex sync.Mutex
+	var infos, errs int
+	record := func(level byte, content string) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		switch level {
+		case 0:
+			infos++
+		case 2:
+			errs++
+		}
+	}
+	checkCountChan := Monitoring(scheduler, time.Millisecond, 1000, true, true, record)
+
+	var items []string
+	processor := func(item base.Item) (base.Item, error) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		items = append(items, item["url"].(string))
+		return item, nil
+	}
+	parser := func(httpResp *http.Response, respDepth uint32, respMeta base.Meta) ([]base.Data, []error) {
+		dataList, parseErrs := anlz.ParseLinks(httpResp, respDepth, respMeta)
+		item := base.Item{"url": httpResp.Request.URL.String()}
+		return append(dataList, &item), parseErrs
+	}
+	firstHttpReq, _ := http.NewRequest("GET", site.URL()+"/", nil)
+	err = scheduler.Start(
+		base.NewChannelArgs(5, 5, 5, 5),
+		base.NewPoolBaseArgs(3, 3),
+		2,
+		func() *http.Client { return &http.Client{Timeout: 5 * time.Second} },
+		[]anlz.ParseResponse{parser},
+		[]ipl.ProcessItem{processor},
+		firstHttpReq)
+	if err != nil {
+		t.Fatalf("Start scheduler error: %s", err)
+	}
+	select {
+	case checkCount := <-checkCountChan:
+		if checkCount == 0 {
+			t.Errorf("The check count should be greater than 0!")
+		}
+	case <-time.After(20 * time.Second):
+		scheduler.Stop()
+		t.Fatalf("The monitoring is not finished in time!")
+	}
+	if scheduler.Running() {
+		t.Errorf("The scheduler should be stopped by the monitoring!")
+	}
+	if summary := scheduler.Summary("").String(); !strings.Contains(summary, "Running: false") {
+		t.Errorf("Unexpected summary after stopping:\n%s", summary)
+	}
+	mutex.Lock()
+	defer mutex.Unlock()
+	// 每个网页（包括错误网页）都会产生一个条目。
+	if len(items) != len(site.Pages()) {
+		t.Errorf("The number of items should be %d, but %d!", len(site.Pages()), len(items))
+	}
+	if infos == 0 || errs == 0 {
+		t.Errorf("Unexpected record counts (infos=%d, errors=%d)!", infos, errs)
+	}
+}
@@ -0,0 +1,125 @@
+package warc
+
+import (
+	"bufio"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// WARC读取器的接口类型。
+type Reader interface {
+	// 读取下一条记录。若已没有更多的记录，则返回io.EOF。
+	Next() (*Record, error)
+	// 关闭读取器。
+	Close() error
+}
+
+// 创建WARC读取器。它同时支持未压缩的和经过gzip压缩的WARC数据。
+func NewReader(r io.Reader) (Reader, error) {
+	bufReader := bufio.NewReader(r)
+	magic, err := bufReader.Peek(2)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	reader := &myReader{}
+	if len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		// 多个gzip成员会被当作一个连续的数据流读取。
+		gzipReader, err := gzip.NewReader(bufReader)
+		if err != nil {
+			return nil, err
+		}
+		reader.closer = gzipReader
+		reader.bufReader = bufio.NewReader(gzipReader)
+	} else {
+		reader.bufReader = bufReader
+	}
+	if closer, ok := r.(io.Closer); ok {
+		reader.fileCloser = closer
+	}
+	return reader, nil
+}
+
+// 打开WARC文件并创建WARC读取器。
+func OpenFile(path string) (Reader, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	reader, err := NewReader(file)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return reader, nil
+}
+
+// WARC读取器的实现类型。
+type myReader struct {
+	bufReader  *bufio.Reader // 带缓冲的读取器。
+	closer     io.Closer     // 解压读取器。
+	fileCloser io.Closer     // 底层的数据源。
+}
+
+func (reader *myReader) Next() (*Record, error) {
+	var version string
+	for {
+		line, err := reader.bufReader.ReadString('\n')
+		if err != nil {
+			if err == io.EOF && strings.TrimSpace(line) == "" {
+				return nil, io.EOF
+			}
+			return nil, err
+		}
+		// 跳过记录之间的空行。
+		if version = strings.TrimRight(line, "\r\n"); version != "" {
+			break
+		}
+	}
+	if !strings.HasPrefix(version, "WARC/") {
+		return nil, errors.New(fmt.Sprintf("Invalid WARC version line %q!", version))
+	}
+	var header Header
+	for {
+		line, err := reader.bufReader.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			break
+		}
+		i := strings.Index(line, ":")
+		if i < 0 {
+			return nil, errors.New(fmt.Sprintf("Invalid WARC header line %q!", line))
+		}
+		header = append(header, Field{
+			Name:  strings.TrimSpace(line[:i]),
+			Value: strings.TrimSpace(line[i+1:]),
+		})
+	}
+	length, err := strconv.ParseInt(header.Get(HEADER_CONTENT_LENGTH), 10, 64)
+	if err != nil || length < 0 {
+		return nil, errors.New(fmt.Sprintf("Invalid WARC content length %q!",
+			header.Get(HEADER_CONTENT_LENGTH)))
+	}
+	content := make([]byte, length)
+	if _, err := io.ReadFull(reader.bufReader, content); err != nil {
+		return nil, err
+	}
+	return &Record{Header: header, Content: content}, nil
+}
+
+func (reader *myReader) Close() error {
+	if reader.closer != nil {
+		reader.closer.Close()
+	}
+	if reader.fileCloser != nil {
+		return reader.fileCloser.Close()
+	}
+	return nil
+}
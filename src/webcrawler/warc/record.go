@@ -0,0 +1,149 @@
+package warc
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base32"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	base "webcrawler/base"
+)
+
+// WARC格式的版本。
+const WARC_VERSION = "WARC/1.1"
+
+// 记录类型。
+const (
+	RECORD_TYPE_WARCINFO = "warcinfo"
+	RECORD_TYPE_REQUEST  = "request"
+	RECORD_TYPE_RESPONSE = "response"
+)
+
+// 记录头的字段名。
+const (
+	HEADER_TYPE            = "WARC-Type"
+	HEADER_RECORD_ID       = "WARC-Record-ID"
+	HEADER_DATE            = "WARC-Date"
+	HEADER_TARGET_URI      = "WARC-Target-URI"
+	HEADER_CONCURRENT_TO   = "WARC-Concurrent-To"
+	HEADER_WARCINFO_ID     = "WARC-Warcinfo-ID"
+	HEADER_FILENAME        = "WARC-Filename"
+	HEADER_BLOCK_DIGEST    = "WARC-Block-Digest"
+	HEADER_CONTENT_TYPE    = "Content-Type"
+	HEADER_CONTENT_LENGTH  = "Content-Length"
+	HEADER_CRAWL_DEPTH     = "Crawl-Depth" // 扩展字段。记录请求的深度。
+	CONTENT_TYPE_REQUEST   = "application/http;msgtype=request"
+	CONTENT_TYPE_RESPONSE  = "application/http;msgtype=response"
+	CONTENT_TYPE_WARC_INFO = "application/warc-fields"
+)
+
+// 记录头的字段。
+type Field struct {
+	Name  string // 字段名。
+	Value string // 字段值。
+}
+
+// 记录头。字段会保持其被设置时的顺序和大小写。
+type Header []Field
+
+// 获得字段值。字段名不区分大小写。
+func (header Header) Get(name string) string {
+	for _, field := range header {
+		if strings.EqualFold(field.Name, name) {
+			return field.Value
+		}
+	}
+	return ""
+}
+
+// 设置字段值。若字段已存在，则替换其值，否则追加该字段。
+func (header *Header) Set(name string, value string) {
+	for i, field := range *header {
+		if strings.EqualFold(field.Name, name) {
+			(*header)[i].Value = value
+			return
+		}
+	}
+	*header = append(*header, Field{Name: name, Value: value})
+}
+
+// WARC记录。
+type Record struct {
+	Header  Header // 记录头。
+	Content []byte // 记录块。
+}
+
+// 创建WARC记录。记录的ID、长度以及摘要会被自动设置。
+func NewRecord(recordType string, contentType string, content []byte) *Record {
+	var header Header
+	header.Set(HEADER_TYPE, recordType)
+	header.Set(HEADER_RECORD_ID, newRecordId())
+	header.Set(HEADER_CONTENT_TYPE, contentType)
+	header.Set(HEADER_CONTENT_LENGTH, strconv.Itoa(len(content)))
+	sum := sha1.Sum(content)
+	header.Set(HEADER_BLOCK_DIGEST, "sha1:"+base32.StdEncoding.EncodeToString(sum[:]))
+	return &Record{Header: header, Content: content}
+}
+
+// 获得记录类型。
+func (record *Record) Type() string {
+	return record.Header.Get(HEADER_TYPE)
+}
+
+// 获得记录的ID。
+func (record *Record) Id() string {
+	return record.Header.Get(HEADER_RECORD_ID)
+}
+
+// 获得目标URI。
+func (record *Record) TargetUri() string {
+	return record.Header.Get(HEADER_TARGET_URI)
+}
+
+// 获得请求的深度。若记录中没有该字段，则结果值为0。
+func (record *Record) Depth() uint32 {
+	depth, _ := strconv.ParseUint(record.Header.Get(HEADER_CRAWL_DEPTH), 10, 32)
+	return uint32(depth)
+}
+
+// 把记录编码为WARC格式。
+func (record *Record) encode() []byte {
+	var buffer bytes.Buffer
+	buffer.WriteString(WARC_VERSION + "\r\n")
+	for _, field := range record.Header {
+		buffer.WriteString(fmt.Sprintf("%s: %s\r\n", field.Name, field.Value))
+	}
+	buffer.WriteString("\r\n")
+	buffer.Write(record.Content)
+	buffer.WriteString("\r\n\r\n")
+	return buffer.Bytes()
+}
+
+// 把响应记录还原为响应。其主体可以被完整读取。
+func (record *Record) Response() (*base.Response, error) {
+	if record.Type() != RECORD_TYPE_RESPONSE {
+		return nil, fmt.Errorf("The record type '%s' is not '%s'!", record.Type(), RECORD_TYPE_RESPONSE)
+	}
+	httpReq, err := http.NewRequest("GET", record.TargetUri(), nil)
+	if err != nil {
+		return nil, err
+	}
+	httpResp, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(record.Content)), httpReq)
+	if err != nil {
+		return nil, err
+	}
+	return base.NewResponse(httpResp, record.Depth()), nil
+}
+
+// 生成记录的ID。它是一个随机的（第4版）UUID。
+func newRecordId() string {
+	var b [16]byte
+	rand.Read(b[:])
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("<urn:uuid:%x-%x-%x-%x-%x>", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
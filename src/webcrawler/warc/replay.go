@@ -0,0 +1,60 @@
+package warc
+
+import (
+	"io"
+	anlz "webcrawler/analyzer"
+	base "webcrawler/base"
+	dl "webcrawler/downloader"
+)
+
+// 创建带有归档功能的网页下载器。它会把每一对请求和响应都写入到WARC文件中。
+func NewArchivingPageDownloader(downloader dl.PageDownloader, writer Writer) dl.PageDownloader {
+	return &archivingPageDownloader{downloader: downloader, writer: writer}
+}
+
+// 带有归档功能的网页下载器的实现类型。
+type archivingPageDownloader struct {
+	downloader dl.PageDownloader // 被包装的网页下载器。
+	writer     Writer            // WARC写入器。
+}
+
+func (downloader *archivingPageDownloader) Id() uint32 {
+	return downloader.downloader.Id()
+}
+
+func (downloader *archivingPageDownloader) Download(req base.Request) (*base.Response, error) {
+	resp, err := downloader.downloader.Download(req)
+	if resp != nil && resp.HttpResp() != nil {
+		if writeErr := downloader.writer.WriteExchange(resp.HttpResp(), req.Depth()); writeErr != nil {
+			logger.Warnf("Write WARC record error: %s (url=%s)\n", writeErr, req.HttpReq().URL)
+		}
+	}
+	return resp, err
+}
+
+// 重放WARC数据中的所有响应记录。每个响应都会被交给分析器，就像它是刚被下载的一样。
+// 分析器得到的数据和错误会被依次交给handle函数。
+func Replay(
+	reader Reader,
+	analyzer anlz.Analyzer,
+	respParsers []anlz.ParseResponse,
+	handle func(dataList []base.Data, errs []error)) error {
+	for {
+		record, err := reader.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if record.Type() != RECORD_TYPE_RESPONSE {
+			continue
+		}
+		resp, err := record.Response()
+		if err != nil {
+			handle(nil, []error{err})
+			continue
+		}
+		handle(analyzer.Analyze(respParsers, *resp))
+	}
+}
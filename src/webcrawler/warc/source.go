@@ -0,0 +1,78 @@
+package warc
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	base "webcrawler/base"
+	dl "webcrawler/downloader"
+)
+
+// 创建基于WARC文件的响应源。文件中的响应记录会按照顺序被依次提供，其他记录会被忽略。
+func NewResponseSource(paths ...string) (dl.ResponseSource, error) {
+	if len(paths) == 0 {
+		return nil, errors.New("The WARC file list is empty!")
+	}
+	return &warcResponseSource{paths: paths}, nil
+}
+
+// 基于WARC文件的响应源的实现类型。
+type warcResponseSource struct {
+	paths  []string   // WARC文件的路径的列表。
+	next   int        // 下一个WARC文件在列表中的位置。
+	reader Reader     // 当前的WARC文件的读取器。
+	count  uint64     // 已提供的响应的数量。
+	mutex  sync.Mutex // 互斥锁。
+}
+
+func (source *warcResponseSource) Next() (*base.Response, error) {
+	source.mutex.Lock()
+	defer source.mutex.Unlock()
+	for {
+		if source.reader == nil {
+			if source.next >= len(source.paths) {
+				return nil, io.EOF
+			}
+			reader, err := OpenFile(source.paths[source.next])
+			source.next++
+			if err != nil {
+				return nil, err
+			}
+			source.reader = reader
+		}
+		record, err := source.reader.Next()
+		if err != nil {
+			// 无法继续读取当前的文件，所以转向下一个文件。
+			source.reader.Close()
+			source.reader = nil
+			if err == io.EOF {
+				continue
+			}
+			return nil, err
+		}
+		if record.Type() != RECORD_TYPE_RESPONSE {
+			continue
+		}
+		source.count++
+		return record.Response()
+	}
+}
+
+func (source *warcResponseSource) Close() error {
+	source.mutex.Lock()
+	defer source.mutex.Unlock()
+	source.next = len(source.paths)
+	if source.reader == nil {
+		return nil
+	}
+	err := source.reader.Close()
+	source.reader = nil
+	return err
+}
+
+func (source *warcResponseSource) Summary() string {
+	source.mutex.Lock()
+	defer source.mutex.Unlock()
+	return fmt.Sprintf("files: %d/%d, responses: %d", source.next, len(source.paths), source.count)
+}
@@ -0,0 +1,102 @@
+package warc
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sort"
+	"testing"
+	anlz "webcrawler/analyzer"
+	base "webcrawler/base"
+	dl "webcrawler/downloader"
+)
+
+func TestWriteAndReplay(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprintf(w, "<html><body>page %s</body></html>", r.URL.Path)
+	}))
+	defer server.Close()
+	dir := t.TempDir()
+	// 文件的大小上限很小，所以每一对记录都会被写入一个新的文件。
+	writer, err := NewWriter(dir, "test", 1)
+	if err != nil {
+		t.Fatalf("Create WARC writer error: %s", err)
+	}
+	downloader := NewArchivingPageDownloader(dl.NewPageDownloader(nil), writer)
+	number := 3
+	for i := 0; i < number; i++ {
+		httpReq, _ := http.NewRequest("GET", fmt.Sprintf("%s/%d", server.URL, i), nil)
+		resp, err := downloader.Download(*base.NewRequest(httpReq, uint32(i)))
+		if err != nil {
+			t.Fatalf("Download error: %s", err)
+		}
+		// 被归档之后，响应的主体应仍然可读。
+		body, _ := ioutil.ReadAll(resp.HttpResp().Body)
+		if string(body) != fmt.Sprintf("<html><body>page /%d</body></html>", i) {
+			t.Fatalf("Unexpected body %q after archiving.", body)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Close WARC writer error: %s", err)
+	}
+	paths, _ := filepath.Glob(filepath.Join(dir, "test-*.warc.gz"))
+	if len(paths) != number {
+		t.Fatalf("Unexpected WARC file number %d, expected %d.", len(paths), number)
+	}
+	sort.Strings(paths)
+
+	var replayed []string
+	parser := func(httpResp *http.Response, respDepth uint32, respMeta base.Meta) ([]base.Data, []error) {
+		body, _ := ioutil.ReadAll(httpResp.Body)
+		item := base.Item{"url": httpResp.Request.URL.String(), "body": string(body), "depth": respDepth}
+		return []base.Data{&item}, nil
+	}
+	for i, path := range paths {
+		reader, err := OpenFile(path)
+		if err != nil {
+			t.Fatalf("Open WARC file error: %s", err)
+		}
+		var types []string
+		for {
+			record, err := reader.Next()
+			if err != nil {
+				break
+			}
+			types = append(types, record.Type())
+		}
+		reader.Close()
+		if fmt.Sprint(types) != "[warcinfo response request]" {
+			t.Fatalf("Unexpected record types %v in file %s.", types, path)
+		}
+
+		reader, _ = OpenFile(path)
+		err = Replay(reader, anlz.NewAnalyzer(), []anlz.ParseResponse{parser},
+			func(dataList []base.Data, errs []error) {
+				for _, err := range errs {
+					t.Errorf("Replay error: %s", err)
+				}
+				for _, data := range dataList {
+					item := *data.(*base.Item)
+					if item["depth"] != uint32(i) {
+						t.Errorf("Unexpected depth %v, expected %d.", item["depth"], i)
+					}
+					replayed = append(replayed, item["body"].(string))
+				}
+			})
+		reader.Close()
+		if err != nil {
+			t.Fatalf("Replay error: %s", err)
+		}
+	}
+	if len(replayed) != number {
+		t.Fatalf("Unexpected replayed number %d, expected %d.", len(replayed), number)
+	}
+	for i, body := range replayed {
+		if body != fmt.Sprintf("<html><body>page /%d</body></html>", i) {
+			t.Errorf("Unexpected replayed body %q.", body)
+		}
+	}
+}
@@ -0,0 +1,229 @@
+package warc
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"logging"
+	"net/http"
+	"net/http/httputil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+	base "webcrawler/base"
+)
+
+// 日志记录器。
+var logger logging.Logger = base.NewLogger()
+
+// WARC写入器的接口类型。它的所有方法都是并发安全的。
+type Writer interface {
+	// 写入一对请求和响应的记录。
+	// 响应的主体会被完整读取，然后被替换为一个等价的主体。
+	WriteExchange(httpResp *http.Response, depth uint32) error
+	// 写入一条记录。
+	WriteRecord(record *Record) error
+	// 获得当前的WARC文件的路径。
+	Path() string
+	// 关闭写入器。
+	Close() error
+	// 获取摘要信息。
+	Summary() string
+}
+
+// 创建WARC写入器。
+// WARC文件会被存放在目录dir下，其名称以prefix为前缀。每条记录都会被单独地进行gzip压缩。
+// 当前文件的大小超过maxFileSize之后，后续的记录会被写入一个新的文件。
+func NewWriter(dir string, prefix string, maxFileSize int64) (Writer, error) {
+	if maxFileSize <= 0 {
+		errMsg := fmt.Sprintf("The WARC writer can not be initialized! (maxFileSize=%d)\n", maxFileSize)
+		return nil, errors.New(errMsg)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &myWriter{dir: dir, prefix: prefix, maxFileSize: maxFileSize}, nil
+}
+
+// WARC写入器的实现类型。
+type myWriter struct {
+	dir         string     // 存放WARC文件的目录。
+	prefix      string     // WARC文件名称的前缀。
+	maxFileSize int64      // 单个WARC文件的大小上限。
+	file        *os.File   // 当前的WARC文件。
+	fileSize    int64      // 当前的WARC文件的大小。
+	warcinfoId  string     // 当前的WARC文件的warcinfo记录的ID。
+	fileCount   uint32     // 已创建的WARC文件的数量。
+	recordCount uint64     // 已写入的记录的数量。
+	closed      bool       // 是否已关闭。
+	mutex       sync.Mutex // 互斥锁。
+}
+
+func (writer *myWriter) WriteExchange(httpResp *http.Response, depth uint32) error {
+	httpReq := httpResp.Request
+	if httpReq == nil || httpReq.URL == nil {
+		return errors.New("The request of HTTP response is invalid!")
+	}
+	var body []byte
+	if httpResp.Body != nil {
+		var err error
+		body, err = ioutil.ReadAll(httpResp.Body)
+		httpResp.Body.Close()
+		httpResp.Body = ioutil.NopCloser(bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+	}
+	// 主体已被解码，所以需要去掉传输编码并重新设置长度。
+	dumped := *httpResp
+	dumped.Header = httpResp.Header.Clone()
+	dumped.Header.Del("Content-Length")
+	dumped.TransferEncoding = nil
+	dumped.ContentLength = int64(len(body))
+	dumped.Body = ioutil.NopCloser(bytes.NewReader(body))
+	respContent, err := httputil.DumpResponse(&dumped, true)
+	if err != nil {
+		return err
+	}
+	reqContent, err := httputil.DumpRequest(httpReq, false)
+	if err != nil {
+		return err
+	}
+	// 请求体已被发送，所以需要通过GetBody重新获得它。
+	if httpReq.GetBody != nil {
+		reqBody, err := httpReq.GetBody()
+		if err != nil {
+			return err
+		}
+		bodyContent, err := ioutil.ReadAll(reqBody)
+		reqBody.Close()
+		if err != nil {
+			return err
+		}
+		reqContent = append(reqContent, bodyContent...)
+	}
+	date := time.Now().UTC().Format(time.RFC3339)
+	targetUri := httpReq.URL.String()
+	respRecord := NewRecord(RECORD_TYPE_RESPONSE, CONTENT_TYPE_RESPONSE, respContent)
+	reqRecord := NewRecord(RECORD_TYPE_REQUEST, CONTENT_TYPE_REQUEST, reqContent)
+	reqRecord.Header.Set(HEADER_CONCURRENT_TO, respRecord.Id())
+	for _, record := range []*Record{respRecord, reqRecord} {
+		record.Header.Set(HEADER_DATE, date)
+		record.Header.Set(HEADER_TARGET_URI, targetUri)
+		record.Header.Set(HEADER_CRAWL_DEPTH, strconv.FormatUint(uint64(depth), 10))
+	}
+	writer.mutex.Lock()
+	defer writer.mutex.Unlock()
+	// 两条记录应被写入同一个文件。
+	if err := writer.writeRecord(respRecord); err != nil {
+		return err
+	}
+	return writer.writeRecordInFile(reqRecord)
+}
+
+func (writer *myWriter) WriteRecord(record *Record) error {
+	writer.mutex.Lock()
+	defer writer.mutex.Unlock()
+	return writer.writeRecord(record)
+}
+
+// 写入记录。必要时会先创建新的文件。调用方需持有互斥锁。
+func (writer *myWriter) writeRecord(record *Record) error {
+	if writer.closed {
+		return errors.New("The WARC writer has been closed!")
+	}
+	if writer.file == nil || writer.fileSize >= writer.maxFileSize {
+		if err := writer.roll(); err != nil {
+			return err
+		}
+	}
+	return writer.writeRecordInFile(record)
+}
+
+// 在当前文件中写入记录。调用方需持有互斥锁。
+func (writer *myWriter) writeRecordInFile(record *Record) error {
+	if writer.warcinfoId != "" && record.Type() != RECORD_TYPE_WARCINFO {
+		record.Header.Set(HEADER_WARCINFO_ID, writer.warcinfoId)
+	}
+	if record.Header.Get(HEADER_DATE) == "" {
+		record.Header.Set(HEADER_DATE, time.Now().UTC().Format(time.RFC3339))
+	}
+	var buffer bytes.Buffer
+	gzipWriter := gzip.NewWriter(&buffer)
+	gzipWriter.Write(record.encode())
+	if err := gzipWriter.Close(); err != nil {
+		return err
+	}
+	n, err := writer.file.Write(buffer.Bytes())
+	writer.fileSize += int64(n)
+	if err != nil {
+		return err
+	}
+	writer.recordCount++
+	return nil
+}
+
+// 关闭当前文件并创建新的文件。新的文件总以一条warcinfo记录开始。调用方需持有互斥锁。
+func (writer *myWriter) roll() error {
+	if writer.file != nil {
+		if err := writer.file.Close(); err != nil {
+			return err
+		}
+		writer.file = nil
+	}
+	writer.fileCount++
+	name := fmt.Sprintf("%s-%s-%05d.warc.gz",
+		writer.prefix, time.Now().UTC().Format("20060102150405"), writer.fileCount)
+	file, err := os.OpenFile(filepath.Join(writer.dir, name), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	writer.file = file
+	writer.fileSize = 0
+	writer.warcinfoId = ""
+	fields := "software: webcrawler\r\nformat: WARC File Format 1.1\r\n"
+	info := NewRecord(RECORD_TYPE_WARCINFO, CONTENT_TYPE_WARC_INFO, []byte(fields))
+	info.Header.Set(HEADER_FILENAME, name)
+	if err := writer.writeRecordInFile(info); err != nil {
+		return err
+	}
+	writer.warcinfoId = info.Id()
+	logger.Infof("Created WARC file '%s'.\n", file.Name())
+	return nil
+}
+
+func (writer *myWriter) Path() string {
+	writer.mutex.Lock()
+	defer writer.mutex.Unlock()
+	if writer.file == nil {
+		return ""
+	}
+	return writer.file.Name()
+}
+
+func (writer *myWriter) Close() error {
+	writer.mutex.Lock()
+	defer writer.mutex.Unlock()
+	if writer.closed {
+		return nil
+	}
+	writer.closed = true
+	if writer.file == nil {
+		return nil
+	}
+	err := writer.file.Close()
+	writer.file = nil
+	return err
+}
+
+var writerSummaryTemplate = "files: %d, records: %d, dir: %s"
+
+func (writer *myWriter) Summary() string {
+	writer.mutex.Lock()
+	defer writer.mutex.Unlock()
+	return fmt.Sprintf(writerSummaryTemplate, writer.fileCount, writer.recordCount, writer.dir)
+}